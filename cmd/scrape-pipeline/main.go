@@ -2,18 +2,32 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
-	"time"
 
 	"github.com/ncolesummers/scrape-pipeline/internal/config"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/internal/registry"
+	"github.com/ncolesummers/scrape-pipeline/pkg/quality"
+	"github.com/ncolesummers/scrape-pipeline/pkg/quarantine"
 )
 
 func main() {
+	// Review rejected content without starting the pipeline
+	if len(os.Args) > 1 && os.Args[1] == "quarantine" {
+		if err := runQuarantine(os.Args[2:], os.Stdout); err != nil && !errors.Is(err, flag.ErrHelp) {
+			log.Fatalf("Quarantine: %v", err)
+		}
+		return
+	}
+
 	// Parse command line flags
 	configPath := parseFlags()
 
@@ -33,26 +47,226 @@ func main() {
 	// Handle OS signals for graceful shutdown
 	setupSignalHandler(cancel)
 
+	// Build the configured stage implementations so a bad name fails early
+	stages, err := buildStages(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize pipeline: %v", err)
+	}
+
 	// Initialize and run the pipeline
 	fmt.Println("Initialized pipeline with configuration")
 	fmt.Printf("Configured scrapers: %d\n", len(cfg.Scrapers))
+	fmt.Printf("Extraction method: %s\n", cfg.Extraction.Method)
+
+	runPipeline(ctx, stages)
 
-	// Use the context in a simple timeout simulation
-	// In the real implementation, this would be used to control the pipeline
-	runPipeline(ctx)
+	// Save what the stages learned for the next run
+	if err := stages.Close(); err != nil {
+		log.Fatalf("Failed to shut down pipeline: %v", err)
+	}
 }
 
-// runPipeline runs the scraping pipeline with the given context
-func runPipeline(ctx context.Context) {
+// runPipeline processes the items released from quarantine, then scrapes
+// every target and processes the pages it fetches. Failures are logged and
+// do not stop the run.
+func runPipeline(ctx context.Context, s *stages) {
 	fmt.Println("Pipeline started")
 
-	// Simulate pipeline operation
-	select {
-	case <-time.After(5 * time.Second):
-		fmt.Println("Pipeline completed successfully")
-	case <-ctx.Done():
+	if err := s.processReleased(ctx); err != nil {
+		log.Printf("Failed to process released items: %v", err)
+	}
+
+	for i, scraper := range s.scrapers {
+		pages, errs := scraper.Scrape(ctx, []string{s.targets[i]})
+		for pages != nil || errs != nil {
+			select {
+			case raw, ok := <-pages:
+				if !ok {
+					pages = nil
+					continue
+				}
+				if err := s.process(ctx, raw, s.chunkers[i]); err != nil {
+					log.Printf("Failed to process %s: %v", raw.URL, err)
+				}
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					continue
+				}
+				log.Printf("Failed to scrape: %v", err)
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
 		fmt.Println("Pipeline was cancelled")
+		return
+	}
+	fmt.Println("Pipeline completed successfully")
+}
+
+// stages holds the stage implementations built from the configuration
+type stages struct {
+	scrapers []models.Scraper
+	// targets holds the start URL of each scraper, in order
+	targets []string
+	// chunkers holds the chunker of each scraper target, in order
+	chunkers   []models.Chunker
+	extractor  models.Extractor
+	normalizer models.Normalizer
+	chunker    models.Chunker
+	quality    models.QualityControl
+	embedder   models.Embedder
+	storage    models.VectorStorage
+	// quarantine keeps the content the extractor and quality stages reject
+	quarantine *quarantine.Store
+}
+
+// buildStages builds every stage implementation named in the configuration
+func buildStages(cfg *config.Config) (*stages, error) {
+	s := &stages{}
+	for _, scraperCfg := range cfg.Scrapers {
+		scraper, err := registry.NewScraper(scraperCfg)
+		if err != nil {
+			return nil, err
+		}
+		chunker, err := registry.NewTargetChunker(cfg, scraperCfg)
+		if err != nil {
+			return nil, err
+		}
+		s.scrapers = append(s.scrapers, scraper)
+		s.targets = append(s.targets, scraperCfg.URL)
+		s.chunkers = append(s.chunkers, chunker)
+	}
+
+	var err error
+	if s.quarantine, err = quarantine.Open(cfg.Quarantine.Path); err != nil {
+		return nil, err
+	}
+	extractor, err := registry.NewExtractor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if s.extractor, err = quarantine.NewExtractor(extractor, s.quarantine); err != nil {
+		return nil, err
+	}
+	if s.normalizer, err = registry.NewNormalizer(cfg); err != nil {
+		return nil, err
+	}
+	if s.chunker, err = registry.NewChunker(cfg); err != nil {
+		return nil, err
+	}
+	checker, err := registry.NewQualityChecker(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if s.quality, err = quarantine.NewQualityControl(checker, s.quarantine); err != nil {
+		return nil, err
+	}
+	if s.storage, err = registry.NewStorage(cfg); err != nil {
+		return nil, err
+	}
+	if s.embedder, err = registry.NewEmbedder(cfg, s.storage); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// process runs a fetched page through the stages from extraction to
+// storage, chunking it with chunker
+func (s *stages) process(ctx context.Context, raw *models.RawContent, chunker models.Chunker) error {
+	extracted, err := s.extractor.Extract(ctx, raw)
+	if err != nil {
+		return err
+	}
+	normalized, err := s.normalizer.Normalize(ctx, extracted)
+	if err != nil {
+		return err
+	}
+	chunks, err := chunker.Chunk(ctx, normalized)
+	if err != nil {
+		return err
+	}
+	return s.store(ctx, chunks)
+}
+
+// store checks the quality of chunks and embeds and stores the accepted
+// ones. Rejected chunks are quarantined by the quality stage and are not
+// an error.
+func (s *stages) store(ctx context.Context, chunks []*models.ContentChunk) error {
+	accepted, errs := s.quality.Check(ctx, chunks)
+	var failures []error
+	for _, err := range errs {
+		var rejection *quality.RejectionError
+		if !errors.As(err, &rejection) {
+			failures = append(failures, err)
+		}
+	}
+	if len(failures) > 0 {
+		return errors.Join(failures...)
+	}
+	if len(accepted) == 0 {
+		return nil
+	}
+
+	embeddings, err := s.embedder.Embed(ctx, accepted)
+	if err != nil {
+		return err
+	}
+	return s.storage.Store(ctx, embeddings)
+}
+
+// processReleased processes the items released from quarantine that no
+// run has processed yet. Pages re-enter the pipeline at extraction and
+// chunks at quality control, which lets them through the check that
+// rejected them. An item is done once its content is stored or it was
+// quarantined again; any other failure leaves it for the next run.
+func (s *stages) processReleased(ctx context.Context) error {
+	items, err := s.quarantine.Released()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, item := range items {
+		switch {
+		case item.Raw != nil:
+			err = s.process(ctx, item.Raw, s.chunkerFor(item.URL))
+		case item.Chunk != nil:
+			err = s.store(ctx, []*models.ContentChunk{item.Chunk})
+		default:
+			err = fmt.Errorf("no content")
+		}
+		if err == nil || errors.Is(err, quarantine.ErrQuarantined) {
+			err = s.quarantine.Done(item.ID)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("released item %s: %w", item.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// chunkerFor returns the chunker of the target whose start URL url is
+// under, or the chunker without a target
+func (s *stages) chunkerFor(url string) models.Chunker {
+	for i, target := range s.targets {
+		if target != "" && strings.HasPrefix(url, target) {
+			return s.chunkers[i]
+		}
+	}
+	return s.chunker
+}
+
+// Close closes the stages that implement io.Closer, which saves the state
+// they learned during the run, such as site templates and dedup indexes
+func (s *stages) Close() error {
+	var errs []error
+	for _, stage := range []interface{}{s.extractor, s.normalizer, s.chunker, s.quality, s.embedder, s.storage} {
+		if closer, ok := stage.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
 	}
+	return errors.Join(errs...)
 }
 
 // parseFlags parses command line flags and returns the path to the configuration file
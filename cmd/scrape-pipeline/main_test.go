@@ -1,9 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/config"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/dedup"
+	"github.com/ncolesummers/scrape-pipeline/pkg/quarantine"
 )
 
 func TestConfigFlagParsing(t *testing.T) {
@@ -47,3 +56,175 @@ func TestConfigFileNotFound(t *testing.T) {
 // Skip the actual loadConfig testing here since it requires mocking the file system
 // or creating a real temporary file. In a real implementation, you would use
 // the same approach as in the previous test, but use a proper config file.
+
+func TestBuildStages(t *testing.T) {
+	cfg, err := loadConfig("../../config.yaml.example")
+	if err != nil {
+		t.Fatalf("Failed to load example config: %v", err)
+	}
+	stages, err := buildStages(cfg)
+	if err != nil {
+		t.Fatalf("Expected the example config to build, got %v", err)
+	}
+	if len(stages.scrapers) != len(cfg.Scrapers) || len(stages.chunkers) != len(cfg.Scrapers) {
+		t.Errorf("Expected a scraper and chunker per target, got %d and %d", len(stages.scrapers), len(stages.chunkers))
+	}
+
+	cfg.Extraction.Method = "unknown"
+	if _, err := buildStages(cfg); err == nil {
+		t.Errorf("Expected an error for an unknown extraction method")
+	}
+}
+
+// exampleConfig loads the example configuration with everything the
+// stages save redirected to a temporary directory, out of the source tree
+func exampleConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg, err := loadConfig("../../config.yaml.example")
+	if err != nil {
+		t.Fatalf("Failed to load example config: %v", err)
+	}
+	dir := t.TempDir()
+	cfg.Extraction.TemplatePath = filepath.Join(dir, "templates.json")
+	cfg.Quality.DuplicateIndexPath = filepath.Join(dir, "dedup", "index.gob")
+	cfg.Embedding.ContentIndexPath = filepath.Join(dir, "dedup", "content.json")
+	cfg.Storage.Path = filepath.Join(dir, "vector_db")
+	cfg.Quarantine.Path = filepath.Join(dir, "quarantine")
+	return cfg
+}
+
+func TestStagesClose(t *testing.T) {
+	cfg := exampleConfig(t)
+	stages, err := buildStages(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build stages: %v", err)
+	}
+	if err := stages.Close(); err != nil {
+		t.Fatalf("Failed to close stages: %v", err)
+	}
+	for _, path := range []string{cfg.Extraction.TemplatePath, cfg.Quality.DuplicateIndexPath, cfg.Embedding.ContentIndexPath} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected closing to save %s: %v", filepath.Base(path), err)
+		}
+	}
+}
+
+// failingStorage fails to store anything
+type failingStorage struct {
+	models.VectorStorage
+}
+
+func (failingStorage) Store(ctx context.Context, embeddings []*models.VectorEmbedding) error {
+	return errors.New("disk full")
+}
+
+func TestProcessQuarantinesAndReleases(t *testing.T) {
+	cfg := exampleConfig(t)
+	cfg.Storage.Type = "memory"
+	stages, err := buildStages(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build stages: %v", err)
+	}
+	defer stages.Close()
+	ctx := context.Background()
+
+	// A short post whose title reads like an error page
+	paragraph := "The build failed with a linker error that pointed at a missing symbol. " +
+		"We traced it to a package that was vendored twice under different import paths, " +
+		"so the linker saw two copies of the same type and refused to merge them."
+	raw := &models.RawContent{
+		URL:        "https://example.com/tech/symbol-not-found",
+		StatusCode: 200,
+		HTML: "<html><head><title>Symbol not found</title></head><body><article><h1>Symbol not found</h1>" +
+			"<p>" + paragraph + "</p><p>" + paragraph + "</p></article></body></html>",
+	}
+	if err := stages.process(ctx, raw, stages.chunkerFor(raw.URL)); !errors.Is(err, quarantine.ErrSoft404) {
+		t.Fatalf("Expected the page to be quarantined as a soft 404, got %v", err)
+	}
+	items, err := stages.quarantine.List(quarantine.StageFetch)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("Expected the page in quarantine, got %v, %v", items, err)
+	}
+
+	// Once released, the next run processes it into storage. A run that
+	// fails to store it leaves it released.
+	if _, err := stages.quarantine.Release(items[0].ID); err != nil {
+		t.Fatalf("Failed to release: %v", err)
+	}
+	storage := stages.storage
+	stages.storage = failingStorage{storage}
+	if err := stages.processReleased(ctx); err == nil {
+		t.Fatal("Expected the storage error")
+	}
+	if pending, _ := stages.quarantine.Released(); len(pending) != 1 {
+		t.Fatalf("Expected the item to stay released after a failure, got %v", pending)
+	}
+	stages.storage = storage
+	if err := stages.processReleased(ctx); err != nil {
+		t.Fatalf("Failed to process released items: %v", err)
+	}
+	if pending, _ := stages.quarantine.Released(); len(pending) != 0 {
+		t.Errorf("Expected the item to be done, got %v", pending)
+	}
+	lookup := stages.storage.(models.LookupStorage)
+	results, err := lookup.Query(ctx, make([]float32, cfg.Embedding.Dimensions), 10)
+	if err != nil || len(results) == 0 {
+		t.Errorf("Expected the released page in storage, got %v, %v", results, err)
+	}
+	if items, _ := stages.quarantine.List(""); len(items) != 0 {
+		t.Errorf("Expected an empty quarantine, got %+v", items)
+	}
+}
+
+// storedSources returns the sources of the chunks in storage
+func storedSources(t *testing.T, s *stages, dimensions int) map[string]int {
+	t.Helper()
+	results, err := s.storage.Query(context.Background(), make([]float32, dimensions), -1)
+	if err != nil {
+		t.Fatalf("Failed to query storage: %v", err)
+	}
+	sources := make(map[string]int)
+	for _, e := range results {
+		sources[e.Chunk.Source]++
+	}
+	return sources
+}
+
+func TestProcessSkipsNearDuplicatePages(t *testing.T) {
+	cfg := exampleConfig(t)
+	cfg.Storage.Type = "memory"
+	stages, err := buildStages(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build stages: %v", err)
+	}
+	defer stages.Close()
+	ctx := context.Background()
+
+	paragraph := "Our deploys stalled for an hour because the build cache was keyed on the wrong file. " +
+		"Every change to the lockfile invalidated it, so each job downloaded all modules again " +
+		"and the workers ran out of disk before the tests started."
+	page := func(url, extra string) *models.RawContent {
+		return &models.RawContent{
+			URL:        url,
+			StatusCode: 200,
+			HTML: "<html><head><title>Slow deploys</title></head><body><article><h1>Slow deploys</h1>" +
+				"<p>" + paragraph + "</p><p>" + paragraph + extra + "</p></article></body></html>",
+		}
+	}
+	original := page("https://example.com/tech/slow-deploys", "")
+	copied := page("https://mirror.example.org/slow-deploys", " Thanks for reading.")
+	for _, raw := range []*models.RawContent{original, copied} {
+		if err := stages.process(ctx, raw, stages.chunkerFor(raw.URL)); err != nil {
+			t.Fatalf("Failed to process %s: %v", raw.URL, err)
+		}
+	}
+
+	sources := storedSources(t, stages, cfg.Embedding.Dimensions)
+	if sources[original.URL] == 0 || sources[copied.URL] != 0 {
+		t.Errorf("Expected only the original page in storage, got %v", sources)
+	}
+	items, _ := stages.quarantine.List(quarantine.StageQuality)
+	if len(items) == 0 || items[0].Chunk.Metadata[dedup.MetaDuplicateOf] == nil || !strings.Contains(items[0].Reason, "duplicate") {
+		t.Errorf("Expected the copy quarantined as a duplicate, got %+v", items)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ncolesummers/scrape-pipeline/pkg/quarantine"
+)
+
+// quarantineUsage describes the quarantine subcommand
+const quarantineUsage = `usage: scrape-pipeline quarantine [-config file] <command>
+
+commands:
+  list [-stage stage]  list quarantined items, oldest first
+  inspect <id>         show a quarantined item in full
+  release <id>...      release items back into the pipeline
+
+IDs may be shortened to any unique prefix.`
+
+// shortID is the number of ID digits shown by list
+const shortID = 12
+
+// maxReason is the number of reason characters shown by list
+const maxReason = 60
+
+// runQuarantine runs the quarantine subcommand with args, the arguments
+// after "quarantine", writing its output to out
+func runQuarantine(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("quarantine", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() { fmt.Fprintln(out, quarantineUsage) }
+	configPath := fs.String("config", "config.yaml", "Path to configuration file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("missing quarantine command")
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	store, err := quarantine.Open(cfg.Quarantine.Path)
+	if err != nil {
+		return err
+	}
+
+	command, rest := fs.Arg(0), fs.Args()[1:]
+	switch command {
+	case "list":
+		return listQuarantine(store, rest, out)
+	case "inspect":
+		if len(rest) != 1 {
+			return errors.New("inspect takes one item ID")
+		}
+		item, err := store.Get(rest[0])
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(item, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(data))
+		return nil
+	case "release":
+		if len(rest) == 0 {
+			return errors.New("release takes at least one item ID")
+		}
+		for _, id := range rest {
+			item, err := store.Release(id)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Released %s (%s) %s\n", item.ID, item.Stage, item.URL)
+		}
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown quarantine command %q", command)
+	}
+}
+
+// listQuarantine writes a table of the quarantined items to out
+func listQuarantine(store *quarantine.Store, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.SetOutput(out)
+	stage := fs.String("stage", "", "Only list items rejected at this stage")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	items, err := store.List(*stage)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		fmt.Fprintln(out, "No quarantined items")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTAGE\tSCORE\tQUARANTINED\tURL\tREASON")
+	for _, item := range items {
+		id := item.ID
+		if len(id) > shortID {
+			id = id[:shortID]
+		}
+		reason := strings.Join(strings.Fields(item.Reason), " ")
+		if runes := []rune(reason); len(runes) > maxReason {
+			reason = string(runes[:maxReason-1]) + "…"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.2f\t%s\t%s\t%s\n", id, item.Stage, item.Score, item.QuarantinedAt.Format(time.DateTime), item.URL, reason)
+	}
+	return w.Flush()
+}
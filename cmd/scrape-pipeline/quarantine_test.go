@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/quarantine"
+)
+
+func TestRunQuarantine(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	config := "scrapers:\n  - name: blog\n    url: https://example.com\n    rate_limit: 1\n" +
+		"quarantine:\n  path: " + filepath.Join(dir, "quarantine") + "\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	store, _ := quarantine.Open(filepath.Join(dir, "quarantine"))
+	item := quarantine.FromRaw(quarantine.StageExtraction, &models.RawContent{URL: "https://example.com/empty"}, errors.New("no main content"))
+	if err := store.Add(item); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := runQuarantine(append([]string{"-config", configPath}, args...), &out)
+		return out.String(), err
+	}
+
+	out, err := run("list")
+	if err != nil || !strings.Contains(out, item.ID[:12]) || !strings.Contains(out, "no main content") {
+		t.Errorf("list = %q, %v", out, err)
+	}
+	if out, _ := run("list", "-stage", quarantine.StageQuality); !strings.Contains(out, "No quarantined items") {
+		t.Errorf("list -stage quality = %q", out)
+	}
+
+	out, err = run("inspect", item.ID[:8])
+	if err != nil || !strings.Contains(out, `"url": "https://example.com/empty"`) {
+		t.Errorf("inspect = %q, %v", out, err)
+	}
+
+	out, err = run("release", item.ID[:8])
+	if err != nil || !strings.Contains(out, "Released "+item.ID) {
+		t.Errorf("release = %q, %v", out, err)
+	}
+	if released, _ := store.Released(); len(released) != 1 {
+		t.Errorf("Expected the item to be released, got %v", released)
+	}
+
+	if _, err := run("inspect", item.ID); !errors.Is(err, quarantine.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after release, got %v", err)
+	}
+	if _, err := run("purge"); err == nil {
+		t.Error("Expected an error for an unknown command")
+	}
+	if _, err := run(); err == nil {
+		t.Error("Expected an error without a command")
+	}
+}
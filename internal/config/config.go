@@ -16,48 +16,157 @@ type Config struct {
 	Chunking   ChunkingConfig   `yaml:"chunking"`
 	Quality    QualityConfig    `yaml:"quality"`
 	Extraction ExtractionConfig `yaml:"extraction"`
+	Normalizer NormalizerConfig `yaml:"normalizer"`
+	Quarantine QuarantineConfig `yaml:"quarantine"`
 }
 
-// ScraperConfig contains configuration for a web scraper
+// ScraperConfig contains configuration for a web scraper.
+// Type selects the implementation by registry name and defaults to "http".
 type ScraperConfig struct {
 	Name             string `yaml:"name"`
+	Type             string `yaml:"type"`
 	URL              string `yaml:"url"`
 	UserAgent        string `yaml:"user_agent"`
 	RateLimit        int    `yaml:"rate_limit"`
 	Concurrency      int    `yaml:"concurrency"`
 	RespectRobotsTxt bool   `yaml:"respect_robots_txt"`
+	// ContextHeader overrides chunking.context_header for this target
+	ContextHeader string `yaml:"context_header"`
 }
 
-// ExtractionConfig contains configuration for content extraction
+// ExtractionConfig contains configuration for content extraction.
+// Method selects the extractor by registry name and defaults to
+// "readability". With a TemplatePath, the recurring blocks learned for
+// each site are loaded from and saved to that file.
 type ExtractionConfig struct {
-	PreserveHeadings bool `yaml:"preserve_headings"`
-	ExtractImages    bool `yaml:"extract_images"`
+	Method            string                    `yaml:"extraction_method"`
+	SiteSpecificRules map[string]SiteRuleConfig `yaml:"site_specific_rules"`
+	FallbackChain     []string                  `yaml:"fallback_chain"`
+	MaxKeyphrases     int                       `yaml:"max_keyphrases"`
+	PreserveHeadings  bool                      `yaml:"preserve_headings"`
+	ExtractImages     bool                      `yaml:"extract_images"`
+	ExtractMetadata   bool                      `yaml:"extract_metadata"`
+	ExtractMarkdown   bool                      `yaml:"extract_markdown"`
+	KeepComments      bool                      `yaml:"keep_comments"`
+	TemplatePath      string                    `yaml:"template_path"`
 }
 
-// ChunkingConfig contains configuration for document chunking
+// SiteRuleConfig contains the CSS selectors used to extract a specific site
+type SiteRuleConfig struct {
+	ArticleSelector string `yaml:"article_selector"`
+	TitleSelector   string `yaml:"title_selector"`
+	AuthorSelector  string `yaml:"author_selector"`
+	DateSelector    string `yaml:"date_selector"`
+}
+
+// NormalizerConfig contains configuration for text normalization.
+// Method selects the normalizer by registry name and defaults to "standard".
+type NormalizerConfig struct {
+	Method           string    `yaml:"method"`
+	UnicodeForm      string    `yaml:"unicode_form"`
+	TrimWhitespace   bool      `yaml:"trim_whitespace"`
+	NormalizeUnicode bool      `yaml:"normalize_unicode"`
+	UnifyPunctuation bool      `yaml:"unify_punctuation"`
+	DecodeEntities   bool      `yaml:"decode_entities"`
+	StripInvisible   bool      `yaml:"strip_invisible"`
+	PreserveLists    bool      `yaml:"preserve_lists"`
+	PreserveHeadings bool      `yaml:"preserve_headings"`
+	LanguageRules    bool      `yaml:"language_rules"`
+	PII              PIIConfig `yaml:"pii"`
+}
+
+// PIIConfig configures the redaction of personal data during
+// normalization. Kinds lists the kinds to detect, all of them when empty.
+// Action is "redact", "mask" or "hash" and Actions overrides it per kind.
+// The "hash" action needs a secret key, read from the environment variable
+// named by SecretEnvVar.
+type PIIConfig struct {
+	Enabled      bool              `yaml:"enabled"`
+	Kinds        []string          `yaml:"kinds"`
+	Action       string            `yaml:"action"`
+	Actions      map[string]string `yaml:"actions"`
+	SecretEnvVar string            `yaml:"secret_env_var"`
+}
+
+// ChunkingConfig contains configuration for document chunking.
+// Strategy selects the chunker by registry name and defaults to "token".
+// Tokenizer is "whitespace" (the default) or "bpe", which reads the
+// tiktoken vocabulary file at VocabPath. TargetTokens and the Respect
+// options apply to the structural strategy; MinTokens,
+// BreakpointPercentile and WindowSentences to the semantic strategy, which
+// embeds sentences with the embedder of the embedding section.
+// ParentTokens is the size of parent chunks of the hierarchical strategy,
+// whose children are up to MaxTokens. ContextHeader is an optional
+// text/template rendering a header that is embedded with every chunk; see
+// chunker.HeaderTemplate.
 type ChunkingConfig struct {
-	MaxTokens int `yaml:"max_tokens"`
-	Overlap   int `yaml:"overlap"`
+	Strategy             string  `yaml:"strategy"`
+	MaxTokens            int     `yaml:"max_tokens"`
+	Overlap              int     `yaml:"overlap"`
+	Tokenizer            string  `yaml:"tokenizer"`
+	VocabPath            string  `yaml:"vocab_path"`
+	TargetTokens         int     `yaml:"target_chunk_size"`
+	RespectHeadings      bool    `yaml:"respect_headings"`
+	RespectParagraphs    bool    `yaml:"respect_paragraphs"`
+	MinTokens            int     `yaml:"min_chunk_size"`
+	BreakpointPercentile float64 `yaml:"breakpoint_percentile"`
+	WindowSentences      int     `yaml:"window_sentences"`
+	ParentTokens         int     `yaml:"parent_chunk_size"`
+	ContextHeader        string  `yaml:"context_header"`
+
+	// Embedding is a copy of the embedding section, set by the registry
+	// when it builds the chunker
+	Embedding EmbeddingConfig `yaml:"-"`
 }
 
-// QualityConfig contains configuration for quality control
+// QualityConfig contains configuration for quality control.
+// Method selects the checker by registry name and defaults to "heuristic".
+// Chunks shorter than MinContentLength characters, or passing fewer than
+// MinQualityScore of the heuristic rules, are rejected. Either takes the
+// package default when unset, and 0 disables its check. With duplicate
+// detection enabled, a chunk is a duplicate of one seen before when the
+// estimated Jaccard similarity of their word shingles is at least
+// DuplicateThreshold (0.85 when zero), or when their SimHash fingerprints
+// differ in at most DuplicateSimHashDistance of 64 bits (3 when zero,
+// disabled when negative). The index of seen chunks is kept in
+// DuplicateIndexPath, or in memory when it is empty.
 type QualityConfig struct {
-	MinContentLength   int     `yaml:"min_content_length"`
-	DuplicateThreshold float64 `yaml:"duplicate_threshold"`
+	Method                   string   `yaml:"method"`
+	MinContentLength         *int     `yaml:"min_content_length,omitempty"`
+	MinQualityScore          *float64 `yaml:"min_content_quality_score,omitempty"`
+	EnableDuplicateDetection bool     `yaml:"enable_duplicate_detection"`
+	DuplicateThreshold       float64  `yaml:"duplicate_threshold"`
+	DuplicateSimHashDistance int      `yaml:"duplicate_simhash_distance"`
+	DuplicateIndexPath       string   `yaml:"duplicate_index_path"`
 }
 
-// EmbeddingConfig contains configuration for embedding generation
+// EmbeddingConfig contains configuration for embedding generation.
+// Provider selects the embedder by registry name and defaults to "hash",
+// a local feature-hashing embedder with Dimensions dimensions. With a
+// ContentIndexPath, text embedded before, in this or an earlier run, is not
+// embedded again.
 type EmbeddingConfig struct {
-	Model     string `yaml:"model"`
-	BatchSize int    `yaml:"batch_size"`
+	Provider         string `yaml:"provider"`
+	Model            string `yaml:"model"`
+	Dimensions       int    `yaml:"embedding_dimension"`
+	BatchSize        int    `yaml:"batch_size"`
+	ContentIndexPath string `yaml:"content_index_path"`
 }
 
-// StorageConfig contains configuration for vector storage
+// StorageConfig contains configuration for vector storage.
+// Type selects the storage by registry name and defaults to "memory";
+// "local" persists the embeddings in the directory at Path.
 type StorageConfig struct {
 	Type string `yaml:"type"`
 	Path string `yaml:"path"`
 }
 
+// QuarantineConfig contains configuration for the store of rejected
+// content. Path is its directory and defaults to "./data/quarantine".
+type QuarantineConfig struct {
+	Path string `yaml:"path"`
+}
+
 // LoadConfig loads configuration from a file
 func LoadConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -102,6 +211,45 @@ func (c *Config) Validate() error {
 			// Set default user agent if missing
 			c.Scrapers[i].UserAgent = "Scrape-Pipeline/1.0"
 		}
+		if scraper.Type == "" {
+			// Use the plain HTTP scraper by default
+			c.Scrapers[i].Type = "http"
+		}
+	}
+
+	if c.Extraction.Method == "" {
+		// Use readability extraction by default
+		c.Extraction.Method = "readability"
+	}
+	if c.Normalizer.Method == "" {
+		// Use the standard normalizer by default
+		c.Normalizer.Method = "standard"
+	}
+	if c.Chunking.Strategy == "" {
+		// Use fixed token windows by default
+		c.Chunking.Strategy = "token"
+	}
+	if c.Quality.Method == "" {
+		// Use the heuristic quality rules by default
+		c.Quality.Method = "heuristic"
+	}
+	if c.Quality.MinContentLength != nil && *c.Quality.MinContentLength < 0 {
+		return fmt.Errorf("quality min_content_length must not be negative, got %d", *c.Quality.MinContentLength)
+	}
+	if c.Quality.MinQualityScore != nil && *c.Quality.MinQualityScore < 0 {
+		return fmt.Errorf("quality min_content_quality_score must not be negative, got %g", *c.Quality.MinQualityScore)
+	}
+	if c.Embedding.Provider == "" {
+		// Use the local hash embedder by default
+		c.Embedding.Provider = "hash"
+	}
+	if c.Storage.Type == "" {
+		// Keep embeddings in memory by default
+		c.Storage.Type = "memory"
+	}
+	if c.Quarantine.Path == "" {
+		// Keep rejected content next to the other data by default
+		c.Quarantine.Path = "./data/quarantine"
 	}
 
 	return nil
@@ -109,11 +257,14 @@ func (c *Config) Validate() error {
 
 // WriteDefaultConfig writes a default configuration to a file
 func WriteDefaultConfig(filepath string) error {
+	minContentLength, minQualityScore := 100, 0.5
+
 	// Create a default configuration
 	config := &Config{
 		Scrapers: []ScraperConfig{
 			{
 				Name:             "Default Scraper",
+				Type:             "http",
 				URL:              "https://example.com",
 				RateLimit:        1,
 				Concurrency:      1,
@@ -121,25 +272,59 @@ func WriteDefaultConfig(filepath string) error {
 			},
 		},
 		Extraction: ExtractionConfig{
+			Method:           "readability",
 			PreserveHeadings: true,
 			ExtractImages:    true,
+			TemplatePath:     "./data/templates.json",
+		},
+		Normalizer: NormalizerConfig{
+			Method:           "standard",
+			UnicodeForm:      "NFC",
+			TrimWhitespace:   true,
+			NormalizeUnicode: true,
+			UnifyPunctuation: true,
+			DecodeEntities:   true,
+			StripInvisible:   true,
+			PreserveLists:    true,
+			PreserveHeadings: true,
+			LanguageRules:    true,
 		},
 		Chunking: ChunkingConfig{
-			MaxTokens: 1000,
-			Overlap:   200,
+			Strategy:             "token",
+			MaxTokens:            1000,
+			Overlap:              200,
+			Tokenizer:            "whitespace",
+			TargetTokens:         500,
+			RespectHeadings:      true,
+			RespectParagraphs:    true,
+			MinTokens:            100,
+			BreakpointPercentile: 95,
+			WindowSentences:      2,
+			ParentTokens:         4000,
 		},
 		Quality: QualityConfig{
-			MinContentLength:   100,
-			DuplicateThreshold: 0.5,
+			Method:                   "heuristic",
+			MinContentLength:         &minContentLength,
+			MinQualityScore:          &minQualityScore,
+			EnableDuplicateDetection: true,
+			DuplicateThreshold:       0.85,
+			DuplicateSimHashDistance: 3,
+			DuplicateIndexPath:       "./data/dedup/index.gob",
 		},
 		Embedding: EmbeddingConfig{
-			Model:     "default_model",
-			BatchSize: 32,
+			Provider:         "hash",
+			Model:            "default_model",
+			Dimensions:       384,
+			BatchSize:        32,
+			ContentIndexPath: "./data/dedup/content.json",
 		},
 		Storage: StorageConfig{
 			Type: "local",
 			Path: "./data",
 		},
+		Quarantine: QuarantineConfig{
+			Path: "./data/quarantine",
+		},
 	}
 
 	// Marshal to YAML
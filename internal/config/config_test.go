@@ -87,6 +87,21 @@ func TestValidateConfig(t *testing.T) {
 	if err := invalidConfig.Validate(); err == nil {
 		t.Errorf("Invalid config passed validation when it should have failed")
 	}
+
+	// Quality minimums may be zero but not negative
+	for _, quality := range []QualityConfig{{MinContentLength: new(int)}, {MinQualityScore: new(float64)}} {
+		validConfig.Quality = quality
+		if err := validConfig.Validate(); err != nil {
+			t.Errorf("Zero quality minimum failed validation: %v", err)
+		}
+	}
+	negativeLength, negativeScore := -1, -0.1
+	for _, quality := range []QualityConfig{{MinContentLength: &negativeLength}, {MinQualityScore: &negativeScore}} {
+		validConfig.Quality = quality
+		if err := validConfig.Validate(); err == nil {
+			t.Errorf("Negative quality minimum %+v passed validation", quality)
+		}
+	}
 }
 
 func TestExampleConfigFile(t *testing.T) {
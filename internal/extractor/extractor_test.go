@@ -1,11 +1,12 @@
 package extractor
 
 import (
+	"context"
 	"strings"
 	"testing"
 
 	"github.com/ncolesummers/scrape-pipeline/internal/config"
-	"github.com/ncolesummers/scrape-pipeline/internal/scraper"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
 )
 
 // TestNewExtractor tests the creation of a new extractor
@@ -25,11 +26,8 @@ func TestNewExtractor(t *testing.T) {
 		t.Fatal("Extractor is nil")
 	}
 
-	// Check that we get a SimpleExtractor
-	_, ok := extractor.(*SimpleExtractor)
-	if !ok {
-		t.Errorf("Expected a SimpleExtractor implementation")
-	}
+	// Check that the SimpleExtractor satisfies the pipeline interface
+	var _ models.Extractor = extractor
 }
 
 // TestExtract tests the content extraction functionality
@@ -72,7 +70,7 @@ func TestExtract(t *testing.T) {
 </html>`
 
 	// Create a scrape result
-	result := &scraper.ScrapeResult{
+	result := &models.RawContent{
 		URL:  "https://example.com/article",
 		HTML: html,
 	}
@@ -89,7 +87,7 @@ func TestExtract(t *testing.T) {
 	}
 
 	// Extract content
-	content, err := extractor.Extract(result)
+	content, err := extractor.Extract(context.Background(), result)
 	if err != nil {
 		t.Fatalf("Failed to extract content: %v", err)
 	}
@@ -104,25 +102,25 @@ func TestExtract(t *testing.T) {
 	}
 
 	// Main content should contain the article text
-	if !strings.Contains(content.Content, "Main Article Heading") {
+	if !strings.Contains(content.Text, "Main Article Heading") {
 		t.Errorf("Content should contain 'Main Article Heading'")
 	}
 
-	if !strings.Contains(content.Content, "This is the first paragraph") {
+	if !strings.Contains(content.Text, "This is the first paragraph") {
 		t.Errorf("Content should contain the first paragraph")
 	}
 
 	// Headers should be preserved
-	if cfg.PreserveHeadings && !strings.Contains(content.Content, "Section Heading") {
+	if cfg.PreserveHeadings && !strings.Contains(content.Text, "Section Heading") {
 		t.Errorf("Content should preserve section headings")
 	}
 
 	// Ensure navigation and footer are removed
-	if strings.Contains(content.Content, "Home") || strings.Contains(content.Content, "About") {
+	if strings.Contains(content.Text, "Home") || strings.Contains(content.Text, "About") {
 		t.Errorf("Navigation menu should be removed from content")
 	}
 
-	if strings.Contains(content.Content, "Copyright 2023") {
+	if strings.Contains(content.Text, "Copyright 2023") {
 		t.Errorf("Footer should be removed from content")
 	}
 
@@ -130,6 +128,11 @@ func TestExtract(t *testing.T) {
 	if cfg.ExtractImages && len(content.Images) == 0 {
 		t.Errorf("Images should be extracted when extract_images is true")
 	}
+
+	// Relative image URLs should be resolved against the page URL
+	if len(content.Images) > 0 && content.Images[0].URL != "https://example.com/images/test.jpg" {
+		t.Errorf("Expected absolute image URL, got '%s'", content.Images[0].URL)
+	}
 }
 
 // TestExtractMetadata tests metadata extraction capabilities
@@ -154,7 +157,7 @@ func TestExtractMetadata(t *testing.T) {
 </html>`
 
 	// Create a scrape result
-	result := &scraper.ScrapeResult{
+	result := &models.RawContent{
 		URL:  "https://example.com/metadata-article",
 		HTML: html,
 	}
@@ -171,7 +174,7 @@ func TestExtractMetadata(t *testing.T) {
 	}
 
 	// Extract content
-	content, err := extractor.Extract(result)
+	content, err := extractor.Extract(context.Background(), result)
 	if err != nil {
 		t.Fatalf("Failed to extract content: %v", err)
 	}
@@ -200,4 +203,62 @@ func TestExtractMetadata(t *testing.T) {
 	if content.Metadata["published_time"] != "2023-05-15T12:00:00Z" {
 		t.Errorf("Expected published_time '2023-05-15T12:00:00Z', got '%s'", content.Metadata["published_time"])
 	}
+
+	if content.Author != "John Doe" || content.Published != "2023-05-15T12:00:00Z" {
+		t.Errorf("Expected author and published time fields, got '%s' and '%s'", content.Author, content.Published)
+	}
+}
+
+// TestExtractCodeAndTables tests that code samples and tables are preserved
+// This test uses the simple extractor implementation
+func TestExtractCodeAndTables(t *testing.T) {
+	html := `<!DOCTYPE html>
+<html>
+<body>
+    <article>
+        <p>Install the tool first.</p>
+        <pre><code class="language-bash">go install ./cmd/tool
+tool   --verbose</code></pre>
+        <table>
+            <tr><th>Option</th><th>Default</th></tr>
+            <tr><td>verbose</td><td>false</td></tr>
+        </table>
+    </article>
+</body>
+</html>`
+
+	result := &models.RawContent{
+		URL:  "https://example.com/code-article",
+		HTML: html,
+	}
+
+	extractor, err := NewExtractor(config.ExtractionConfig{PreserveHeadings: true})
+	if err != nil {
+		t.Fatalf("Failed to create extractor: %v", err)
+	}
+
+	content, err := extractor.Extract(context.Background(), result)
+	if err != nil {
+		t.Fatalf("Failed to extract content: %v", err)
+	}
+
+	if len(content.Blocks) != 2 {
+		t.Fatalf("Expected 2 special blocks, got %d", len(content.Blocks))
+	}
+
+	code := content.Blocks[0]
+	if code.Kind != models.BlockCode || code.Language != "bash" {
+		t.Errorf("Expected a bash code block, got %s/%s", code.Kind, code.Language)
+	}
+	if content.Text[code.Start:code.End] != "go install ./cmd/tool\ntool   --verbose" {
+		t.Errorf("Code should be kept verbatim, got %q", content.Text[code.Start:code.End])
+	}
+
+	table := content.Blocks[1]
+	if table.Kind != models.BlockTable || len(table.Rows) != 2 || table.Rows[1][0] != "verbose" {
+		t.Errorf("Unexpected table block %+v", table)
+	}
+	if !strings.Contains(content.Text, "| Option | Default |\n| --- | --- |\n| verbose | false |") {
+		t.Errorf("Table should be converted to Markdown, got %q", content.Text)
+	}
 }
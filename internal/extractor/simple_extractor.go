@@ -5,101 +5,106 @@
 package extractor
 
 import (
+	"context"
+	"fmt"
+	"net/url"
 	"strings"
 
 	"golang.org/x/net/html"
 
 	"github.com/ncolesummers/scrape-pipeline/internal/config"
-	"github.com/ncolesummers/scrape-pipeline/internal/scraper"
+	"github.com/ncolesummers/scrape-pipeline/internal/htmlutil"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
 )
 
-// Image represents an extracted image
-type Image struct {
-	URL         string
-	Alt         string
-	Description string
+// content collects what the extractor finds while walking the page.
+// Blocks holds the code samples and tables found in the main content, with
+// offsets into the text written so far.
+type content struct {
+	extracted *models.ExtractedContent
+	metadata  map[string]string
+	main      *html.Node
+	blocks    []models.Block
 }
 
-// Content represents the extracted content from HTML
-type Content struct {
-	Metadata  map[string]string
-	Title     string
-	Content   string
-	URL       string
-	Images    []Image
-	WordCount int
-}
-
-// Extractor is the interface for content extractors
-type Extractor interface {
-	// Extract extracts content from scraped HTML
-	Extract(result *scraper.ScrapeResult) (*Content, error)
-}
-
-// SimpleExtractor implements the Extractor interface using basic HTML parsing
+// SimpleExtractor implements the models.Extractor interface using basic HTML parsing
 // This is a simplified version for testing purposes only
 type SimpleExtractor struct {
 	config config.ExtractionConfig
 }
 
 // NewExtractor creates a new extractor instance based on the provided configuration
-func NewExtractor(cfg config.ExtractionConfig) (Extractor, error) {
+func NewExtractor(cfg config.ExtractionConfig) (*SimpleExtractor, error) {
 	return &SimpleExtractor{
 		config: cfg,
 	}, nil
 }
 
 // Extract extracts content from scraped HTML
-func (e *SimpleExtractor) Extract(result *scraper.ScrapeResult) (*Content, error) {
+func (e *SimpleExtractor) Extract(ctx context.Context, rawContent *models.RawContent) (*models.ExtractedContent, error) {
+	// Check if context is canceled
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
 	// In a real implementation, we would use the go-readability library
 	// For this test implementation, we'll use a simple HTML parser
 
 	// Parse the HTML
-	doc, err := html.Parse(strings.NewReader(result.HTML))
+	doc, err := html.Parse(strings.NewReader(rawContent.HTML))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
 
 	// Initialize the content
-	content := &Content{
-		URL:      result.URL,
-		Metadata: make(map[string]string),
-		Images:   []Image{},
+	c := &content{
+		extracted: &models.ExtractedContent{URL: rawContent.URL},
+		metadata:  make(map[string]string),
 	}
 
 	// Extract metadata and content
-	e.extractTitle(doc, content)
-	e.extractMetadata(doc, content)
-	e.extractMainContent(doc, content)
+	e.extractTitle(doc, c)
+	e.extractMetadata(doc, c)
+	e.extractMainContent(doc, c)
 
 	if e.config.ExtractImages {
-		e.extractImages(doc, content)
+		e.extractImages(doc, c)
 	}
 
-	// Count words in content
-	content.WordCount = len(strings.Fields(content.Content))
+	extracted := c.extracted
+	extracted.Author = c.metadata["author"]
+	extracted.Description = c.metadata["description"]
+	extracted.Published = c.metadata["published_time"]
+	extracted.Blocks = c.blocks
+	extracted.Metadata = c.metadata
+	extracted.ExtractionStrategy = "custom"
+	if c.main != nil {
+		extracted.Content = htmlutil.Render(c.main)
+	}
 
-	return content, nil
+	return extracted, nil
 }
 
-func (e *SimpleExtractor) extractTitle(doc *html.Node, content *Content) {
+func (e *SimpleExtractor) extractTitle(doc *html.Node, c *content) {
 	var extractTitle func(*html.Node)
 	extractTitle = func(n *html.Node) {
 		if n.Type == html.ElementNode && n.Data == "title" {
 			if n.FirstChild != nil {
-				content.Title = n.FirstChild.Data
-				content.Metadata["title"] = content.Title
+				c.extracted.Title = n.FirstChild.Data
+				c.metadata["title"] = c.extracted.Title
 				return
 			}
 		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			extractTitle(c)
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			extractTitle(child)
 		}
 	}
 	extractTitle(doc)
 }
 
-func (e *SimpleExtractor) extractMetadata(doc *html.Node, content *Content) {
+func (e *SimpleExtractor) extractMetadata(doc *html.Node, c *content) {
 	var extractMeta func(*html.Node)
 	extractMeta = func(n *html.Node) {
 		if n.Type == html.ElementNode && n.Data == "meta" {
@@ -115,23 +120,23 @@ func (e *SimpleExtractor) extractMetadata(doc *html.Node, content *Content) {
 			}
 
 			if name != "" && contentValue != "" {
-				content.Metadata[name] = contentValue
+				c.metadata[name] = contentValue
 			} else if property != "" && contentValue != "" {
 				if strings.HasPrefix(property, "og:") {
-					content.Metadata[property] = contentValue
+					c.metadata[property] = contentValue
 				} else if property == "article:published_time" {
-					content.Metadata["published_time"] = contentValue
+					c.metadata["published_time"] = contentValue
 				}
 			}
 		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			extractMeta(c)
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			extractMeta(child)
 		}
 	}
 	extractMeta(doc)
 }
 
-func (e *SimpleExtractor) extractMainContent(doc *html.Node, content *Content) {
+func (e *SimpleExtractor) extractMainContent(doc *html.Node, c *content) {
 	// In a real implementation, this would be much more sophisticated
 	// For now, we'll extract content from either the main or article tag
 	var sb strings.Builder
@@ -144,12 +149,13 @@ func (e *SimpleExtractor) extractMainContent(doc *html.Node, content *Content) {
 		}
 
 		if n.Type == html.ElementNode && (n.Data == "article" || n.Data == "main") {
-			e.extractNodeText(n, &sb, true)
+			e.extractNodeText(n, &sb, c, true)
+			c.main = n
 			contentFound = true
 			return
 		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			extractContent(c)
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			extractContent(child)
 		}
 	}
 
@@ -160,21 +166,22 @@ func (e *SimpleExtractor) extractMainContent(doc *html.Node, content *Content) {
 		var extractArticle func(*html.Node)
 		extractArticle = func(n *html.Node) {
 			if n.Type == html.ElementNode && n.Data == "article" {
-				e.extractNodeText(n, &sb, true)
+				e.extractNodeText(n, &sb, c, true)
+				c.main = n
 				contentFound = true
 				return
 			}
-			for c := n.FirstChild; c != nil; c = c.NextSibling {
-				extractArticle(c)
+			for child := n.FirstChild; child != nil; child = child.NextSibling {
+				extractArticle(child)
 			}
 		}
 		extractArticle(doc)
 	}
 
-	content.Content = sb.String()
+	c.extracted.Text = sb.String()
 }
 
-func (e *SimpleExtractor) extractNodeText(n *html.Node, sb *strings.Builder, isRoot bool) {
+func (e *SimpleExtractor) extractNodeText(n *html.Node, sb *strings.Builder, c *content, isRoot bool) {
 	// Skip non-content elements
 	if n.Type == html.ElementNode {
 		if n.Data == "script" || n.Data == "style" || n.Data == "nav" || n.Data == "footer" {
@@ -187,9 +194,9 @@ func (e *SimpleExtractor) extractNodeText(n *html.Node, sb *strings.Builder, isR
 			if e.config.PreserveHeadings {
 				sb.WriteString("\n\n")
 				// Process child nodes for headings
-				for c := n.FirstChild; c != nil; c = c.NextSibling {
-					if c.Type == html.TextNode {
-						text := strings.TrimSpace(c.Data)
+				for child := n.FirstChild; child != nil; child = child.NextSibling {
+					if child.Type == html.TextNode {
+						text := strings.TrimSpace(child.Data)
 						if text != "" {
 							sb.WriteString(text)
 							sb.WriteString(" ")
@@ -204,18 +211,58 @@ func (e *SimpleExtractor) extractNodeText(n *html.Node, sb *strings.Builder, isR
 				sb.WriteString("\n\n")
 			}
 			// Get the paragraph text
-			for c := n.FirstChild; c != nil; c = c.NextSibling {
-				e.extractNodeText(c, sb, false)
+			for child := n.FirstChild; child != nil; child = child.NextSibling {
+				e.extractNodeText(child, sb, c, false)
 			}
 			sb.WriteString("\n")
 			return
 		case "br":
 			sb.WriteString("\n")
 			return
+		case "pre":
+			// Code is kept verbatim rather than flattened into words
+			code := htmlutil.PreformattedText(n)
+			if code == "" {
+				return
+			}
+			sb.WriteString("\n\n")
+			start := sb.Len()
+			sb.WriteString(code)
+			language := htmlutil.CodeLanguage(n)
+			c.blocks = append(c.blocks, models.Block{
+				Kind:     models.BlockCode,
+				Language: language,
+				Text:     code,
+				Markdown: htmlutil.FencedCode(code, language),
+				Start:    start,
+				End:      sb.Len(),
+			})
+			sb.WriteString("\n")
+			return
+		case "table":
+			// Tables are written as Markdown so rows and columns survive
+			rows := htmlutil.TableRows(n)
+			table := htmlutil.MarkdownTable(rows)
+			if table == "" {
+				return
+			}
+			sb.WriteString("\n\n")
+			start := sb.Len()
+			sb.WriteString(table)
+			c.blocks = append(c.blocks, models.Block{
+				Kind:     models.BlockTable,
+				Text:     table,
+				Markdown: table,
+				Rows:     rows,
+				Start:    start,
+				End:      sb.Len(),
+			})
+			sb.WriteString("\n")
+			return
 		case "li":
 			sb.WriteString("\n- ")
-			for c := n.FirstChild; c != nil; c = c.NextSibling {
-				e.extractNodeText(c, sb, false)
+			for child := n.FirstChild; child != nil; child = child.NextSibling {
+				e.extractNodeText(child, sb, c, false)
 			}
 			return
 		}
@@ -231,34 +278,18 @@ func (e *SimpleExtractor) extractNodeText(n *html.Node, sb *strings.Builder, isR
 	}
 
 	// Process child nodes
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		e.extractNodeText(c, sb, false)
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		e.extractNodeText(child, sb, c, false)
 	}
 }
 
-func (e *SimpleExtractor) extractImages(doc *html.Node, content *Content) {
-	var extractImgs func(*html.Node)
-	extractImgs = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "img" {
-			var src, alt string
-			for _, attr := range n.Attr {
-				if attr.Key == "src" {
-					src = attr.Val
-				} else if attr.Key == "alt" {
-					alt = attr.Val
-				}
-			}
-
-			if src != "" {
-				content.Images = append(content.Images, Image{
-					URL: src,
-					Alt: alt,
-				})
-			}
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			extractImgs(c)
-		}
+// extractImages collects the images of the page with absolute URLs,
+// skipping tracking pixels and icons
+func (e *SimpleExtractor) extractImages(doc *html.Node, c *content) {
+	base, err := url.Parse(c.extracted.URL)
+	if err != nil {
+		base = nil
 	}
-	extractImgs(doc)
+
+	c.extracted.Images = htmlutil.Images(doc, base)
 }
@@ -0,0 +1,282 @@
+// Package htmlutil contains small helpers for walking and querying parsed HTML
+// documents. It is shared by the extractor implementations so they agree on
+// how attributes and text content are read.
+package htmlutil
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Attr returns the value of the named attribute, or an empty string if the
+// node does not have it
+func Attr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// IsElement reports whether n is an element node with one of the given tag names
+func IsElement(n *html.Node, tags ...string) bool {
+	if n == nil || n.Type != html.ElementNode {
+		return false
+	}
+	for _, tag := range tags {
+		if n.Data == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Walk calls fn for n and every descendant in document order.
+// If fn returns false the children of that node are skipped.
+func Walk(n *html.Node, fn func(*html.Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		Walk(c, fn)
+	}
+}
+
+// FindAll returns every element below n (including n) with one of the given tag names
+func FindAll(n *html.Node, tags ...string) []*html.Node {
+	var nodes []*html.Node
+	Walk(n, func(node *html.Node) bool {
+		if IsElement(node, tags...) {
+			nodes = append(nodes, node)
+		}
+		return true
+	})
+	return nodes
+}
+
+// TextContent returns the concatenated text of n and its descendants with
+// runs of whitespace collapsed to a single space
+func TextContent(n *html.Node) string {
+	var sb strings.Builder
+	Walk(n, func(node *html.Node) bool {
+		if IsElement(node, "script", "style", "noscript") {
+			return false
+		}
+		if node.Type == html.TextNode {
+			sb.WriteString(node.Data)
+			sb.WriteString(" ")
+		}
+		return true
+	})
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// languagePattern matches the class names syntax highlighters use for the
+// language of a code block
+var languagePattern = regexp.MustCompile(`^(?:language|lang|highlight|brush)-([A-Za-z0-9_+#.-]+)$`)
+
+// CodeLanguage returns the language hint of a <pre> block from a data-lang
+// attribute or the classes of the block or its <code> child
+func CodeLanguage(pre *html.Node) string {
+	candidates := []*html.Node{pre}
+	for c := pre.FirstChild; c != nil; c = c.NextSibling {
+		if IsElement(c, "code") {
+			candidates = append(candidates, c)
+		}
+	}
+
+	for _, n := range candidates {
+		if lang := Attr(n, "data-lang"); lang != "" {
+			return strings.ToLower(lang)
+		}
+		for _, class := range strings.Fields(Attr(n, "class")) {
+			if m := languagePattern.FindStringSubmatch(class); m != nil {
+				return strings.ToLower(m[1])
+			}
+		}
+	}
+	return ""
+}
+
+// PreformattedText returns the text of a <pre> block with its whitespace
+// intact. Line break elements become newlines and surrounding blank lines
+// are dropped.
+func PreformattedText(n *html.Node) string {
+	var sb strings.Builder
+	Walk(n, func(node *html.Node) bool {
+		switch {
+		case node.Type == html.TextNode:
+			sb.WriteString(node.Data)
+		case IsElement(node, "br"):
+			sb.WriteString("\n")
+		}
+		return true
+	})
+	return strings.TrimLeft(strings.TrimRight(sb.String(), "\n "), "\n")
+}
+
+// TableRows returns the text of every cell in a table, row by row
+func TableRows(table *html.Node) [][]string {
+	var rows [][]string
+	for _, tr := range FindAll(table, "tr") {
+		var cells []string
+		for c := tr.FirstChild; c != nil; c = c.NextSibling {
+			if IsElement(c, "td", "th") {
+				cells = append(cells, TextContent(c))
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+	}
+	return rows
+}
+
+// MarkdownTable lays out rows as a GitHub-flavored Markdown table with the
+// first row as the header. Short rows are padded so every row has the same
+// number of cells.
+func MarkdownTable(rows [][]string) string {
+	width := 0
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	if width == 0 {
+		return ""
+	}
+
+	lines := make([]string, 0, len(rows)+1)
+	for i, row := range rows {
+		cells := make([]string, width)
+		for j := range row {
+			cells[j] = strings.ReplaceAll(row[j], "|", `\|`)
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", width))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FencedCode wraps text in a Markdown code fence, annotated with language
+// when it is known. The fence is longer than any run of backticks in text.
+func FencedCode(text, language string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + text + "\n" + fence
+}
+
+// Render returns the HTML serialization of n
+func Render(n *html.Node) string {
+	var sb strings.Builder
+	if err := html.Render(&sb, n); err != nil {
+		return ""
+	}
+	return sb.String()
+}
+
+// NodePath returns an XPath-like location of n in its document, such as
+// "/html[1]/body[1]/div[2]/p[1]". Positions count element siblings with the
+// same tag name.
+func NodePath(n *html.Node) string {
+	var parts []string
+	for node := n; node != nil && node.Type == html.ElementNode; node = node.Parent {
+		position := 1
+		for s := node.PrevSibling; s != nil; s = s.PrevSibling {
+			if s.Type == html.ElementNode && s.Data == node.Data {
+				position++
+			}
+		}
+		parts = append(parts, node.Data+"["+strconv.Itoa(position)+"]")
+	}
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// opaqueElements have children that html.Render writes verbatim, so offsets
+// are not recorded inside them
+var opaqueElements = map[string]bool{
+	"iframe": true, "noembed": true, "noframes": true, "noscript": true,
+	"plaintext": true, "script": true, "style": true, "xmp": true,
+}
+
+// RenderWithOffsets returns the HTML serialization of n, identical to
+// Render, together with the byte offset at which each text node's escaped
+// text starts in it. Text inside script, style and foreign content such as
+// SVG is not recorded.
+func RenderWithOffsets(n *html.Node) (string, map[*html.Node]int) {
+	var sb strings.Builder
+	offsets := make(map[*html.Node]int)
+	renderOffsets(&sb, n, offsets)
+	return sb.String(), offsets
+}
+
+func renderOffsets(sb *strings.Builder, n *html.Node, offsets map[*html.Node]int) {
+	switch n.Type {
+	case html.DocumentNode:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			renderOffsets(sb, c, offsets)
+		}
+		return
+	case html.TextNode:
+		offsets[n] = sb.Len()
+		sb.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+		if n.Namespace == "" && !opaqueElements[n.Data] {
+			break
+		}
+		fallthrough
+	default:
+		sb.WriteString(Render(n))
+		return
+	}
+
+	// Render the element without children to get its tags
+	shallow := Render(&html.Node{Type: n.Type, DataAtom: n.DataAtom, Data: n.Data, Attr: n.Attr})
+	endTag := "</" + n.Data + ">"
+	if !strings.HasSuffix(shallow, endTag) {
+		// Void elements have no end tag
+		sb.WriteString(shallow)
+		return
+	}
+	sb.WriteString(strings.TrimSuffix(shallow, endTag))
+
+	// html.Render adds a newline that the parser strips from these elements
+	if IsElement(n, "pre", "listing", "textarea") && n.FirstChild != nil &&
+		n.FirstChild.Type == html.TextNode && strings.HasPrefix(n.FirstChild.Data, "\n") {
+		sb.WriteString("\n")
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		renderOffsets(sb, c, offsets)
+	}
+	sb.WriteString(endTag)
+}
+
+// RelativeNodePath returns the location of n below root in the form of
+// NodePath, starting at root itself, such as "/div[1]/p[2]". It resolves
+// the same way in a serialization of root alone.
+func RelativeNodePath(root, n *html.Node) string {
+	if root.Parent == nil || root.Parent.Type != html.ElementNode {
+		return NodePath(n)
+	}
+	// The root is always the first of its kind in its own serialization
+	path := strings.TrimPrefix(NodePath(n), NodePath(root.Parent))
+	rootStep, rest, _ := strings.Cut(path[1:], "/")
+	rootStep = rootStep[:strings.IndexByte(rootStep, '[')] + "[1]"
+	if rest == "" {
+		return "/" + rootStep
+	}
+	return "/" + rootStep + "/" + rest
+}
@@ -0,0 +1,65 @@
+package htmlutil
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestRenderWithOffsets(t *testing.T) {
+	page := `<div id="main"><p class="lead">Fish &amp; <b>chips</b></p>` +
+		`<pre>
+code &lt;here&gt;</pre><img src="a.png"><script>var a = "<b>";</script>` +
+		`<svg><text>ignored</text></svg><p>tail "quoted"</p></div>`
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("Failed to parse page: %v", err)
+	}
+	root := FindAll(doc, "div")[0]
+
+	got, offsets := RenderWithOffsets(root)
+	if want := Render(root); got != want {
+		t.Fatalf("RenderWithOffsets() = %q, want %q", got, want)
+	}
+
+	recorded := 0
+	Walk(root, func(n *html.Node) bool {
+		if n.Type != html.TextNode {
+			return true
+		}
+		start, ok := offsets[n]
+		if !ok {
+			return true
+		}
+		recorded++
+		escaped := html.EscapeString(n.Data)
+		if got[start:start+len(escaped)] != escaped {
+			t.Errorf("text %q recorded at %d, found %q", n.Data, start, got[start:start+len(escaped)])
+		}
+		return true
+	})
+	// Fish, chips, the code and the tail; script and SVG text are opaque
+	if recorded != 4 {
+		t.Errorf("Recorded %d text nodes, want 4", recorded)
+	}
+}
+
+func TestRelativeNodePath(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<div></div><div><p>a</p><p>b <em>c</em></p></div>`))
+	if err != nil {
+		t.Fatalf("Failed to parse page: %v", err)
+	}
+	root := FindAll(doc, "div")[1]
+	em := FindAll(root, "em")[0]
+
+	if got := RelativeNodePath(root, em); got != "/div[1]/p[2]/em[1]" {
+		t.Errorf("RelativeNodePath() = %q", got)
+	}
+	if got := RelativeNodePath(root, root); got != "/div[1]" {
+		t.Errorf("RelativeNodePath(root) = %q", got)
+	}
+	if got := RelativeNodePath(doc, em); got != NodePath(em) {
+		t.Errorf("RelativeNodePath(doc) = %q, want %q", got, NodePath(em))
+	}
+}
@@ -0,0 +1,218 @@
+package htmlutil
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+const (
+	// maxPixelSize is the largest declared dimension of a tracking pixel
+	maxPixelSize = 3
+	// maxIconSize is the largest declared dimension of an icon
+	maxIconSize = 48
+)
+
+// decorativeImage matches URLs and class names of tracking pixels, icons,
+// avatars and other images that carry no content
+var decorativeImage = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(?:pixel|tracking|beacon|spacer|blank\.gif|1x1|b\.gif|` +
+	`favicon|icons?|emoji|avatars?|gravatar|badges?|sprites?)(?:[^a-z0-9]|$)|` +
+	`facebook\.com/tr|google-analytics|doubleclick|\.ico(?:\?|$)`)
+
+// Images returns every content image below root. URLs are resolved against
+// base, the largest srcset candidate is preferred over src, and the caption
+// of an enclosing <figure> becomes the description. Tracking pixels, icons
+// and duplicate images are skipped.
+func Images(root *html.Node, base *url.URL) []models.ImageInfo {
+	var images []models.ImageInfo
+	seen := make(map[string]bool)
+
+	for _, img := range FindAll(root, "img") {
+		image, ok := imageInfo(img, base)
+		// The same image may be referenced by its src elsewhere on the page
+		src := ResolveURL(base, Attr(img, "src"))
+		if strings.HasPrefix(src, "data:") {
+			src = ""
+		}
+		if !ok || seen[image.URL] || (src != "" && seen[src]) {
+			continue
+		}
+		seen[image.URL] = true
+		if src != "" {
+			seen[src] = true
+		}
+		images = append(images, image)
+	}
+
+	return images
+}
+
+// IsDecorativeImage reports whether an image URL points at a tracking
+// pixel, icon or similar non-content image
+func IsDecorativeImage(imageURL string) bool {
+	return decorativeImage.MatchString(imageURL)
+}
+
+func imageInfo(img *html.Node, base *url.URL) (models.ImageInfo, bool) {
+	width := dimension(Attr(img, "width"))
+	height := dimension(Attr(img, "height"))
+
+	src, srcWidth := bestSource(img)
+	if src == "" {
+		return models.ImageInfo{}, false
+	}
+	if width == 0 {
+		width = srcWidth
+	}
+
+	resolved := ResolveURL(base, src)
+	if resolved == "" || isDecorative(img, resolved, width, height) {
+		return models.ImageInfo{}, false
+	}
+
+	return models.ImageInfo{
+		URL:         resolved,
+		Alt:         strings.TrimSpace(Attr(img, "alt")),
+		Description: figureCaption(img),
+		Width:       width,
+		Height:      height,
+	}, true
+}
+
+// bestSource returns the image URL to keep, preferring the widest srcset
+// candidate of the image or an enclosing <picture>, and the width its
+// descriptor declares
+func bestSource(img *html.Node) (string, int) {
+	srcsets := []string{Attr(img, "srcset"), Attr(img, "data-srcset")}
+	if IsElement(img.Parent, "picture") {
+		for c := img.Parent.FirstChild; c != nil; c = c.NextSibling {
+			if IsElement(c, "source") {
+				srcsets = append(srcsets, Attr(c, "srcset"))
+			}
+		}
+	}
+
+	var candidates []srcsetCandidate
+	for _, srcset := range srcsets {
+		candidates = append(candidates, parseSrcset(srcset)...)
+	}
+	if len(candidates) > 0 {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].score() > candidates[j].score()
+		})
+		return candidates[0].URL, candidates[0].Width
+	}
+
+	for _, key := range []string{"src", "data-src", "data-lazy-src"} {
+		if src := strings.TrimSpace(Attr(img, key)); src != "" && !strings.HasPrefix(src, "data:") {
+			return src, 0
+		}
+	}
+	return "", 0
+}
+
+// srcsetCandidate is one entry of a srcset attribute
+type srcsetCandidate struct {
+	URL     string
+	Width   int
+	Density float64
+}
+
+// score orders candidates by width descriptor, falling back to pixel density
+func (c srcsetCandidate) score() float64 {
+	if c.Width > 0 {
+		return float64(c.Width)
+	}
+	return c.Density
+}
+
+// parseSrcset parses a srcset attribute into its candidates. Candidates
+// without a descriptor have a density of 1.
+func parseSrcset(srcset string) []srcsetCandidate {
+	var candidates []srcsetCandidate
+	for _, entry := range strings.Split(srcset, ",") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "data:") {
+			continue
+		}
+
+		candidate := srcsetCandidate{URL: fields[0], Density: 1}
+		if len(fields) > 1 {
+			descriptor := fields[1]
+			switch {
+			case strings.HasSuffix(descriptor, "w"):
+				candidate.Width, _ = strconv.Atoi(strings.TrimSuffix(descriptor, "w"))
+			case strings.HasSuffix(descriptor, "x"):
+				if density, err := strconv.ParseFloat(strings.TrimSuffix(descriptor, "x"), 64); err == nil {
+					candidate.Density = density
+				}
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+// isDecorative reports whether an image is a tracking pixel or an icon,
+// judging by its declared size, URL and class names
+func isDecorative(img *html.Node, imageURL string, width, height int) bool {
+	if (width > 0 && width <= maxPixelSize) || (height > 0 && height <= maxPixelSize) {
+		return true
+	}
+	if width > 0 && height > 0 && width <= maxIconSize && height <= maxIconSize {
+		return true
+	}
+	if Attr(img, "role") == "presentation" || Attr(img, "aria-hidden") == "true" {
+		return true
+	}
+	return IsDecorativeImage(imageURL) || decorativeImage.MatchString(Attr(img, "class"))
+}
+
+// figureCaption returns the text of the <figcaption> of the nearest
+// enclosing <figure>
+func figureCaption(img *html.Node) string {
+	for n := img.Parent; n != nil; n = n.Parent {
+		if !IsElement(n, "figure") {
+			continue
+		}
+		for _, caption := range FindAll(n, "figcaption") {
+			return TextContent(caption)
+		}
+		return ""
+	}
+	return ""
+}
+
+// dimension parses a width or height attribute such as "640" or "640px".
+// Relative sizes such as percentages are ignored.
+func dimension(value string) int {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "px")
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 0 {
+		return 0
+	}
+	return size
+}
+
+// ResolveURL returns ref resolved against base, or an empty string for
+// references that cannot be followed such as javascript: links
+func ResolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(strings.ToLower(ref), "javascript:") {
+		return ""
+	}
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		parsed = base.ResolveReference(parsed)
+	}
+	return parsed.String()
+}
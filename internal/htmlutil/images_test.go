@@ -0,0 +1,73 @@
+package htmlutil
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestImages(t *testing.T) {
+	page := `<article>
+<figure>
+  <img src="/img/small.jpg" srcset="/img/small.jpg 480w, /img/large.jpg 1200w, /img/medium.jpg 800w"
+       alt="Throughput chart" width="600" height="400">
+  <figcaption>Requests per second   by version</figcaption>
+</figure>
+<picture>
+  <source srcset="https://cdn.example.com/hero@2x.webp 2x, https://cdn.example.com/hero.webp 1x">
+  <img src="hero.jpg" alt="Hero">
+</picture>
+<img src="/img/small.jpg" alt="Duplicate">
+<img src="https://tracker.example.com/pixel.gif" width="1" height="1">
+<img src="/static/share-icon.png" alt="Share">
+<img src="/img/author.jpg" width="32" height="32">
+<img src="data:image/gif;base64,R0lGOD" data-src="lazy.png" alt="Lazy">
+</article>`
+
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	base, _ := url.Parse("https://blog.example.com/posts/perf")
+
+	images := Images(doc, base)
+	if len(images) != 3 {
+		t.Fatalf("Expected 3 content images, got %d: %+v", len(images), images)
+	}
+
+	chart := images[0]
+	if chart.URL != "https://blog.example.com/img/large.jpg" {
+		t.Errorf("Expected the widest srcset candidate, got %s", chart.URL)
+	}
+	if chart.Width != 600 || chart.Height != 400 {
+		t.Errorf("Expected dimensions from attributes, got %dx%d", chart.Width, chart.Height)
+	}
+	if chart.Alt != "Throughput chart" || chart.Description != "Requests per second by version" {
+		t.Errorf("Unexpected alt/description %q/%q", chart.Alt, chart.Description)
+	}
+
+	if images[1].URL != "https://cdn.example.com/hero@2x.webp" {
+		t.Errorf("Expected the densest <picture> source, got %s", images[1].URL)
+	}
+	if images[2].URL != "https://blog.example.com/posts/lazy.png" {
+		t.Errorf("Expected the lazy-loaded source resolved to an absolute URL, got %s", images[2].URL)
+	}
+}
+
+func TestIsDecorativeImage(t *testing.T) {
+	tests := map[string]bool{
+		"https://example.com/favicon.ico":            true,
+		"https://example.com/assets/icons/rss.svg":   true,
+		"https://www.facebook.com/tr?id=1&ev=View":   true,
+		"https://example.com/img/silicon-valley.jpg": false,
+		"https://example.com/img/diagram.png":        false,
+	}
+
+	for imageURL, want := range tests {
+		if got := IsDecorativeImage(imageURL); got != want {
+			t.Errorf("IsDecorativeImage(%q) = %v, want %v", imageURL, got, want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+// BlockKind identifies a special block that later stages must keep whole
+type BlockKind string
+
+const (
+	// BlockCode is a preformatted code sample
+	BlockCode BlockKind = "code"
+	// BlockTable is a data table
+	BlockTable BlockKind = "table"
+)
+
+// Block is a code sample or table preserved verbatim during extraction.
+// Text is the block as it appears in the text rendering, Markdown the
+// fenced code block or GitHub-flavored table, and Rows the table cells with
+// the header row first. Start and End are byte offsets into the text the
+// block was recorded for.
+type Block struct {
+	Kind     BlockKind
+	Language string
+	Text     string
+	Markdown string
+	Rows     [][]string
+	Start    int
+	End      int
+}
+
+// BlockAt returns the block that contains offset, or nil when the offset
+// is outside every block
+func BlockAt(blocks []Block, offset int) *Block {
+	for i := range blocks {
+		if offset >= blocks[i].Start && offset < blocks[i].End {
+			return &blocks[i]
+		}
+	}
+	return nil
+}
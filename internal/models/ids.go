@@ -0,0 +1,77 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/urlutil"
+)
+
+// IDs are content addressed so a re-crawl produces the same IDs for
+// unchanged content and storage can upsert by ID:
+//
+//   - a document ID is the hash of the page's canonical URL, so the same
+//     page reached through different links is one document
+//   - a chunk ID is the hash of the document ID, the hash of the chunk text
+//     and the chunk's occurrence, its position among the chunks of the
+//     document with the same text. Byte offsets are deliberately not part of
+//     the ID: an edit early in a page would otherwise change the ID of every
+//     chunk after it.
+//   - an embedding ID is the hash of the chunk ID and the model name and
+//     version, so vectors from different models can be stored side by side
+//
+// Every ID is the first 16 bytes of a SHA-256 hash in hexadecimal.
+
+// idBytes is the number of hash bytes kept in an ID
+const idBytes = 16
+
+// DocumentID returns the ID of the page at pageURL. URLs that cannot be
+// canonicalized are hashed as given.
+func DocumentID(pageURL string) string {
+	if canonical, err := urlutil.Normalize(pageURL); err == nil {
+		pageURL = canonical
+	}
+	return hashID(pageURL)
+}
+
+// ChunkID returns the ID of a chunk of document docID with the given text.
+// occurrence counts the earlier chunks of the document with the same text.
+func ChunkID(docID, text string, occurrence int) string {
+	return hashID(docID, ContentHash(text), strconv.Itoa(occurrence))
+}
+
+// EmbeddingID returns the ID of the embedding of a chunk by a model
+func EmbeddingID(chunkID, model, version string) string {
+	return hashID(chunkID, model, version)
+}
+
+// ContentHash returns the SHA-256 hash of text in hexadecimal
+func ContentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// AssignChunkIDs sets the ID of every chunk of document docID from its text
+// and occurrence. chunks must be in document order.
+func AssignChunkIDs(docID string, chunks []*ContentChunk) {
+	seen := make(map[string]int)
+	for _, chunk := range chunks {
+		hash := ContentHash(chunk.Text)
+		chunk.ID = hashID(docID, hash, strconv.Itoa(seen[hash]))
+		seen[hash]++
+	}
+}
+
+// hashID hashes parts separated by NUL bytes so that no two different
+// sequences of parts hash the same input
+func hashID(parts ...string) string {
+	h := sha256.New()
+	for i, part := range parts {
+		if i > 0 {
+			h.Write([]byte{0})
+		}
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:idBytes])
+}
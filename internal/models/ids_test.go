@@ -0,0 +1,56 @@
+package models
+
+import "testing"
+
+func TestDocumentID(t *testing.T) {
+	id := DocumentID("https://example.com/post")
+	if len(id) != 2*idBytes {
+		t.Fatalf("DocumentID() = %q, want %d hex digits", id, 2*idBytes)
+	}
+	if DocumentID("HTTPS://Example.com/post?utm_source=feed#top") != id {
+		t.Error("Variants of the same URL should share an ID")
+	}
+	if DocumentID("https://example.com/other") == id {
+		t.Error("Different pages should not share an ID")
+	}
+}
+
+func TestAssignChunkIDs(t *testing.T) {
+	docID := DocumentID("https://example.com/post")
+	chunks := []*ContentChunk{{Text: "intro"}, {Text: "repeated"}, {Text: "body"}, {Text: "repeated"}}
+	AssignChunkIDs(docID, chunks)
+
+	seen := make(map[string]bool)
+	for _, chunk := range chunks {
+		if seen[chunk.ID] {
+			t.Errorf("Duplicate chunk ID %q", chunk.ID)
+		}
+		seen[chunk.ID] = true
+	}
+	if chunks[1].ID != ChunkID(docID, "repeated", 0) || chunks[3].ID != ChunkID(docID, "repeated", 1) {
+		t.Error("Repeated text should be told apart by occurrence")
+	}
+
+	// Inserting a chunk before the others keeps their IDs
+	edited := []*ContentChunk{{Text: "new lede"}, {Text: "intro"}, {Text: "repeated"}, {Text: "body"}}
+	AssignChunkIDs(docID, edited)
+	for i, chunk := range edited[1:] {
+		if chunk.ID != chunks[i].ID {
+			t.Errorf("Chunk %q changed ID after an insertion", chunk.Text)
+		}
+	}
+
+	if ChunkID(DocumentID("https://example.com/other"), "intro", 0) == chunks[0].ID {
+		t.Error("Chunks of different documents should not share an ID")
+	}
+}
+
+func TestEmbeddingID(t *testing.T) {
+	chunkID := ChunkID(DocumentID("https://example.com/post"), "text", 0)
+	if EmbeddingID(chunkID, "model", "1") == EmbeddingID(chunkID, "model", "2") {
+		t.Error("Embeddings by different model versions should not share an ID")
+	}
+	if EmbeddingID(chunkID, "model", "1") != EmbeddingID(chunkID, "model", "1") {
+		t.Error("Embedding IDs should be deterministic")
+	}
+}
@@ -16,15 +16,41 @@ type RawContent struct {
 
 // ExtractedContent represents content after extraction from HTML
 type ExtractedContent struct {
-	URL       string
-	Title     string
-	Content   string
+	URL   string
+	Title string
+	// Description is the summary the page gives of itself
+	Description string
+	// Content is the cleaned article HTML
+	Content string
+	// Text is a plain-text rendering of Content
+	Text string
+	// Markdown is an optional CommonMark rendering of Content
+	Markdown string
+	// Comments holds the text of the comment threads when they are kept
+	Comments  string
 	Author    string
 	Published string
 	Updated   string
 	Language  string
 	Tags      []string
 	Images    []ImageInfo
+	// Links holds the normalized outlinks of the article
+	Links []Link
+	// Outline and Blocks hold offsets into Text
+	Outline []*Section
+	Blocks  []Block
+	// Segments lists the labeled regions of the page separated from the
+	// article
+	Segments []Segment
+	// SourceMap ties Text back to Content
+	SourceMap SourceMap
+	// Metadata holds page metadata, such as meta elements, that has no
+	// dedicated field
+	Metadata map[string]string
+	// ExtractionStrategy and ExtractionScore record which strategy produced
+	// the content when several were compared
+	ExtractionStrategy string
+	ExtractionScore    float64
 }
 
 // ImageInfo represents metadata about an image in the content
@@ -36,23 +62,44 @@ type ImageInfo struct {
 	Height      int
 }
 
-// NormalizedContent represents content after normalization
+// NormalizedContent represents content after normalization.
+// Outline and Blocks mirror those of Original with offsets remapped to Text.
+// Offsets maps each byte offset of Text, and its end, to the offset of
+// Original.Text it was normalized from; see Cite. Redactions lists the
+// personal data replaced in Text.
 type NormalizedContent struct {
-	ID       string
-	Original *ExtractedContent
-	Text     string
+	ID         string
+	Original   *ExtractedContent
+	Text       string
+	Outline    []*Section
+	Blocks     []Block
+	Offsets    []int
+	Redactions []Redaction
 }
 
-// ContentChunk represents a chunk of content ready for embedding
+// ContentChunk represents a chunk of content ready for embedding.
+// Context is an optional header describing where the chunk comes from,
+// such as the page title and section. It is embedded along with Text but
+// is not part of the chunk's display text.
 type ContentChunk struct {
 	ID       string
 	Text     string
+	Context  string
 	Metadata map[string]interface{}
 	Source   string
 	Start    int
 	End      int
 }
 
+// EmbeddingText returns the text to embed for the chunk: its context
+// header, if any, followed by its text
+func (c *ContentChunk) EmbeddingText() string {
+	if c.Context == "" {
+		return c.Text
+	}
+	return c.Context + "\n\n" + c.Text
+}
+
 // VectorEmbedding represents a vector embedding of a content chunk
 type VectorEmbedding struct {
 	Chunk   *ContentChunk
@@ -107,6 +154,15 @@ type Embedder interface {
 	Embed(ctx context.Context, chunks []*ContentChunk) ([]*VectorEmbedding, error)
 }
 
+// VersionedEmbedder is implemented by embedders that report the model and
+// version of the embeddings they return
+type VersionedEmbedder interface {
+	Embedder
+
+	// Model returns the model name and version recorded in every embedding
+	Model() (name, version string)
+}
+
 // VectorStorage defines the interface for the vector storage module
 type VectorStorage interface {
 	// Store saves vector embeddings to storage
@@ -119,6 +175,29 @@ type VectorStorage interface {
 	Delete(ctx context.Context, ids []string) error
 }
 
+// LookupStorage is implemented by vector storage that returns stored
+// embeddings by ID
+type LookupStorage interface {
+	VectorStorage
+
+	// Get returns the stored embeddings with the given IDs, skipping IDs
+	// that are not stored
+	Get(ctx context.Context, ids []string) ([]*VectorEmbedding, error)
+}
+
+// HierarchicalStorage is implemented by vector storage that supports
+// small-to-big retrieval over parent and child chunks
+type HierarchicalStorage interface {
+	VectorStorage
+
+	// QueryParents searches the child chunks for similar vectors and returns
+	// their parents
+	QueryParents(ctx context.Context, queryVector []float32, limit int) ([]*VectorEmbedding, error)
+
+	// Parent returns the parent of the chunk with the given ID, or nil
+	Parent(ctx context.Context, chunkID string) (*VectorEmbedding, error)
+}
+
 // Observer defines the interface for the observability module
 type Observer interface {
 	// RecordMetric records a metric
@@ -0,0 +1,12 @@
+package models
+
+// Link is an outlink found in extracted content. URL is normalized, Text is
+// the anchor text and Context the surrounding sentence or block text.
+// Internal links point to the same site as the page they were found on.
+type Link struct {
+	URL      string
+	Text     string
+	Context  string
+	Rel      string
+	Internal bool
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// metadataDecoders holds the decoders registered with RegisterMetadataType
+var metadataDecoders = struct {
+	sync.RWMutex
+	byKey map[string]func(json.RawMessage) (interface{}, error)
+}{byKey: make(map[string]func(json.RawMessage) (interface{}, error))}
+
+// RegisterMetadataType registers T as the type of the chunk metadata under
+// key, so that a ContentChunk decoded from JSON holds a T there rather than
+// the float64 or []interface{} JSON would give. The package that sets a key
+// registers it, typically in init.
+func RegisterMetadataType[T any](key string) {
+	metadataDecoders.Lock()
+	defer metadataDecoders.Unlock()
+	metadataDecoders.byKey[key] = func(data json.RawMessage) (interface{}, error) {
+		var v T
+		err := json.Unmarshal(data, &v)
+		return v, err
+	}
+}
+
+// UnmarshalJSON decodes a chunk, giving the metadata of registered keys
+// their registered types
+func (c *ContentChunk) UnmarshalJSON(data []byte) error {
+	type plain ContentChunk
+	raw := struct {
+		*plain
+		Metadata map[string]json.RawMessage
+	}{plain: (*plain)(c)}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Metadata == nil {
+		c.Metadata = nil
+		return nil
+	}
+
+	metadataDecoders.RLock()
+	defer metadataDecoders.RUnlock()
+	c.Metadata = make(map[string]interface{}, len(raw.Metadata))
+	for key, value := range raw.Metadata {
+		var v interface{}
+		var err error
+		if decode, ok := metadataDecoders.byKey[key]; ok {
+			v, err = decode(value)
+		} else {
+			err = json.Unmarshal(value, &v)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decode chunk metadata %s: %w", key, err)
+		}
+		c.Metadata[key] = v
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestContentChunkKeepsRegisteredMetadataTypes(t *testing.T) {
+	RegisterMetadataType[int]("test_index")
+	RegisterMetadataType[[]Redaction]("test_redactions")
+
+	chunk := &ContentChunk{
+		ID:     "c1",
+		Text:   "Mail me at someone@example.com",
+		Source: "https://example.com/post",
+		Start:  4,
+		End:    34,
+		Metadata: map[string]interface{}{
+			"test_index":      2,
+			"test_redactions": []Redaction{{Kind: "email", Action: "redact", Start: 11, End: 30}},
+			"title":           "Post",
+			"unregistered":    3,
+		},
+	}
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		t.Fatalf("Failed to encode chunk: %v", err)
+	}
+	var got ContentChunk
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Failed to decode chunk: %v", err)
+	}
+
+	want := *chunk
+	want.Metadata = map[string]interface{}{
+		"test_index":      2,
+		"test_redactions": chunk.Metadata["test_redactions"],
+		"title":           "Post",
+		// Keys nobody registered decode as JSON decodes them
+		"unregistered": 3.0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decoded chunk = %#v, want %#v", got, want)
+	}
+
+	if err := json.Unmarshal([]byte(`{"Metadata":{"test_index":"two"}}`), &got); err == nil {
+		t.Error("Expected an error for metadata of the wrong type")
+	}
+}
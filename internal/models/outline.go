@@ -0,0 +1,58 @@
+package models
+
+// Section is a node in the heading outline of a document. Start and End are
+// byte offsets into the text the outline was built for; a section spans its
+// heading and everything up to the next heading of the same or higher level.
+type Section struct {
+	Heading  string
+	Anchor   string
+	Children []*Section
+	Level    int
+	Start    int
+	End      int
+}
+
+// Contains reports whether offset falls inside the section
+func (s *Section) Contains(offset int) bool {
+	return offset >= s.Start && offset < s.End
+}
+
+// SectionPath returns the chain of sections enclosing offset, from the
+// outermost section down to the innermost one
+func SectionPath(outline []*Section, offset int) []*Section {
+	var path []*Section
+	sections := outline
+	for {
+		var next *Section
+		for _, section := range sections {
+			if section.Contains(offset) {
+				next = section
+				break
+			}
+		}
+		if next == nil {
+			return path
+		}
+		path = append(path, next)
+		sections = next.Children
+	}
+}
+
+// HeadingPath returns the headings enclosing offset, suitable for building
+// a breadcrumb such as "Install > Linux > Debian"
+func HeadingPath(outline []*Section, offset int) []string {
+	sections := SectionPath(outline, offset)
+	headings := make([]string, len(sections))
+	for i, section := range sections {
+		headings[i] = section.Heading
+	}
+	return headings
+}
+
+// WalkOutline calls fn for every section in document order
+func WalkOutline(outline []*Section, fn func(*Section)) {
+	for _, section := range outline {
+		fn(section)
+		WalkOutline(section.Children, fn)
+	}
+}
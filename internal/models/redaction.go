@@ -0,0 +1,24 @@
+package models
+
+// Redaction records personal data removed from normalized text. Kind is
+// the type of data, such as "email", and Action how it was replaced:
+// "redact", "mask" or "hash". Start and End are byte offsets of the
+// replacement in NormalizedContent.Text. The original value is not kept.
+type Redaction struct {
+	Kind   string
+	Action string
+	Start  int
+	End    int
+}
+
+// RedactionsIn returns the redactions that overlap the range start to end
+// of the normalized text, such as the span of a chunk
+func (c *NormalizedContent) RedactionsIn(start, end int) []Redaction {
+	var redactions []Redaction
+	for _, r := range c.Redactions {
+		if r.Start < end && start < r.End {
+			redactions = append(redactions, r)
+		}
+	}
+	return redactions
+}
@@ -0,0 +1,31 @@
+package models
+
+// SegmentLabel classifies a region of a page
+type SegmentLabel string
+
+const (
+	// SegmentMain is the article itself
+	SegmentMain SegmentLabel = "main"
+	// SegmentComments is a reader comment thread
+	SegmentComments SegmentLabel = "comments"
+	// SegmentBio is an author biography box
+	SegmentBio SegmentLabel = "bio"
+	// SegmentRelated is a list of related or recommended posts
+	SegmentRelated SegmentLabel = "related"
+	// SegmentCTA is a call to action such as a newsletter signup
+	SegmentCTA SegmentLabel = "cta"
+	// SegmentNav is site navigation, breadcrumbs or pagination
+	SegmentNav SegmentLabel = "nav"
+	// SegmentTemplate is a block that recurs across the pages of a site
+	SegmentTemplate SegmentLabel = "template"
+)
+
+// Segment is a labeled region of the page recorded during extraction.
+// Path locates the element in the page, Excerpt holds the start of its
+// text and Length the length of its full text.
+type Segment struct {
+	Label   SegmentLabel
+	Path    string
+	Excerpt string
+	Length  int
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/urlutil"
+)
+
+// SourceSpan ties a word of the extracted text to the HTML it was rendered
+// from. Start and End are byte offsets into ExtractedContent.Text,
+// HTMLStart and HTMLEnd into ExtractedContent.Content, and Path locates the
+// element containing the word within Content in the form
+// "/div[1]/p[2]".
+type SourceSpan struct {
+	Path      string
+	Start     int
+	End       int
+	HTMLStart int
+	HTMLEnd   int
+}
+
+// SourceMap lists the spans of the extracted text in order of Start.
+// Text the extractor added itself, such as list markers or whitespace
+// between blocks, has no span.
+type SourceMap []SourceSpan
+
+// Span returns the span containing the text offset, or the closest span
+// before it. It returns false when no span starts at or before offset.
+func (m SourceMap) Span(offset int) (SourceSpan, bool) {
+	i := sort.Search(len(m), func(i int) bool { return m[i].Start > offset })
+	if i == 0 {
+		return SourceSpan{}, false
+	}
+	return m[i-1], true
+}
+
+// HTMLOffset returns the offset into Content corresponding to a text
+// offset. Offsets between spans map to the end of the preceding span.
+func (m SourceMap) HTMLOffset(offset int) (int, bool) {
+	span, ok := m.Span(offset)
+	if !ok {
+		if len(m) == 0 {
+			return 0, false
+		}
+		return m[0].HTMLStart, true
+	}
+	if offset >= span.End {
+		return span.HTMLEnd, true
+	}
+	// Escaped characters make the HTML longer than the text
+	return min(span.HTMLStart+offset-span.Start, span.HTMLEnd), true
+}
+
+// Citation locates a range of normalized text on its source page
+type Citation struct {
+	// URL is the page URL with a text fragment highlighting the quote
+	URL string
+	// Quote is the cited extracted text
+	Quote string
+	// Path is the element holding the start of the quote within Content
+	Path string
+	// Start and End are byte offsets into ExtractedContent.Text
+	Start int
+	End   int
+	// HTMLStart and HTMLEnd are byte offsets into ExtractedContent.Content
+	HTMLStart int
+	HTMLEnd   int
+}
+
+// OriginalOffset maps an offset into Text back to an offset into
+// Original.Text. Offsets without a mapping are returned unchanged.
+func (c *NormalizedContent) OriginalOffset(offset int) int {
+	if len(c.Offsets) == 0 {
+		return offset
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(c.Offsets) {
+		offset = len(c.Offsets) - 1
+	}
+	return c.Offsets[offset]
+}
+
+// Cite returns the citation for the normalized text between start and end,
+// such as the Start and End of a ContentChunk
+func (c *NormalizedContent) Cite(start, end int) Citation {
+	citation := Citation{Start: c.OriginalOffset(start), End: c.OriginalOffset(end)}
+	if c.Original == nil {
+		return citation
+	}
+	text := c.Original.Text
+	citation.Start = min(max(citation.Start, 0), len(text))
+	citation.End = min(max(citation.End, citation.Start), len(text))
+	quote := text[citation.Start:citation.End]
+	citation.Start += len(quote) - len(strings.TrimLeft(quote, " \t\n"))
+	citation.End -= len(quote) - len(strings.TrimRight(quote, " \t\n"))
+	citation.End = max(citation.End, citation.Start)
+	citation.Quote = text[citation.Start:citation.End]
+	citation.URL = urlutil.TextFragment(c.Original.URL, citation.Quote)
+
+	sourceMap := c.Original.SourceMap
+	if span, ok := sourceMap.Span(citation.Start); ok {
+		citation.Path = span.Path
+	}
+	citation.HTMLStart, _ = sourceMap.HTMLOffset(citation.Start)
+	citation.HTMLEnd, _ = sourceMap.HTMLOffset(citation.End)
+	return citation
+}
@@ -0,0 +1,89 @@
+// Package registry builds pipeline stage implementations by name so the
+// configuration, not the calling code, decides which implementation runs.
+// Every stage has its own Registry; the built-in implementations are
+// registered when the package is loaded and more can be added with Register.
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrUnknownImplementation is returned when no factory is registered under a name
+	ErrUnknownImplementation = errors.New("unknown implementation")
+	// ErrDuplicateImplementation is returned when a name is registered twice
+	ErrDuplicateImplementation = errors.New("implementation already registered")
+)
+
+// Factory builds an implementation of a stage from its configuration
+type Factory[C, T any] func(cfg C) (T, error)
+
+// Registry maps implementation names of one pipeline stage to factories.
+// It is safe for concurrent use.
+type Registry[C, T any] struct {
+	mu        sync.RWMutex
+	stage     string
+	factories map[string]Factory[C, T]
+}
+
+// New creates an empty Registry for the named stage
+func New[C, T any](stage string) *Registry[C, T] {
+	return &Registry[C, T]{stage: stage, factories: make(map[string]Factory[C, T])}
+}
+
+// Register adds a factory under name
+func (r *Registry[C, T]) Register(name string, factory Factory[C, T]) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.factories[name]; ok {
+		return fmt.Errorf("%s %q: %w", r.stage, name, ErrDuplicateImplementation)
+	}
+	r.factories[name] = factory
+	return nil
+}
+
+// Build creates the implementation registered under name
+func (r *Registry[C, T]) Build(name string, cfg C) (T, error) {
+	r.mu.RLock()
+	factory, ok := r.factories[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("%s %q (available: %s): %w",
+			r.stage, name, strings.Join(r.Names(), ", "), ErrUnknownImplementation)
+	}
+
+	impl, err := factory(cfg)
+	if err != nil {
+		var zero T
+		return zero, fmt.Errorf("failed to build %s %q: %w", r.stage, name, err)
+	}
+	return impl, nil
+}
+
+// Names returns the registered names, sorted
+func (r *Registry[C, T]) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mustRegister registers a built-in factory. Built-in names are constants,
+// so a duplicate is a programming error.
+func mustRegister[C, T any](r *Registry[C, T], name string, factory Factory[C, T]) {
+	if err := r.Register(name, factory); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,414 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/config"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/dedup"
+)
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head><title>Registry Test</title></head>
+<body>
+    <article>
+        <h1>Registry Test</h1>
+        <p>Every extractor registered by name must turn this page into extracted content. The text is
+        long enough for readability to accept the article element as the main content of the page,
+        which needs a few sentences with commas, periods, and plenty of ordinary words in them.</p>
+        <p>A second paragraph gives the scoring algorithms more to work with, so that the heuristic and
+        readability strategies agree on which element holds the article and what its text says.</p>
+    </article>
+</body>
+</html>`
+
+func TestNewExtractor(t *testing.T) {
+	for _, method := range []string{ExtractorReadability, ExtractorCustom, ExtractorHeuristic, ExtractorChain} {
+		t.Run(method, func(t *testing.T) {
+			cfg := &config.Config{Extraction: config.ExtractionConfig{Method: method, PreserveHeadings: true}}
+
+			extractor, err := NewExtractor(cfg)
+			if err != nil {
+				t.Fatalf("Failed to build extractor: %v", err)
+			}
+
+			content, err := extractor.Extract(context.Background(), &models.RawContent{
+				URL:  "https://example.com/registry",
+				HTML: testPage,
+			})
+			if err != nil {
+				t.Fatalf("Failed to extract content: %v", err)
+			}
+			if !strings.Contains(content.Text, "A second paragraph") {
+				t.Errorf("Expected the article text, got:\n%s", content.Text)
+			}
+		})
+	}
+}
+
+func TestNewExtractorSiteSpecific(t *testing.T) {
+	cfg := &config.Config{Extraction: config.ExtractionConfig{
+		Method: ExtractorSiteSpecific,
+		SiteSpecificRules: map[string]config.SiteRuleConfig{
+			"example.com": {ArticleSelector: "article"},
+		},
+	}}
+
+	extractor, err := NewExtractor(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build extractor: %v", err)
+	}
+	content, err := extractor.Extract(context.Background(), &models.RawContent{URL: "https://www.example.com/a", HTML: testPage})
+	if err != nil {
+		t.Fatalf("Failed to extract content: %v", err)
+	}
+	if content.Title != "Registry Test" {
+		t.Errorf("Expected the configured site rule to apply, got title %q", content.Title)
+	}
+}
+
+func TestNewScraper(t *testing.T) {
+	for _, kind := range []string{ScraperHTTP, ScraperColly} {
+		scraper, err := NewScraper(config.ScraperConfig{Name: "test", Type: kind, URL: "https://example.com", RateLimit: 1, Concurrency: 1})
+		if err != nil || scraper == nil {
+			t.Errorf("Failed to build %s scraper: %v", kind, err)
+		}
+	}
+}
+
+func TestUnknownImplementation(t *testing.T) {
+	_, err := NewExtractor(&config.Config{Extraction: config.ExtractionConfig{Method: "magic"}})
+	if !errors.Is(err, ErrUnknownImplementation) {
+		t.Fatalf("Expected ErrUnknownImplementation, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "readability") {
+		t.Errorf("Expected the error to list the available methods, got %v", err)
+	}
+
+	if _, err := NewScraper(config.ScraperConfig{Type: "ftp"}); !errors.Is(err, ErrUnknownImplementation) {
+		t.Errorf("Expected ErrUnknownImplementation, got %v", err)
+	}
+}
+
+// stubExtractor returns fixed content
+type stubExtractor struct{}
+
+func (stubExtractor) Extract(ctx context.Context, rawContent *models.RawContent) (*models.ExtractedContent, error) {
+	return &models.ExtractedContent{URL: rawContent.URL, Text: "stub"}, nil
+}
+
+func TestRegister(t *testing.T) {
+	r := New[config.ExtractionConfig, models.Extractor]("extractor")
+	factory := func(config.ExtractionConfig) (models.Extractor, error) { return stubExtractor{}, nil }
+
+	if err := r.Register("stub", factory); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	if err := r.Register("stub", factory); !errors.Is(err, ErrDuplicateImplementation) {
+		t.Errorf("Expected ErrDuplicateImplementation, got %v", err)
+	}
+
+	extractor, err := r.Build("stub", config.ExtractionConfig{})
+	if err != nil {
+		t.Fatalf("Failed to build: %v", err)
+	}
+	if content, _ := extractor.Extract(context.Background(), &models.RawContent{}); content.Text != "stub" {
+		t.Errorf("Expected the registered implementation, got %+v", content)
+	}
+
+	failing := func(config.ExtractionConfig) (models.Extractor, error) { return nil, errors.New("boom") }
+	if err := r.Register("failing", failing); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	if _, err := r.Build("failing", config.ExtractionConfig{}); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected the factory error to be returned, got %v", err)
+	}
+}
+
+func TestNewNormalizer(t *testing.T) {
+	cfg := &config.Config{Normalizer: config.NormalizerConfig{Method: NormalizerStandard, TrimWhitespace: true, UnifyPunctuation: true}}
+
+	normalizer, err := NewNormalizer(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build normalizer: %v", err)
+	}
+	normalized, err := normalizer.Normalize(context.Background(), &models.ExtractedContent{Text: "  “spaced”   out  "})
+	if err != nil {
+		t.Fatalf("Failed to normalize: %v", err)
+	}
+	if normalized.Text != `"spaced" out` {
+		t.Errorf("Expected the configured rules to apply, got %q", normalized.Text)
+	}
+
+	cfg.Normalizer.NormalizeUnicode = true
+	cfg.Normalizer.UnicodeForm = "bogus"
+	if _, err := NewNormalizer(cfg); err == nil {
+		t.Errorf("Expected an error for an invalid unicode form")
+	}
+}
+
+func TestNewNormalizerPII(t *testing.T) {
+	cfg := &config.Config{Normalizer: config.NormalizerConfig{
+		Method: NormalizerStandard,
+		PII:    config.PIIConfig{Enabled: true, Kinds: []string{"email"}, Action: "mask"},
+	}}
+
+	normalizer, err := NewNormalizer(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build normalizer: %v", err)
+	}
+	normalized, err := normalizer.Normalize(context.Background(), &models.ExtractedContent{Text: "Mail ann@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to normalize: %v", err)
+	}
+	if normalized.Text != "Mail a**@example.com" {
+		t.Errorf("Expected the email to be masked, got %q", normalized.Text)
+	}
+
+	cfg.Normalizer.PII.Action = "shred"
+	if _, err := NewNormalizer(cfg); err == nil {
+		t.Errorf("Expected an error for an unknown PII action")
+	}
+
+	// Hashing needs the secret from the environment
+	cfg.Normalizer.PII.Action = "hash"
+	cfg.Normalizer.PII.SecretEnvVar = "TEST_PII_HASH_SECRET"
+	t.Setenv("TEST_PII_HASH_SECRET", "")
+	if _, err := NewNormalizer(cfg); err == nil {
+		t.Errorf("Expected an error for hashing without a secret")
+	}
+	t.Setenv("TEST_PII_HASH_SECRET", "s3cret")
+	if _, err := NewNormalizer(cfg); err != nil {
+		t.Errorf("Failed to build normalizer with a secret: %v", err)
+	}
+}
+
+func TestNewChunker(t *testing.T) {
+	cfg := &config.Config{Chunking: config.ChunkingConfig{Strategy: ChunkerToken, MaxTokens: 3}}
+
+	chunker, err := NewChunker(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build chunker: %v", err)
+	}
+	chunks, err := chunker.Chunk(context.Background(), &models.NormalizedContent{Text: "one two three four five"})
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+	if len(chunks) != 2 || chunks[0].Text != "one two three" {
+		t.Errorf("Expected chunks of three words, got %d", len(chunks))
+	}
+
+	cfg.Chunking.Tokenizer = TokenizerBPE
+	if _, err := NewChunker(cfg); err == nil {
+		t.Errorf("Expected an error for a bpe tokenizer without a vocabulary")
+	}
+	cfg.Chunking.Tokenizer = "bogus"
+	if _, err := NewChunker(cfg); err == nil {
+		t.Errorf("Expected an error for an unknown tokenizer")
+	}
+}
+
+func TestNewSemanticChunker(t *testing.T) {
+	cfg := &config.Config{
+		Chunking:  config.ChunkingConfig{Strategy: ChunkerSemantic, MaxTokens: 50, MinTokens: 5},
+		Embedding: config.EmbeddingConfig{Provider: EmbedderHash, Dimensions: 64},
+	}
+
+	chunker, err := NewChunker(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build chunker: %v", err)
+	}
+	chunks, err := chunker.Chunk(context.Background(), &models.NormalizedContent{Text: "One sentence here. Another sentence there."})
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+	if len(chunks) == 0 {
+		t.Errorf("Expected chunks")
+	}
+
+	cfg.Embedding.Provider = "bogus"
+	if _, err := NewChunker(cfg); !errors.Is(err, ErrUnknownImplementation) {
+		t.Errorf("Expected ErrUnknownImplementation for an unknown embedder, got %v", err)
+	}
+}
+
+func TestNewHierarchicalChunker(t *testing.T) {
+	cfg := &config.Config{Chunking: config.ChunkingConfig{Strategy: ChunkerHierarchical, MaxTokens: 3, ParentTokens: 6}}
+
+	chunker, err := NewChunker(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build chunker: %v", err)
+	}
+	chunks, err := chunker.Chunk(context.Background(), &models.NormalizedContent{Text: "one two three four five six"})
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Errorf("Expected a parent and two children, got %d chunks", len(chunks))
+	}
+
+	cfg.Chunking.ParentTokens = 2
+	if _, err := NewChunker(cfg); err == nil {
+		t.Errorf("Expected an error for parents smaller than children")
+	}
+}
+
+func TestNewStorage(t *testing.T) {
+	for _, cfg := range []config.StorageConfig{{Type: StorageMemory}, {Type: StorageLocal, Path: t.TempDir()}} {
+		if _, err := NewStorage(&config.Config{Storage: cfg}); err != nil {
+			t.Errorf("Failed to build %s storage: %v", cfg.Type, err)
+		}
+	}
+	if _, err := NewStorage(&config.Config{Storage: config.StorageConfig{Type: StorageLocal}}); err == nil {
+		t.Errorf("Expected an error for local storage without a path")
+	}
+}
+
+func TestNewEmbedderContentIndex(t *testing.T) {
+	cfg := &config.Config{
+		Embedding: config.EmbeddingConfig{
+			Provider:         EmbedderHash,
+			ContentIndexPath: filepath.Join(t.TempDir(), "content.json"),
+		},
+		Storage: config.StorageConfig{Type: StorageMemory},
+	}
+	storage, err := NewStorage(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build storage: %v", err)
+	}
+	embedder, err := NewEmbedder(cfg, storage)
+	if err != nil {
+		t.Fatalf("Failed to build embedder: %v", err)
+	}
+
+	chunks := []*models.ContentChunk{{ID: "a", Text: "same text"}, {ID: "b", Text: "same text"}}
+	embeddings, err := embedder.Embed(context.Background(), chunks)
+	if err != nil {
+		t.Fatalf("Failed to embed: %v", err)
+	}
+	if len(embeddings) != 2 || embeddings[1].ID == embeddings[0].ID || embeddings[1].Chunk != chunks[1] || &embeddings[1].Vector[0] != &embeddings[0].Vector[0] {
+		t.Errorf("Expected the repeated text to reuse the first vector under its own ID, got %+v", embeddings)
+	}
+	if _, ok := chunks[1].Metadata[dedup.MetaURLs]; !ok {
+		t.Errorf("Expected the URLs of the text on the chunk, got %v", chunks[1].Metadata)
+	}
+
+	if _, err := NewEmbedder(cfg, nil); err == nil {
+		t.Error("Expected an error for a content index without a storage")
+	}
+}
+
+func TestNewTargetChunker(t *testing.T) {
+	cfg := &config.Config{Chunking: config.ChunkingConfig{Strategy: ChunkerToken, ContextHeader: "{{.Title}}"}}
+	content := &models.NormalizedContent{Original: &models.ExtractedContent{Title: "Post", URL: "https://example.com/a"}, Text: "some text"}
+
+	for _, tt := range []struct {
+		target config.ScraperConfig
+		want   string
+	}{
+		{config.ScraperConfig{Name: "default"}, "Post"},
+		{config.ScraperConfig{Name: "override", ContextHeader: "{{.Site}}: {{.Title}}"}, "example.com: Post"},
+	} {
+		chunker, err := NewTargetChunker(cfg, tt.target)
+		if err != nil {
+			t.Fatalf("Failed to build chunker: %v", err)
+		}
+		chunks, err := chunker.Chunk(context.Background(), content)
+		if err != nil {
+			t.Fatalf("Failed to chunk: %v", err)
+		}
+		if len(chunks) != 1 || chunks[0].Context != tt.want || chunks[0].Text != "some text" {
+			t.Errorf("%s: got %+v", tt.target.Name, chunks)
+		}
+	}
+
+	if _, err := NewTargetChunker(cfg, config.ScraperConfig{Name: "bad", ContextHeader: "{{.Nope}}"}); err == nil || !strings.Contains(err.Error(), "bad") {
+		t.Errorf("Expected an error naming the target, got %v", err)
+	}
+}
+
+func TestNewQualityChecker(t *testing.T) {
+	minLength, minScore := 10, 0.5
+	cfg := &config.Config{Quality: config.QualityConfig{Method: QualityHeuristic, MinContentLength: &minLength, MinQualityScore: &minScore}}
+
+	checker, err := NewQualityChecker(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build quality checker: %v", err)
+	}
+	accepted, errs := checker.Check(context.Background(), []*models.ContentChunk{{ID: "a", Text: "tiny"}})
+	if len(accepted) != 0 || len(errs) != 1 {
+		t.Errorf("Expected the short chunk to be rejected, got %v, %v", accepted, errs)
+	}
+
+	// Without a minimum length or score the package defaults apply
+	cfg.Quality.MinContentLength, cfg.Quality.MinQualityScore = nil, nil
+	checker, err = NewQualityChecker(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build quality checker: %v", err)
+	}
+	noise := strings.Repeat("$$ ## %% && ", 10)
+	if accepted, _ := checker.Check(context.Background(), []*models.ContentChunk{{ID: "b", Text: noise}}); len(accepted) != 0 {
+		t.Errorf("Expected the default minimum score to reject symbol noise")
+	}
+	short := "The post ends here, and that is all there is to it."
+	if accepted, _ := checker.Check(context.Background(), []*models.ContentChunk{{ID: "c", Text: short}}); len(accepted) != 0 {
+		t.Errorf("Expected the default minimum length to reject a short chunk")
+	}
+
+	// Zero disables both checks
+	minLength, minScore = 0, 0
+	cfg.Quality.MinContentLength, cfg.Quality.MinQualityScore = &minLength, &minScore
+	checker, err = NewQualityChecker(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build quality checker: %v", err)
+	}
+	chunks := []*models.ContentChunk{{ID: "b", Text: noise}, {ID: "c", Text: short}}
+	if accepted, errs := checker.Check(context.Background(), chunks); len(accepted) != 2 {
+		t.Errorf("Expected every chunk to pass with the checks disabled, got %v", errs)
+	}
+
+	minScore = 2
+	if _, err := NewQualityChecker(cfg); err == nil {
+		t.Errorf("Expected an error for a score above 1")
+	}
+}
+
+func TestNewQualityCheckerDuplicates(t *testing.T) {
+	cfg := &config.Config{Quality: config.QualityConfig{
+		Method:                   QualityHeuristic,
+		EnableDuplicateDetection: true,
+		DuplicateIndexPath:       filepath.Join(t.TempDir(), "index.gob"),
+	}}
+	text := "The same syndicated post is published on several blogs with only its byline changed at the top."
+
+	checker, err := NewQualityChecker(cfg)
+	if err != nil {
+		t.Fatalf("Failed to build quality checker: %v", err)
+	}
+	if dup, _, _ := checker.IsDuplicate(context.Background(), &models.ContentChunk{ID: "a", Text: text}); dup {
+		t.Error("The first copy is not a duplicate")
+	}
+	if err := checker.(io.Closer).Close(); err != nil {
+		t.Fatalf("Failed to save duplicate index: %v", err)
+	}
+
+	// A new checker finds copies of chunks seen by the previous one
+	checker, err = NewQualityChecker(cfg)
+	if err != nil {
+		t.Fatalf("Failed to reopen quality checker: %v", err)
+	}
+	if dup, _, _ := checker.IsDuplicate(context.Background(), &models.ContentChunk{ID: "b", Text: text}); !dup {
+		t.Error("Expected the copy to be a duplicate of the saved chunk")
+	}
+
+	cfg.Quality.DuplicateThreshold = 2
+	if _, err := NewQualityChecker(cfg); err == nil {
+		t.Errorf("Expected an error for a duplicate threshold above 1")
+	}
+}
@@ -0,0 +1,419 @@
+package registry
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/config"
+	simpleextractor "github.com/ncolesummers/scrape-pipeline/internal/extractor"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	httpscraper "github.com/ncolesummers/scrape-pipeline/internal/scraper"
+	"github.com/ncolesummers/scrape-pipeline/pkg/chunker"
+	"github.com/ncolesummers/scrape-pipeline/pkg/dedup"
+	"github.com/ncolesummers/scrape-pipeline/pkg/embedding"
+	"github.com/ncolesummers/scrape-pipeline/pkg/extractor"
+	"github.com/ncolesummers/scrape-pipeline/pkg/normalizer"
+	"github.com/ncolesummers/scrape-pipeline/pkg/pii"
+	"github.com/ncolesummers/scrape-pipeline/pkg/quality"
+	"github.com/ncolesummers/scrape-pipeline/pkg/scraper"
+	"github.com/ncolesummers/scrape-pipeline/pkg/storage"
+)
+
+// Names of the built-in implementations
+const (
+	ExtractorReadability  = "readability"
+	ExtractorCustom       = "custom"
+	ExtractorSiteSpecific = "site-specific"
+	ExtractorHeuristic    = "heuristic"
+	ExtractorChain        = "chain"
+
+	ScraperHTTP  = "http"
+	ScraperColly = "colly"
+
+	NormalizerStandard = "standard"
+
+	ChunkerToken        = "token"
+	ChunkerStructural   = "structural"
+	ChunkerSemantic     = "semantic"
+	ChunkerHierarchical = "hierarchical"
+
+	QualityHeuristic = "heuristic"
+
+	EmbedderHash = "hash"
+
+	StorageMemory = "memory"
+	StorageLocal  = "local"
+
+	TokenizerWhitespace = "whitespace"
+	TokenizerBPE        = "bpe"
+)
+
+// defaultScraperTimeout is the request timeout of scrapers built from config
+const defaultScraperTimeout = 30 * time.Second
+
+// defaultHashDimensions is the vector size of the hash embedder when the
+// configuration gives none
+const defaultHashDimensions = 384
+
+// Extractors builds models.Extractor implementations, selected by
+// extraction.extraction_method
+var Extractors = New[config.ExtractionConfig, models.Extractor]("extractor")
+
+// Scrapers builds models.Scraper implementations, selected by the type of
+// each scraper
+var Scrapers = New[config.ScraperConfig, models.Scraper]("scraper")
+
+// Normalizers builds models.Normalizer implementations, selected by
+// normalizer.method
+var Normalizers = New[config.NormalizerConfig, models.Normalizer]("normalizer")
+
+// Chunkers builds models.Chunker implementations, selected by
+// chunking.strategy
+var Chunkers = New[config.ChunkingConfig, models.Chunker]("chunker")
+
+// QualityCheckers builds models.QualityControl implementations, selected
+// by quality.method
+var QualityCheckers = New[config.QualityConfig, models.QualityControl]("quality checker")
+
+// Embedders builds models.Embedder implementations, selected by
+// embedding.provider
+var Embedders = New[config.EmbeddingConfig, models.Embedder]("embedder")
+
+// Storages builds models.VectorStorage implementations, selected by
+// storage.type
+var Storages = New[config.StorageConfig, models.VectorStorage]("storage")
+
+func init() {
+	mustRegister(Extractors, ExtractorReadability, func(cfg config.ExtractionConfig) (models.Extractor, error) {
+		extractorCfg, err := extractorConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return extractor.NewReadabilityExtractor(extractorCfg), nil
+	})
+	mustRegister(Extractors, ExtractorCustom, func(cfg config.ExtractionConfig) (models.Extractor, error) {
+		return simpleextractor.NewExtractor(cfg)
+	})
+	mustRegister(Extractors, ExtractorSiteSpecific, func(cfg config.ExtractionConfig) (models.Extractor, error) {
+		extractorCfg, err := extractorConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return extractor.NewSiteRuleExtractor(extractorCfg), nil
+	})
+	mustRegister(Extractors, ExtractorHeuristic, func(cfg config.ExtractionConfig) (models.Extractor, error) {
+		extractorCfg, err := extractorConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return extractor.NewHeuristicExtractor(extractorCfg), nil
+	})
+	mustRegister(Extractors, ExtractorChain, func(cfg config.ExtractionConfig) (models.Extractor, error) {
+		extractorCfg, err := extractorConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return extractor.NewChainExtractor(extractorCfg)
+	})
+
+	mustRegister(Scrapers, ScraperHTTP, func(cfg config.ScraperConfig) (models.Scraper, error) {
+		return httpscraper.NewScraper(cfg)
+	})
+	mustRegister(Scrapers, ScraperColly, func(cfg config.ScraperConfig) (models.Scraper, error) {
+		return scraper.NewCollyScraper(collyConfig(cfg))
+	})
+
+	mustRegister(Normalizers, NormalizerStandard, func(cfg config.NormalizerConfig) (models.Normalizer, error) {
+		return normalizer.New(normalizerConfig(cfg))
+	})
+
+	mustRegister(Chunkers, ChunkerToken, func(cfg config.ChunkingConfig) (models.Chunker, error) {
+		chunkerCfg, err := chunkerConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return chunker.NewTokenChunker(chunkerCfg)
+	})
+	mustRegister(Chunkers, ChunkerStructural, func(cfg config.ChunkingConfig) (models.Chunker, error) {
+		chunkerCfg, err := chunkerConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return chunker.NewStructuralChunker(chunkerCfg)
+	})
+	mustRegister(Chunkers, ChunkerSemantic, func(cfg config.ChunkingConfig) (models.Chunker, error) {
+		chunkerCfg, err := chunkerConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		chunkerCfg.Embedder, err = Embedders.Build(cfg.Embedding.Provider, cfg.Embedding)
+		if err != nil {
+			return nil, err
+		}
+		return chunker.NewSemanticChunker(chunkerCfg)
+	})
+	mustRegister(Chunkers, ChunkerHierarchical, func(cfg config.ChunkingConfig) (models.Chunker, error) {
+		chunkerCfg, err := chunkerConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return chunker.NewHierarchicalChunker(chunkerCfg)
+	})
+
+	mustRegister(QualityCheckers, QualityHeuristic, func(cfg config.QualityConfig) (models.QualityControl, error) {
+		qualityCfg, err := qualityConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return quality.New(qualityCfg)
+	})
+
+	mustRegister(Embedders, EmbedderHash, func(cfg config.EmbeddingConfig) (models.Embedder, error) {
+		dimensions := cfg.Dimensions
+		if dimensions == 0 {
+			dimensions = defaultHashDimensions
+		}
+		return embedding.NewHashEmbedder(dimensions)
+	})
+
+	mustRegister(Storages, StorageMemory, func(cfg config.StorageConfig) (models.VectorStorage, error) {
+		return storage.NewMemoryStorage(), nil
+	})
+	mustRegister(Storages, StorageLocal, func(cfg config.StorageConfig) (models.VectorStorage, error) {
+		return storage.OpenLocalStorage(cfg.Path)
+	})
+}
+
+// NewExtractor builds the extractor selected by the configuration
+func NewExtractor(cfg *config.Config) (models.Extractor, error) {
+	return Extractors.Build(cfg.Extraction.Method, cfg.Extraction)
+}
+
+// NewScraper builds the scraper selected by a scraper configuration
+func NewScraper(cfg config.ScraperConfig) (models.Scraper, error) {
+	return Scrapers.Build(cfg.Type, cfg)
+}
+
+// NewNormalizer builds the normalizer selected by the configuration
+func NewNormalizer(cfg *config.Config) (models.Normalizer, error) {
+	return Normalizers.Build(cfg.Normalizer.Method, cfg.Normalizer)
+}
+
+// NewChunker builds the chunker selected by the configuration
+func NewChunker(cfg *config.Config) (models.Chunker, error) {
+	chunkingCfg := cfg.Chunking
+	chunkingCfg.Embedding = cfg.Embedding
+	return Chunkers.Build(chunkingCfg.Strategy, chunkingCfg)
+}
+
+// NewTargetChunker builds the chunker selected by the configuration for
+// the pages of one scraper target, adding the context header configured
+// for the target or else the one of the chunking section
+func NewTargetChunker(cfg *config.Config, target config.ScraperConfig) (models.Chunker, error) {
+	c, err := NewChunker(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	text := cfg.Chunking.ContextHeader
+	if target.ContextHeader != "" {
+		text = target.ContextHeader
+	}
+	if text == "" {
+		return c, nil
+	}
+	header, err := chunker.NewHeaderTemplate(text)
+	if err != nil {
+		return nil, fmt.Errorf("scraper %q: %w", target.Name, err)
+	}
+	return chunker.WithContextHeaders(c, header), nil
+}
+
+// NewQualityChecker builds the quality checker selected by the
+// configuration
+func NewQualityChecker(cfg *config.Config) (models.QualityControl, error) {
+	return QualityCheckers.Build(cfg.Quality.Method, cfg.Quality)
+}
+
+// NewEmbedder builds the embedder selected by the configuration. With a
+// content index path it reuses the embeddings in storage of text that was
+// embedded before.
+func NewEmbedder(cfg *config.Config, storage models.VectorStorage) (models.Embedder, error) {
+	embedder, err := Embedders.Build(cfg.Embedding.Provider, cfg.Embedding)
+	if err != nil || cfg.Embedding.ContentIndexPath == "" {
+		return embedder, err
+	}
+	versioned, ok := embedder.(models.VersionedEmbedder)
+	if !ok {
+		return nil, fmt.Errorf("embedder %q does not report its model, which the content index requires", cfg.Embedding.Provider)
+	}
+	lookup, ok := storage.(models.LookupStorage)
+	if !ok {
+		return nil, fmt.Errorf("storage %q cannot look up embeddings by ID, which the content index requires", cfg.Storage.Type)
+	}
+	index, err := dedup.NewContentIndex(cfg.Embedding.ContentIndexPath)
+	if err != nil {
+		return nil, err
+	}
+	return dedup.NewContentEmbedder(versioned, index, lookup)
+}
+
+// NewStorage builds the vector storage selected by the configuration
+func NewStorage(cfg *config.Config) (models.VectorStorage, error) {
+	return Storages.Build(cfg.Storage.Type, cfg.Storage)
+}
+
+// extractorConfig converts the extraction section of the configuration and
+// loads the template store it names
+func extractorConfig(cfg config.ExtractionConfig) (extractor.Config, error) {
+	rules := make(map[string]extractor.SiteRule, len(cfg.SiteSpecificRules))
+	for host, rule := range cfg.SiteSpecificRules {
+		rules[host] = extractor.SiteRule{
+			ArticleSelector: rule.ArticleSelector,
+			TitleSelector:   rule.TitleSelector,
+			AuthorSelector:  rule.AuthorSelector,
+			DateSelector:    rule.DateSelector,
+		}
+	}
+
+	extractorCfg := extractor.Config{
+		SiteSpecificRules: rules,
+		FallbackChain:     cfg.FallbackChain,
+		MaxKeyphrases:     cfg.MaxKeyphrases,
+		ExtractImages:     cfg.ExtractImages,
+		ExtractMetadata:   cfg.ExtractMetadata,
+		ExtractMarkdown:   cfg.ExtractMarkdown,
+		KeepComments:      cfg.KeepComments,
+	}
+	if cfg.TemplatePath != "" {
+		templates, err := extractor.LoadTemplateStore(cfg.TemplatePath, extractor.TemplateConfig{})
+		if err != nil {
+			return extractor.Config{}, err
+		}
+		extractorCfg.Templates = templates
+	}
+	return extractorCfg, nil
+}
+
+// collyConfig converts a scraper configuration for the Colly scraper,
+// restricting it to the host of the configured URL
+func collyConfig(cfg config.ScraperConfig) scraper.Config {
+	var domains []string
+	if u, err := url.Parse(cfg.URL); err == nil && u.Hostname() != "" {
+		domains = []string{u.Hostname()}
+	}
+
+	rateLimit := float64(cfg.RateLimit)
+	if rateLimit <= 0 {
+		rateLimit = 1
+	}
+
+	return scraper.Config{
+		UserAgent:          cfg.UserAgent,
+		AllowedDomains:     domains,
+		MaxConcurrency:     cfg.Concurrency,
+		RateLimitPerDomain: rateLimit,
+		TimeoutSeconds:     int(defaultScraperTimeout / time.Second),
+		RespectRobotsTxt:   cfg.RespectRobotsTxt,
+	}
+}
+
+// normalizerConfig converts the normalizer section of the configuration
+func normalizerConfig(cfg config.NormalizerConfig) normalizer.Config {
+	normalizerCfg := normalizer.Config{
+		UnicodeForm:      cfg.UnicodeForm,
+		NormalizeUnicode: cfg.NormalizeUnicode,
+		TrimWhitespace:   cfg.TrimWhitespace,
+		UnifyPunctuation: cfg.UnifyPunctuation,
+		DecodeEntities:   cfg.DecodeEntities,
+		StripInvisible:   cfg.StripInvisible,
+		PreserveLists:    cfg.PreserveLists,
+		PreserveHeadings: cfg.PreserveHeadings,
+		LanguageRules:    cfg.LanguageRules,
+	}
+
+	if cfg.PII.Enabled {
+		piiCfg := &pii.Config{Action: pii.Action(cfg.PII.Action)}
+		if cfg.PII.SecretEnvVar != "" {
+			piiCfg.Secret = []byte(os.Getenv(cfg.PII.SecretEnvVar))
+		}
+		for _, kind := range cfg.PII.Kinds {
+			piiCfg.Kinds = append(piiCfg.Kinds, pii.Kind(kind))
+		}
+		if len(cfg.PII.Actions) > 0 {
+			piiCfg.Actions = make(map[pii.Kind]pii.Action, len(cfg.PII.Actions))
+			for kind, action := range cfg.PII.Actions {
+				piiCfg.Actions[pii.Kind(kind)] = pii.Action(action)
+			}
+		}
+		normalizerCfg.PII = piiCfg
+	}
+	return normalizerCfg
+}
+
+// qualityConfig converts the quality section of the configuration and
+// opens the duplicate index when duplicate detection is enabled. An unset
+// minimum length or score, and a zero duplicate threshold or SimHash
+// distance, takes the default.
+func qualityConfig(cfg config.QualityConfig) (quality.Config, error) {
+	qualityCfg := quality.DefaultConfig()
+	if cfg.MinContentLength != nil {
+		qualityCfg.MinContentLength = *cfg.MinContentLength
+	}
+	if cfg.MinQualityScore != nil {
+		qualityCfg.MinScore = *cfg.MinQualityScore
+	}
+
+	if cfg.EnableDuplicateDetection {
+		dedupCfg := dedup.DefaultConfig()
+		if cfg.DuplicateThreshold != 0 {
+			dedupCfg.Threshold = cfg.DuplicateThreshold
+		}
+		if cfg.DuplicateSimHashDistance != 0 {
+			dedupCfg.SimHashDistance = cfg.DuplicateSimHashDistance
+		}
+		dedupCfg.Path = cfg.DuplicateIndexPath
+		index, err := dedup.NewIndex(dedupCfg)
+		if err != nil {
+			return quality.Config{}, err
+		}
+		qualityCfg.Duplicates = index
+	}
+	return qualityCfg, nil
+}
+
+// chunkerConfig converts the chunking section of the configuration and
+// loads the tokenizer it names. A zero token limit takes the default.
+func chunkerConfig(cfg config.ChunkingConfig) (chunker.Config, error) {
+	chunkerCfg := chunker.DefaultConfig()
+	if cfg.MaxTokens != 0 {
+		chunkerCfg.MaxTokens = cfg.MaxTokens
+		chunkerCfg.Overlap = cfg.Overlap
+	}
+	chunkerCfg.TargetTokens = cfg.TargetTokens
+	chunkerCfg.RespectHeadings = cfg.RespectHeadings
+	chunkerCfg.RespectParagraphs = cfg.RespectParagraphs
+	chunkerCfg.MinTokens = cfg.MinTokens
+	chunkerCfg.BreakpointPercentile = cfg.BreakpointPercentile
+	chunkerCfg.WindowSentences = cfg.WindowSentences
+	chunkerCfg.ParentTokens = cfg.ParentTokens
+
+	switch cfg.Tokenizer {
+	case "", TokenizerWhitespace:
+		chunkerCfg.Tokenizer = chunker.WhitespaceTokenizer{}
+	case TokenizerBPE:
+		if cfg.VocabPath == "" {
+			return chunkerCfg, fmt.Errorf("the bpe tokenizer requires chunking.vocab_path")
+		}
+		tokenizer, err := chunker.LoadBPETokenizer(cfg.VocabPath)
+		if err != nil {
+			return chunkerCfg, err
+		}
+		chunkerCfg.Tokenizer = tokenizer
+	default:
+		return chunkerCfg, fmt.Errorf("unknown tokenizer %q", cfg.Tokenizer)
+	}
+	return chunkerCfg, nil
+}
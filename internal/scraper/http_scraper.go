@@ -4,46 +4,37 @@
 package scraper
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/ncolesummers/scrape-pipeline/internal/config"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
 )
 
-// ScrapeResult represents the result of a web scrape
-type ScrapeResult struct {
-	FetchedAt time.Time
-	Headers   map[string]string
-	URL       string
-	HTML      string
-	Status    int
-}
-
-// Scraper is the interface for web scrapers
-type Scraper interface {
-	// Name returns the name of the scraper
-	Name() string
-	// Scrape fetches the content of a URL
-	Scrape(url string) (*ScrapeResult, error)
-}
+// ErrDisallowed is returned for URLs excluded by robots.txt
+var ErrDisallowed = errors.New("URL is disallowed by robots.txt")
 
-// HTTPScraper implements the Scraper interface using standard HTTP
+// HTTPScraper implements the models.Scraper interface using standard HTTP
 type HTTPScraper struct {
 	client          *http.Client
 	name            string
 	baseURL         string
 	userAgent       string
 	disallowedPaths []string
+	queue           []string
 	rateLimit       float64
 	concurrency     int
 	respectRobots   bool
+	mutex           sync.Mutex
 }
 
 // NewScraper creates a new scraper instance based on the provided configuration
-func NewScraper(cfg config.ScraperConfig) (Scraper, error) {
+func NewScraper(cfg config.ScraperConfig) (*HTTPScraper, error) {
 	// Create an HTTP client with reasonable timeout defaults
 	client := &http.Client{
 		Timeout: 30 * time.Second,
@@ -58,6 +49,9 @@ func NewScraper(cfg config.ScraperConfig) (Scraper, error) {
 		concurrency:   cfg.Concurrency,
 		respectRobots: cfg.RespectRobotsTxt,
 	}
+	if scraper.concurrency <= 0 {
+		scraper.concurrency = 1
+	}
 
 	// If we respect robots.txt, fetch and parse it
 	if cfg.RespectRobotsTxt {
@@ -74,20 +68,123 @@ func (s *HTTPScraper) Name() string {
 	return s.name
 }
 
-// Scrape fetches the content of a URL
-func (s *HTTPScraper) Scrape(url string) (*ScrapeResult, error) {
+// AddURLs adds URLs to the scraping queue. Queued URLs are fetched by the
+// next call to Scrape.
+func (s *HTTPScraper) AddURLs(urls []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, url := range urls {
+		if url == "" {
+			return errors.New("cannot queue an empty URL")
+		}
+	}
+	s.queue = append(s.queue, urls...)
+	return nil
+}
+
+// SetRateLimit sets the rate limit for scraping per domain
+func (s *HTTPScraper) SetRateLimit(requestsPerSecond float64) error {
+	if requestsPerSecond <= 0 {
+		return fmt.Errorf("rate limit must be positive, got %v", requestsPerSecond)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.rateLimit = requestsPerSecond
+	return nil
+}
+
+// Scrape fetches the queued URLs followed by urls and returns the raw
+// content. Pages are fetched by up to the configured number of workers and
+// no faster than the rate limit. Both channels are closed once every URL has
+// been handled or ctx is canceled, and callers must drain both.
+func (s *HTTPScraper) Scrape(ctx context.Context, urls []string) (<-chan *models.RawContent, <-chan error) {
+	contentChan := make(chan *models.RawContent)
+	errorChan := make(chan error)
+
+	s.mutex.Lock()
+	pending := append(s.queue, urls...)
+	s.queue = nil
+	interval := time.Duration(0)
+	if s.rateLimit > 0 {
+		interval = time.Duration(float64(time.Second) / s.rateLimit)
+	}
+	s.mutex.Unlock()
+
+	go func() {
+		defer close(contentChan)
+		defer close(errorChan)
+
+		jobs := make(chan string)
+		var wg sync.WaitGroup
+		for i := 0; i < s.concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for url := range jobs {
+					content, err := s.Fetch(ctx, url)
+					if err != nil {
+						select {
+						case errorChan <- err:
+						case <-ctx.Done():
+							return
+						}
+						continue
+					}
+					select {
+					case contentChan <- content:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+		}
+
+		var ticker *time.Ticker
+		if interval > 0 {
+			ticker = time.NewTicker(interval)
+			defer ticker.Stop()
+		}
+
+	dispatch:
+		for i, url := range pending {
+			// The first request goes out immediately, later ones wait for the limiter
+			if ticker != nil && i > 0 {
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					break dispatch
+				}
+			}
+			select {
+			case jobs <- url:
+			case <-ctx.Done():
+				break dispatch
+			}
+		}
+		close(jobs)
+		wg.Wait()
+	}()
+
+	return contentChan, errorChan
+}
+
+// Fetch fetches the content of a single URL
+func (s *HTTPScraper) Fetch(ctx context.Context, url string) (*models.RawContent, error) {
 	// Simple robots.txt check - in a real implementation this would use a proper parser
 	if s.respectRobots {
 		for _, path := range s.disallowedPaths {
 			if len(url) >= len(s.baseURL)+len(path) &&
 				url[len(s.baseURL):len(s.baseURL)+len(path)] == path {
-				return nil, errors.New("URL is disallowed by robots.txt")
+				return nil, fmt.Errorf("%w: %s", ErrDisallowed, url)
 			}
 		}
 	}
 
 	// Create a new request
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -124,12 +221,13 @@ func (s *HTTPScraper) Scrape(url string) (*ScrapeResult, error) {
 	}
 
 	// Create and return the result
-	result := &ScrapeResult{
-		URL:       url,
-		HTML:      htmlContent,
-		Headers:   headers,
-		Status:    resp.StatusCode,
-		FetchedAt: time.Now(),
+	result := &models.RawContent{
+		URL:         url,
+		HTML:        htmlContent,
+		Headers:     headers,
+		ContentType: resp.Header.Get("Content-Type"),
+		StatusCode:  resp.StatusCode,
+		Timestamp:   time.Now().Unix(),
 	}
 
 	return result, nil
@@ -1,14 +1,20 @@
 package scraper
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/ncolesummers/scrape-pipeline/internal/config"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
 )
 
+// The HTTP scraper must be usable wherever the pipeline expects a scraper
+var _ models.Scraper = (*HTTPScraper)(nil)
+
 // TestNewScraper tests the creation of a new scraper
 // This test uses the simple HTTP scraper implementation in http_scraper.go
 func TestNewScraper(t *testing.T) {
@@ -70,7 +76,7 @@ func TestScrape(t *testing.T) {
 	}
 
 	// Scrape the test URL
-	result, err := scraper.Scrape(server.URL)
+	result, err := scraper.Fetch(context.Background(), server.URL)
 	if err != nil {
 		t.Fatalf("Failed to scrape URL: %v", err)
 	}
@@ -91,6 +97,10 @@ func TestScrape(t *testing.T) {
 	if !strings.Contains(result.HTML, "This is a test paragraph") {
 		t.Errorf("Expected HTML to contain 'This is a test paragraph'")
 	}
+
+	if result.StatusCode != http.StatusOK || result.ContentType != "text/html" {
+		t.Errorf("Expected status 200 and content type text/html, got %d and %q", result.StatusCode, result.ContentType)
+	}
 }
 
 // TestRespectRobotsTxt tests robots.txt functionality
@@ -127,7 +137,7 @@ Disallow: /private/
 	}
 
 	// Test scraping an allowed URL
-	allowed, err := scraper.Scrape(robotsServer.URL + "/allowed")
+	allowed, err := scraper.Fetch(context.Background(), robotsServer.URL+"/allowed")
 	if err != nil {
 		t.Fatalf("Failed to scrape allowed URL: %v", err)
 	}
@@ -136,11 +146,88 @@ Disallow: /private/
 	}
 
 	// Test scraping a disallowed URL
-	disallowed, err := scraper.Scrape(robotsServer.URL + "/private/disallowed")
-	if err == nil {
-		t.Errorf("Expected error when scraping disallowed URL, got none")
+	disallowed, err := scraper.Fetch(context.Background(), robotsServer.URL+"/private/disallowed")
+	if !errors.Is(err, ErrDisallowed) {
+		t.Errorf("Expected ErrDisallowed when scraping disallowed URL, got %v", err)
 	}
 	if disallowed != nil {
 		t.Errorf("Expected nil result for disallowed URL, got content: %s", disallowed.HTML)
 	}
 }
+
+// TestScrapeChannels tests fetching queued and passed URLs through the
+// models.Scraper interface
+func TestScrapeChannels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("<p>" + r.URL.Path + "</p>"))
+	}))
+	defer server.Close()
+
+	scraper, err := NewScraper(config.ScraperConfig{
+		Name:        "channel-test",
+		URL:         server.URL,
+		RateLimit:   100,
+		Concurrency: 2,
+		UserAgent:   "Test Bot",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create scraper: %v", err)
+	}
+
+	if err := scraper.AddURLs([]string{server.URL + "/queued"}); err != nil {
+		t.Fatalf("Failed to queue URL: %v", err)
+	}
+	if err := scraper.SetRateLimit(0); err == nil {
+		t.Errorf("Expected an error for a zero rate limit")
+	}
+
+	contents, errs := scraper.Scrape(context.Background(), []string{server.URL + "/a", server.URL + "/missing"})
+
+	pages := make(map[string]bool)
+	var failures []error
+	for contents != nil || errs != nil {
+		select {
+		case content, ok := <-contents:
+			if !ok {
+				contents = nil
+				continue
+			}
+			pages[content.HTML] = true
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			failures = append(failures, err)
+		}
+	}
+
+	if !pages["<p>/queued</p>"] || !pages["<p>/a</p>"] {
+		t.Errorf("Expected the queued and passed URLs to be fetched, got %v", pages)
+	}
+	if len(failures) != 1 {
+		t.Errorf("Expected one error for the empty response, got %v", failures)
+	}
+}
+
+// TestScrapeCanceled tests that Scrape stops when its context is canceled
+func TestScrapeCanceled(t *testing.T) {
+	scraper, err := NewScraper(config.ScraperConfig{Name: "cancel-test", URL: "http://127.0.0.1:1", RateLimit: 1, Concurrency: 1})
+	if err != nil {
+		t.Fatalf("Failed to create scraper: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	contents, errs := scraper.Scrape(ctx, []string{"http://127.0.0.1:1/a", "http://127.0.0.1:1/b"})
+	for range contents {
+		t.Errorf("Expected no content after cancellation")
+	}
+	for range errs {
+	}
+}
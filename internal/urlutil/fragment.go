@@ -0,0 +1,39 @@
+package urlutil
+
+import (
+	"net/url"
+	"strings"
+)
+
+// fragmentContextWords is the number of words quoted at each end of a long
+// text fragment
+const fragmentContextWords = 5
+
+// TextFragment returns pageURL with a text fragment ("#:~:text=") that
+// browsers scroll to and highlight. Quotes longer than twice
+// fragmentContextWords words are abbreviated to a start and end range.
+// Any existing fragment is replaced; an empty quote returns pageURL
+// without its fragment.
+func TextFragment(pageURL, quote string) string {
+	base, _, _ := strings.Cut(pageURL, "#")
+	words := strings.Fields(quote)
+	if len(words) == 0 {
+		return base
+	}
+
+	directive := encodeFragmentText(strings.Join(words, " "))
+	if len(words) > 2*fragmentContextWords {
+		start := strings.Join(words[:fragmentContextWords], " ")
+		end := strings.Join(words[len(words)-fragmentContextWords:], " ")
+		directive = encodeFragmentText(start) + "," + encodeFragmentText(end)
+	}
+	return base + "#:~:text=" + directive
+}
+
+// encodeFragmentText percent-encodes text for a text directive, which
+// additionally reserves "-", "," and "&"
+func encodeFragmentText(text string) string {
+	encoded := url.QueryEscape(text)
+	encoded = strings.ReplaceAll(encoded, "+", "%20")
+	return strings.ReplaceAll(encoded, "-", "%2D")
+}
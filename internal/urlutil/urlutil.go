@@ -0,0 +1,108 @@
+// Package urlutil canonicalizes URLs so the same page reached through
+// different links is recorded under one key.
+package urlutil
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// trackingParams are query parameters that identify a campaign or click
+// rather than a resource
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "msclkid": true, "dclid": true,
+	"mc_cid": true, "mc_eid": true, "_ga": true, "_hsenc": true, "_hsmi": true,
+	"igshid": true, "yclid": true,
+}
+
+// Normalize returns the canonical form of an absolute http or https URL.
+// The scheme and host are lowercased, default ports, user info and
+// fragments dropped, dot segments resolved, tracking parameters such as
+// utm_source removed and the remaining query parameters sorted.
+func Normalize(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+	return normalizeURL(u)
+}
+
+// Resolve resolves ref against base and normalizes the result. It returns
+// false for references that are not http or https URLs, such as mailto:
+// links.
+func Resolve(base *url.URL, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", false
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	normalized, err := normalizeURL(u)
+	if err != nil {
+		return "", false
+	}
+	return normalized, true
+}
+
+// SameSite reports whether two hosts belong to the same site. A leading
+// "www." is ignored.
+func SameSite(a, b string) bool {
+	return Host(a) == Host(b)
+}
+
+// Host returns the lowercased host name without port and leading "www."
+func Host(host string) string {
+	host = strings.ToLower(host)
+	if h, _, ok := strings.Cut(host, ":"); ok && !strings.Contains(host, "]") {
+		host = h
+	}
+	return strings.TrimPrefix(host, "www.")
+}
+
+func normalizeURL(u *url.URL) (string, error) {
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", errors.New("not an http URL")
+	}
+	if u.Host == "" {
+		return "", errors.New("URL has no host")
+	}
+
+	host := strings.ToLower(u.Host)
+	if (scheme == "http" && strings.HasSuffix(host, ":80")) ||
+		(scheme == "https" && strings.HasSuffix(host, ":443")) {
+		host = host[:strings.LastIndex(host, ":")]
+	}
+	host = strings.TrimSuffix(host, ".")
+
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
+	} else {
+		trailing := strings.HasSuffix(p, "/")
+		p = path.Clean(p)
+		if trailing && p != "/" {
+			p += "/"
+		}
+	}
+
+	query := u.Query()
+	for key := range query {
+		if trackingParams[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+
+	canonical := scheme + "://" + host + p
+	if encoded := query.Encode(); encoded != "" {
+		canonical += "?" + encoded
+	}
+	return canonical, nil
+}
@@ -0,0 +1,73 @@
+package urlutil
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"HTTPS://Example.COM", "https://example.com/"},
+		{"https://example.com:443/a/./b/../c/", "https://example.com/a/c/"},
+		{"http://example.com:80/a#section", "http://example.com/a"},
+		{"https://user:pw@example.com/a", "https://example.com/a"},
+		{"https://example.com/a?utm_source=feed&b=2&a=1&fbclid=x", "https://example.com/a?a=1&b=2"},
+		{"https://example.com:8443/a", "https://example.com:8443/a"},
+		{"https://example.com/caf%C3%A9", "https://example.com/caf%C3%A9"},
+	}
+
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if err != nil {
+			t.Errorf("Normalize(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"mailto:a@example.com", "/relative/path", "javascript:void(0)"} {
+		if _, err := Normalize(bad); err == nil {
+			t.Errorf("Normalize(%q) should fail", bad)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	base, _ := url.Parse("https://www.example.com/posts/gc-tuning")
+
+	if got, ok := Resolve(base, "../about?utm_medium=web"); !ok || got != "https://www.example.com/about" {
+		t.Errorf("Resolve() = %q, %v", got, ok)
+	}
+	if _, ok := Resolve(base, "mailto:jane@example.com"); ok {
+		t.Errorf("Resolve() should reject mailto links")
+	}
+	if !SameSite("www.example.com", "EXAMPLE.com:443") || SameSite("example.com", "blog.example.com") {
+		t.Errorf("SameSite() returned unexpected results")
+	}
+}
+
+func TestTextFragment(t *testing.T) {
+	tests := []struct {
+		url, quote, want string
+	}{
+		{"https://example.com/a", "Hello, world", "https://example.com/a#:~:text=Hello%2C%20world"},
+		{"https://example.com/a#top", "well-known  &\nsafe", "https://example.com/a#:~:text=well%2Dknown%20%26%20safe"},
+		{"https://example.com/a#top", "  ", "https://example.com/a"},
+		{
+			"https://example.com/a",
+			"one two three four five six seven eight nine ten eleven",
+			"https://example.com/a#:~:text=one%20two%20three%20four%20five,seven%20eight%20nine%20ten%20eleven",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := TextFragment(tt.url, tt.quote); got != tt.want {
+			t.Errorf("TextFragment(%q, %q) = %q, want %q", tt.url, tt.quote, got, tt.want)
+		}
+	}
+}
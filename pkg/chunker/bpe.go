@@ -0,0 +1,218 @@
+package chunker
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BPETokenizer is a byte-level byte pair encoding tokenizer compatible with
+// the cl100k_base encoding. It runs offline from a vocabulary file in the
+// tiktoken format, one base64-encoded token and its rank per line.
+type BPETokenizer struct {
+	ranks map[string]int
+}
+
+// LoadBPETokenizer reads a tiktoken vocabulary file such as
+// cl100k_base.tiktoken
+func LoadBPETokenizer(path string) (*BPETokenizer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open BPE vocabulary: %w", err)
+	}
+	defer f.Close()
+
+	tokenizer, err := NewBPETokenizer(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return tokenizer, nil
+}
+
+// NewBPETokenizer reads a vocabulary in the tiktoken format from r
+func NewBPETokenizer(r io.Reader) (*BPETokenizer, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		encoded, rankText, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a token and a rank", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid token: %w", line, err)
+		}
+		rank, err := strconv.Atoi(rankText)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rank: %w", line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read BPE vocabulary: %w", err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("empty BPE vocabulary")
+	}
+	return &BPETokenizer{ranks: ranks}, nil
+}
+
+// Tokenize splits text into words the way cl100k_base does and encodes
+// each word with byte pair merges. Tokens may split multi-byte characters.
+func (t *BPETokenizer) Tokenize(text string) []Token {
+	var tokens []Token
+	for _, word := range pretokenize(text) {
+		piece := text[word.Start:word.End]
+		if _, ok := t.ranks[piece]; ok {
+			tokens = append(tokens, word)
+			continue
+		}
+		bounds := t.merge(piece)
+		for i := 0; i+1 < len(bounds); i++ {
+			tokens = append(tokens, Token{Start: word.Start + bounds[i], End: word.Start + bounds[i+1]})
+		}
+	}
+	return tokens
+}
+
+// Count returns the number of tokens in text
+func (t *BPETokenizer) Count(text string) int {
+	return len(t.Tokenize(text))
+}
+
+// merge starts from the single bytes of piece and repeatedly merges the
+// adjacent pair whose concatenation has the lowest rank. It returns the
+// boundaries of the resulting tokens.
+func (t *BPETokenizer) merge(piece string) []int {
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+
+	for len(bounds) > 2 {
+		best, bestRank := -1, 0
+		for i := 0; i+2 < len(bounds); i++ {
+			rank, ok := t.ranks[piece[bounds[i]:bounds[i+2]]]
+			if ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+	return bounds
+}
+
+// pretokenize splits text into the words BPE merges are confined to. It
+// follows the cl100k_base pattern:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// which Go's regexp package cannot express because of the lookahead.
+func pretokenize(text string) []Token {
+	var words []Token
+	for i := 0; i < len(text); {
+		end := matchWord(text, i)
+		words = append(words, Token{Start: i, End: end})
+		i = end
+	}
+	return words
+}
+
+// contractions are the suffixes cl100k_base keeps as separate words
+var contractions = []string{"s", "t", "re", "ve", "m", "ll", "d"}
+
+// matchWord returns the end of the word starting at i
+func matchWord(text string, i int) int {
+	r, size := utf8.DecodeRuneInString(text[i:])
+
+	// Contractions
+	if r == '\'' {
+		for _, suffix := range contractions {
+			if end := i + 1 + len(suffix); end <= len(text) && strings.EqualFold(text[i+1:end], suffix) {
+				return end
+			}
+		}
+	}
+
+	// Letters, optionally preceded by one other character
+	j := i
+	if !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\r' && r != '\n' {
+		j += size
+	}
+	if end := skip(text, j, unicode.IsLetter, -1); end > j {
+		return end
+	}
+
+	// Up to three digits
+	if unicode.IsNumber(r) {
+		return skip(text, i, unicode.IsNumber, 3)
+	}
+
+	// Punctuation, optionally preceded by a space and followed by newlines
+	j = i
+	if r == ' ' {
+		j++
+	}
+	if end := skip(text, j, isSymbol, -1); end > j {
+		return skip(text, end, isNewline, -1)
+	}
+
+	// Whitespace
+	end := skip(text, i, unicode.IsSpace, -1)
+	if end == i {
+		return i + size
+	}
+	// Whitespace up to the last newline of the run
+	for k := end; k > i; {
+		last, lastSize := utf8.DecodeLastRuneInString(text[i:k])
+		if isNewline(last) {
+			return k
+		}
+		k -= lastSize
+	}
+	// Whitespace not followed by a word, leaving the last space to the word
+	if end == len(text) {
+		return end
+	}
+	if _, lastSize := utf8.DecodeLastRuneInString(text[i:end]); end-lastSize > i {
+		return end - lastSize
+	}
+	return end
+}
+
+// skip returns the offset after the runes from i that satisfy match, up to
+// limit runes when limit is positive
+func skip(text string, i int, match func(rune) bool, limit int) int {
+	for n := 0; i < len(text) && n != limit; n++ {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !match(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+func isSymbol(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
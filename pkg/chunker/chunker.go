@@ -0,0 +1,234 @@
+// Package chunker splits normalized content into chunks sized for
+// embedding. Chunk sizes are measured in tokens of a pluggable Tokenizer.
+// Every chunk records its byte range in the normalized text, its source
+// page and metadata such as its token count, and gets a content-addressed
+// ID.
+package chunker
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// Metadata keys set on every chunk
+const (
+	MetaDocumentID = "document_id"
+	MetaStrategy   = "strategy"
+	MetaIndex      = "chunk_index"
+	MetaTokens     = "token_count"
+	MetaTitle      = "title"
+	// MetaLanguage holds the language of the page, when known
+	MetaLanguage = "language"
+	// MetaTags holds the topic tags of the page as a []string, when any,
+	// for filtering retrieval by topic
+	MetaTags = "tags"
+	// MetaSectionPath holds the headings enclosing the chunk as a []string,
+	// outermost first
+	MetaSectionPath = "section_path"
+	// MetaRedactions holds the models.Redaction values inside the chunk
+	MetaRedactions = "redactions"
+)
+
+// Metadata keys set by the hierarchical chunker
+const (
+	// MetaLevel is LevelParent or LevelChild
+	MetaLevel = "chunk_level"
+	// MetaParentID holds the ID of the parent of a child chunk
+	MetaParentID = "parent_id"
+	// MetaChildIDs holds the IDs of the children of a parent chunk as a
+	// []string, in order
+	MetaChildIDs = "child_ids"
+)
+
+// Values of MetaLevel
+const (
+	LevelParent = "parent"
+	LevelChild  = "child"
+)
+
+func init() {
+	models.RegisterMetadataType[int](MetaIndex)
+	models.RegisterMetadataType[int](MetaTokens)
+	models.RegisterMetadataType[[]string](MetaTags)
+	models.RegisterMetadataType[[]string](MetaSectionPath)
+	models.RegisterMetadataType[[]models.Redaction](MetaRedactions)
+	models.RegisterMetadataType[[]string](MetaChildIDs)
+}
+
+// Config holds configuration for the chunkers
+type Config struct {
+	// MaxTokens is the hard limit on the tokens of a chunk
+	MaxTokens int
+	// Overlap is the number of tokens repeated from the end of one chunk at
+	// the start of the next
+	Overlap int
+	// Tokenizer measures chunk sizes. It defaults to WhitespaceTokenizer.
+	Tokenizer Tokenizer
+	// TargetTokens is the size up to which the structural chunker merges
+	// small pieces. It defaults to MaxTokens and cannot exceed it.
+	TargetTokens int
+	// RespectHeadings makes the structural chunker split at headings first
+	RespectHeadings bool
+	// RespectParagraphs makes the structural chunker split at paragraphs
+	// before sentences
+	RespectParagraphs bool
+	// MinTokens is the size below which the semantic chunker does not cut
+	// at a breakpoint. It cannot exceed MaxTokens.
+	MinTokens int
+	// BreakpointPercentile selects which distances between neighboring
+	// sentence windows count as breakpoints for the semantic chunker: those
+	// at or above this percentile of all distances in the document. It
+	// defaults to 95.
+	BreakpointPercentile float64
+	// WindowSentences is the number of sentences on each side of a
+	// candidate breakpoint compared by the semantic chunker. It defaults
+	// to 2.
+	WindowSentences int
+	// Embedder embeds the sentences for the semantic chunker
+	Embedder models.Embedder
+	// ParentTokens is the size up to which the hierarchical chunker merges
+	// text into parent chunks. It defaults to four times MaxTokens and
+	// cannot be less than MaxTokens.
+	ParentTokens int
+}
+
+// DefaultConfig returns the configuration used when none is given
+func DefaultConfig() Config {
+	return Config{
+		MaxTokens:            512,
+		Overlap:              64,
+		Tokenizer:            WhitespaceTokenizer{},
+		TargetTokens:         512,
+		RespectHeadings:      true,
+		RespectParagraphs:    true,
+		MinTokens:            64,
+		BreakpointPercentile: 95,
+		WindowSentences:      2,
+		ParentTokens:         2048,
+	}
+}
+
+func (c Config) validate() (Config, error) {
+	if c.MaxTokens <= 0 {
+		return c, fmt.Errorf("max tokens must be positive, got %d", c.MaxTokens)
+	}
+	if c.Overlap < 0 || c.Overlap >= c.MaxTokens {
+		return c, fmt.Errorf("overlap must be between 0 and max tokens (%d), got %d", c.MaxTokens, c.Overlap)
+	}
+	if c.Tokenizer == nil {
+		c.Tokenizer = WhitespaceTokenizer{}
+	}
+	if c.TargetTokens <= 0 || c.TargetTokens > c.MaxTokens {
+		c.TargetTokens = c.MaxTokens
+	}
+	if c.MinTokens < 0 || c.MinTokens > c.MaxTokens {
+		return c, fmt.Errorf("min tokens must be between 0 and max tokens (%d), got %d", c.MaxTokens, c.MinTokens)
+	}
+	if c.BreakpointPercentile < 0 || c.BreakpointPercentile > 100 {
+		return c, fmt.Errorf("breakpoint percentile must be between 0 and 100, got %g", c.BreakpointPercentile)
+	}
+	if c.BreakpointPercentile == 0 {
+		c.BreakpointPercentile = 95
+	}
+	if c.WindowSentences <= 0 {
+		c.WindowSentences = 2
+	}
+	if c.ParentTokens == 0 {
+		c.ParentTokens = 4 * c.MaxTokens
+	}
+	if c.ParentTokens < c.MaxTokens {
+		return c, fmt.Errorf("parent tokens must be at least max tokens (%d), got %d", c.MaxTokens, c.ParentTokens)
+	}
+	return c, nil
+}
+
+// newChunk returns the chunk of content between start and end, trimmed of
+// surrounding whitespace, or nil when the range holds only whitespace
+func newChunk(content *models.NormalizedContent, start, end int, strategy string) *models.ContentChunk {
+	start, end = trimRange(content.Text, start, end)
+	if start >= end {
+		return nil
+	}
+
+	chunk := &models.ContentChunk{
+		Text:  content.Text[start:end],
+		Start: start,
+		End:   end,
+		Metadata: map[string]interface{}{
+			MetaDocumentID: content.ID,
+			MetaStrategy:   strategy,
+		},
+	}
+	if content.Original != nil {
+		chunk.Source = content.Original.URL
+		if content.Original.Title != "" {
+			chunk.Metadata[MetaTitle] = content.Original.Title
+		}
+		if content.Original.Language != "" {
+			chunk.Metadata[MetaLanguage] = content.Original.Language
+		}
+		if len(content.Original.Tags) > 0 {
+			chunk.Metadata[MetaTags] = append([]string(nil), content.Original.Tags...)
+		}
+	}
+	if path := sectionPath(content.Outline, start, end); len(path) > 0 {
+		chunk.Metadata[MetaSectionPath] = path
+	}
+	if redactions := content.RedactionsIn(start, end); len(redactions) > 0 {
+		chunk.Metadata[MetaRedactions] = redactions
+	}
+	return chunk
+}
+
+// sectionPath returns the headings enclosing the whole range start to end
+func sectionPath(outline []*models.Section, start, end int) []string {
+	first := models.HeadingPath(outline, start)
+	last := models.HeadingPath(outline, end-1)
+	n := 0
+	for n < len(first) && n < len(last) && first[n] == last[n] {
+		n++
+	}
+	return first[:n]
+}
+
+// finish numbers the chunks, records their token counts and assigns their
+// IDs
+func finish(content *models.NormalizedContent, chunks []*models.ContentChunk, tokenizer Tokenizer) []*models.ContentChunk {
+	for i, chunk := range chunks {
+		chunk.Metadata[MetaIndex] = i
+		chunk.Metadata[MetaTokens] = tokenizer.Count(chunk.Text)
+	}
+	models.AssignChunkIDs(content.ID, chunks)
+	return chunks
+}
+
+// trimRange shrinks the range start to end of text to exclude surrounding
+// whitespace
+func trimRange(text string, start, end int) (int, int) {
+	for start < end {
+		r, size := utf8.DecodeRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += size
+	}
+	for end > start {
+		r, size := utf8.DecodeLastRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		end -= size
+	}
+	return start, end
+}
+
+// runeStart moves offset back to the start of the character it falls in
+func runeStart(text string, offset int) int {
+	for offset > 0 && offset < len(text) && !utf8.RuneStart(text[offset]) {
+		offset--
+	}
+	return offset
+}
@@ -0,0 +1,165 @@
+package chunker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// numberedWords returns n words "w0 w1 ..." wrapped at ten words a line
+func numberedWords(n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			if i%10 == 0 {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString(" ")
+			}
+		}
+		fmt.Fprintf(&sb, "w%d", i)
+	}
+	return sb.String()
+}
+
+func normalizedContent(text string) *models.NormalizedContent {
+	original := &models.ExtractedContent{URL: "https://example.com/post", Title: "A Post", Text: text}
+	return &models.NormalizedContent{ID: models.DocumentID(original.URL), Original: original, Text: text}
+}
+
+// checkChunks verifies that every chunk matches its range of the text and
+// stays within the token limit
+func checkChunks(t *testing.T, content *models.NormalizedContent, chunks []*models.ContentChunk, tokenizer Tokenizer, maxTokens int) {
+	t.Helper()
+
+	for i, chunk := range chunks {
+		if chunk.Text != content.Text[chunk.Start:chunk.End] {
+			t.Errorf("Chunk %d text does not match its range %d-%d", i, chunk.Start, chunk.End)
+		}
+		if n := tokenizer.Count(chunk.Text); n > maxTokens {
+			t.Errorf("Chunk %d has %d tokens, more than %d", i, n, maxTokens)
+		}
+		if chunk.ID == "" || chunk.Source != content.Original.URL {
+			t.Errorf("Chunk %d has ID %q and source %q", i, chunk.ID, chunk.Source)
+		}
+		if chunk.Metadata[MetaIndex] != i || chunk.Metadata[MetaDocumentID] != content.ID {
+			t.Errorf("Chunk %d has metadata %v", i, chunk.Metadata)
+		}
+	}
+}
+
+func TestTokenChunker(t *testing.T) {
+	content := normalizedContent(numberedWords(100))
+	c, err := NewTokenChunker(Config{MaxTokens: 30, Overlap: 5})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+
+	chunks, err := c.Chunk(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+	checkChunks(t, content, chunks, WhitespaceTokenizer{}, 30)
+
+	if len(chunks) != 4 {
+		t.Fatalf("Expected 4 chunks, got %d", len(chunks))
+	}
+	if !strings.HasPrefix(chunks[1].Text, "w25 ") || !strings.HasSuffix(chunks[3].Text, "w99") {
+		t.Errorf("Unexpected windows: %q ... %q", chunks[1].Text[:10], chunks[3].Text)
+	}
+	if chunks[0].Metadata[MetaTokens] != 30 || chunks[0].Metadata[MetaStrategy] != StrategyToken {
+		t.Errorf("Unexpected metadata: %v", chunks[0].Metadata)
+	}
+}
+
+func TestTokenChunkerBPE(t *testing.T) {
+	tokenizer, err := LoadBPETokenizer("testdata/tiny.tiktoken")
+	if err != nil {
+		t.Fatalf("Failed to load vocabulary: %v", err)
+	}
+	// Multi-byte characters are split across tokens by the tiny vocabulary
+	content := normalizedContent(strings.Repeat("the chunk é ", 20))
+	c, err := NewTokenChunker(Config{MaxTokens: 7, Overlap: 2, Tokenizer: tokenizer})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+
+	chunks, err := c.Chunk(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+	checkChunks(t, content, chunks, tokenizer, 7)
+	for i, chunk := range chunks {
+		if !utf8.ValidString(chunk.Text) {
+			t.Errorf("Chunk %d splits a character: %q", i, chunk.Text)
+		}
+	}
+	if last := chunks[len(chunks)-1]; last.End != len(strings.TrimSpace(content.Text)) {
+		t.Errorf("Last chunk ends at %d, want the end of the text", last.End)
+	}
+}
+
+func TestChunkRecordsTags(t *testing.T) {
+	content := normalizedContent(numberedWords(20))
+	content.Original.Tags = []string{"go", "c#"}
+	c, err := NewTokenChunker(Config{MaxTokens: 10})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+
+	chunks, err := c.Chunk(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+	for i, chunk := range chunks {
+		tags, _ := chunk.Metadata[MetaTags].([]string)
+		if strings.Join(tags, ",") != "go,c#" {
+			t.Errorf("Chunk %d has tags %v", i, chunk.Metadata[MetaTags])
+		}
+	}
+}
+
+func TestChunkRecordsRedactions(t *testing.T) {
+	content := normalizedContent("Mail [EMAIL] for details about the plan")
+	content.Redactions = []models.Redaction{{Kind: "email", Action: "redact", Start: 5, End: 12}}
+	c, err := NewTokenChunker(Config{MaxTokens: 4})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+
+	chunks, err := c.Chunk(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+	if _, ok := chunks[0].Metadata[MetaRedactions]; !ok {
+		t.Error("First chunk should record the redaction")
+	}
+	if _, ok := chunks[1].Metadata[MetaRedactions]; ok {
+		t.Error("Second chunk should not record the redaction")
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	for _, config := range []Config{{MaxTokens: 0}, {MaxTokens: 10, Overlap: 10}, {MaxTokens: 10, Overlap: -1}} {
+		if _, err := NewTokenChunker(config); err == nil {
+			t.Errorf("NewTokenChunker(%+v) should fail", config)
+		}
+	}
+}
+
+func TestChunkCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c, err := NewTokenChunker(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+	if _, err := c.Chunk(ctx, normalizedContent("text")); err == nil {
+		t.Error("Expected an error for a canceled context")
+	}
+}
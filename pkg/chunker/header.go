@@ -0,0 +1,135 @@
+package chunker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// HeaderData is the data a context header template is executed with
+type HeaderData struct {
+	Title string
+	// Site is the site name the page gives, or else the host of its URL
+	Site string
+	URL  string
+	// Date is the publication date, or else the date of the last update,
+	// as YYYY-MM-DD when it can be parsed
+	Date   string
+	Author string
+	// SectionPath holds the headings enclosing the chunk, outermost first,
+	// and Section the same headings joined with " > "
+	SectionPath []string
+	Section     string
+}
+
+// HeaderTemplate renders context headers for chunks from a text/template
+// such as
+//
+//	{{.Title}}{{with .Section}} > {{.}}{{end}}
+//	{{.Site}}{{with .Date}}, {{.}}{{end}}
+//
+// Surrounding whitespace is trimmed from the result.
+type HeaderTemplate struct {
+	tmpl *template.Template
+}
+
+// NewHeaderTemplate parses a context header template. Templates that refer
+// to fields HeaderData does not have are rejected here rather than when
+// chunking.
+func NewHeaderTemplate(text string) (*HeaderTemplate, error) {
+	tmpl, err := template.New("context_header").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid context header template: %w", err)
+	}
+	if err := tmpl.Execute(io.Discard, HeaderData{}); err != nil {
+		return nil, fmt.Errorf("invalid context header template: %w", err)
+	}
+	return &HeaderTemplate{tmpl: tmpl}, nil
+}
+
+// Header renders the context header of a chunk of content
+func (h *HeaderTemplate) Header(content *models.NormalizedContent, chunk *models.ContentChunk) (string, error) {
+	data := HeaderData{URL: chunk.Source}
+	if original := content.Original; original != nil {
+		data.Title = original.Title
+		data.URL = original.URL
+		data.Site = siteName(original)
+		data.Author = original.Author
+		data.Date = formatDate(original.Published)
+		if data.Date == "" {
+			data.Date = formatDate(original.Updated)
+		}
+	}
+	if path, ok := chunk.Metadata[MetaSectionPath].([]string); ok {
+		data.SectionPath = path
+		data.Section = strings.Join(path, " > ")
+	}
+
+	var sb strings.Builder
+	if err := h.tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render context header: %w", err)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// ContextualChunker implements the models.Chunker interface by setting the
+// Context of the chunks of another chunker to a header rendered from a
+// HeaderTemplate
+type ContextualChunker struct {
+	chunker models.Chunker
+	header  *HeaderTemplate
+}
+
+// WithContextHeaders wraps chunker to give its chunks context headers
+func WithContextHeaders(chunker models.Chunker, header *HeaderTemplate) *ContextualChunker {
+	return &ContextualChunker{chunker: chunker, header: header}
+}
+
+// Chunk splits the normalized content with the wrapped chunker and renders
+// the context header of every chunk
+func (c *ContextualChunker) Chunk(ctx context.Context, content *models.NormalizedContent) ([]*models.ContentChunk, error) {
+	chunks, err := c.chunker.Chunk(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	for _, chunk := range chunks {
+		if chunk.Context, err = c.header.Header(content, chunk); err != nil {
+			return nil, err
+		}
+	}
+	return chunks, nil
+}
+
+// siteName returns the site name from the page metadata, or else the host
+// of the page URL without "www."
+func siteName(content *models.ExtractedContent) string {
+	if name := content.Metadata["og:site_name"]; name != "" {
+		return name
+	}
+	u, err := url.Parse(content.URL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// dateLayouts are the date formats formatDate recognizes
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02", time.RFC1123Z, time.RFC1123}
+
+// formatDate returns date as YYYY-MM-DD when it is in one of dateLayouts,
+// and unchanged otherwise
+func formatDate(date string) string {
+	date = strings.TrimSpace(date)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+	return date
+}
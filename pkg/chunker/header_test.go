@@ -0,0 +1,100 @@
+package chunker
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+const testHeader = "{{.Title}}{{with .Section}} > {{.}}{{end}}\n{{.Site}}{{with .Date}}, {{.}}{{end}}"
+
+func TestContextualChunker(t *testing.T) {
+	content, _ := structuredContent()
+	content.Original.URL = "https://www.example.com/guide"
+	content.Original.Title = "The Guide"
+	content.Original.Published = "2024-03-05T10:00:00Z"
+
+	inner, err := NewStructuralChunker(Config{MaxTokens: 20, TargetTokens: 15, RespectHeadings: true, RespectParagraphs: true})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+	header, err := NewHeaderTemplate(testHeader)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+	plain, err := inner.Chunk(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+	chunks, err := WithContextHeaders(inner, header).Chunk(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+
+	if want := "The Guide > Guide > Usage\nexample.com, 2024-03-05"; chunks[1].Context != want {
+		t.Errorf("Context = %q, want %q", chunks[1].Context, want)
+	}
+	for i, chunk := range chunks {
+		// The display text and ID are those of the chunk without a header
+		if chunk.Text != plain[i].Text || chunk.ID != plain[i].ID {
+			t.Errorf("Chunk %d changed: %q", i, chunk.Text)
+		}
+		if want := chunk.Context + "\n\n" + chunk.Text; chunk.EmbeddingText() != want {
+			t.Errorf("Chunk %d embedding text = %q", i, chunk.EmbeddingText())
+		}
+	}
+}
+
+func TestHeaderFallbacks(t *testing.T) {
+	header, err := NewHeaderTemplate("{{.Site}}|{{.Date}}|{{.Section}}")
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+	content := normalizedContent("text")
+	content.Original.Metadata = map[string]string{"og:site_name": "Example Blog"}
+	content.Original.Updated = "March 2024"
+
+	got, err := header.Header(content, &models.ContentChunk{Metadata: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	if want := "Example Blog|March 2024|"; got != want {
+		t.Errorf("Header = %q, want %q", got, want)
+	}
+}
+
+func TestNewHeaderTemplateInvalid(t *testing.T) {
+	for _, text := range []string{"{{.Title", "{{.Unknown}}"} {
+		if _, err := NewHeaderTemplate(text); err == nil || !strings.Contains(err.Error(), "context header") {
+			t.Errorf("NewHeaderTemplate(%q) error = %v", text, err)
+		}
+	}
+}
+
+func TestHeaderFromPageMetadata(t *testing.T) {
+	header, err := NewHeaderTemplate("{{.Site}}, {{.Date}}")
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	// The site name and date as the extractors record them from the meta
+	// elements of a page
+	extracted := &models.ExtractedContent{
+		URL:       "https://blog.example.com/gc",
+		Title:     "Tuning the Garbage Collector",
+		Text:      "The garbage collector trades memory for CPU time.",
+		Published: "2024-03-05T10:00:00Z",
+		Metadata:  map[string]string{"og:site_name": "Example Engineering"},
+	}
+	content := &models.NormalizedContent{ID: models.DocumentID(extracted.URL), Original: extracted, Text: extracted.Text}
+
+	got, err := header.Header(content, &models.ContentChunk{Source: extracted.URL})
+	if err != nil {
+		t.Fatalf("Failed to render header: %v", err)
+	}
+	if want := "Example Engineering, 2024-03-05"; got != want {
+		t.Errorf("Header = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,87 @@
+package chunker
+
+import (
+	"context"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// StrategyHierarchical is the strategy name recorded by
+// HierarchicalChunker
+const StrategyHierarchical = "hierarchical"
+
+// HierarchicalChunker implements the models.Chunker interface with two
+// levels of chunks for small-to-big retrieval. The text is first split
+// along its structure into parent chunks of up to ParentTokens tokens, and
+// each parent is then split the same way into child chunks of up to
+// MaxTokens tokens. Children are matched against queries and their parent
+// is handed to the reader for context.
+//
+// Each parent is followed by its children in the result. Parents record
+// MetaChildIDs and children MetaParentID; both record MetaLevel.
+type HierarchicalChunker struct {
+	config Config
+}
+
+// NewHierarchicalChunker creates a HierarchicalChunker with the given
+// configuration
+func NewHierarchicalChunker(config Config) (*HierarchicalChunker, error) {
+	config, err := config.validate()
+	if err != nil {
+		return nil, err
+	}
+	return &HierarchicalChunker{config: config}, nil
+}
+
+// Chunk splits the normalized content into parents and their children
+func (c *HierarchicalChunker) Chunk(ctx context.Context, content *models.NormalizedContent) ([]*models.ContentChunk, error) {
+	// Check if context is canceled
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var outline []*models.Section
+	if c.config.RespectHeadings {
+		outline = content.Outline
+	}
+
+	parentConfig := c.config
+	parentConfig.MaxTokens = c.config.ParentTokens
+	parentConfig.TargetTokens = c.config.ParentTokens
+	parentSplit := newStructuralSplit(parentConfig, content)
+	childSplit := newStructuralSplit(c.config, content)
+
+	var chunks []*models.ContentChunk
+	families := make(map[*models.ContentChunk][]*models.ContentChunk)
+	for _, p := range parentSplit.section(0, len(content.Text), outline, false) {
+		parent := parentSplit.chunk(p, StrategyHierarchical)
+		if parent == nil {
+			continue
+		}
+		parent.Metadata[MetaLevel] = LevelParent
+		chunks = append(chunks, parent)
+
+		for _, cp := range childSplit.section(parent.Start, parent.End, outline, false) {
+			if child := childSplit.chunk(cp, StrategyHierarchical); child != nil {
+				child.Metadata[MetaLevel] = LevelChild
+				chunks = append(chunks, child)
+				families[parent] = append(families[parent], child)
+			}
+		}
+	}
+
+	// IDs are assigned over both levels at once, so a child with the same
+	// text as its parent still gets an ID of its own
+	chunks = finish(content, chunks, c.config.Tokenizer)
+	for parent, children := range families {
+		childIDs := make([]string, len(children))
+		for i, child := range children {
+			child.Metadata[MetaParentID] = parent.ID
+			childIDs[i] = child.ID
+		}
+		parent.Metadata[MetaChildIDs] = childIDs
+	}
+	return chunks, nil
+}
@@ -0,0 +1,86 @@
+package chunker
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestHierarchicalChunker(t *testing.T) {
+	content := normalizedContent(sentencesOf(12))
+	c, err := NewHierarchicalChunker(Config{MaxTokens: 20, ParentTokens: 60, RespectParagraphs: true})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+
+	chunks, err := c.Chunk(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+	checkChunks(t, content, chunks, WhitespaceTokenizer{}, 60)
+
+	ids := make(map[string]bool)
+	var parents, children int
+	var parentText string
+	for i, chunk := range chunks {
+		if ids[chunk.ID] {
+			t.Errorf("Chunk %d has a duplicate ID", i)
+		}
+		ids[chunk.ID] = true
+
+		switch chunk.Metadata[MetaLevel] {
+		case LevelParent:
+			parents++
+			parentText = chunk.Text
+			childIDs, _ := chunk.Metadata[MetaChildIDs].([]string)
+			if len(childIDs) == 0 {
+				t.Errorf("Parent %d has no children", i)
+			}
+			for j, id := range childIDs {
+				if i+1+j >= len(chunks) || chunks[i+1+j].ID != id {
+					t.Errorf("Parent %d is not followed by child %s", i, id)
+				}
+			}
+		case LevelChild:
+			children++
+			if n := (WhitespaceTokenizer{}).Count(chunk.Text); n > 20 {
+				t.Errorf("Child %d has %d tokens", i, n)
+			}
+			parentID, _ := chunk.Metadata[MetaParentID].(string)
+			if !ids[parentID] || !strings.Contains(parentText, chunk.Text) {
+				t.Errorf("Child %d is not inside its parent %q", i, parentID)
+			}
+		default:
+			t.Errorf("Chunk %d has level %v", i, chunk.Metadata[MetaLevel])
+		}
+	}
+	if parents != 2 || children != 6 {
+		t.Errorf("Expected 2 parents and 6 children, got %d and %d", parents, children)
+	}
+}
+
+func TestHierarchicalChunkerSmallDocument(t *testing.T) {
+	content := normalizedContent("A single short sentence.")
+	c, err := NewHierarchicalChunker(Config{MaxTokens: 20})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+	chunks, err := c.Chunk(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+
+	// The child repeats its parent's text but has an ID of its own
+	if len(chunks) != 2 || chunks[0].Text != chunks[1].Text || chunks[0].ID == chunks[1].ID {
+		t.Fatalf("Expected a parent and an identical child with distinct IDs, got %q", chunkTexts(chunks))
+	}
+	if chunks[1].Metadata[MetaParentID] != chunks[0].ID {
+		t.Errorf("Child has parent %v, want %s", chunks[1].Metadata[MetaParentID], chunks[0].ID)
+	}
+}
+
+func TestHierarchicalConfigValidation(t *testing.T) {
+	if _, err := NewHierarchicalChunker(Config{MaxTokens: 20, ParentTokens: 10}); err == nil {
+		t.Error("Expected an error for parents smaller than children")
+	}
+}
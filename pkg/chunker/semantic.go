@@ -0,0 +1,181 @@
+package chunker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/embedding"
+)
+
+// StrategySemantic is the strategy name recorded by SemanticChunker
+const StrategySemantic = "semantic"
+
+// SemanticChunker implements the models.Chunker interface by cutting where
+// the topic changes. The text is split into sentences, which are embedded
+// with the configured Embedder, and a chunk ends where the cosine distance
+// between the sentences before and after a point spikes, as long as the
+// chunk has at least MinTokens tokens. A chunk is always cut before it
+// would exceed MaxTokens. Code blocks and tables count as single sentences
+// and are never cut.
+type SemanticChunker struct {
+	config Config
+}
+
+// NewSemanticChunker creates a SemanticChunker with the given
+// configuration, which must include an Embedder
+func NewSemanticChunker(config Config) (*SemanticChunker, error) {
+	config, err := config.validate()
+	if err != nil {
+		return nil, err
+	}
+	if config.Embedder == nil {
+		return nil, fmt.Errorf("the semantic chunker requires an embedder")
+	}
+	return &SemanticChunker{config: config}, nil
+}
+
+// Chunk splits the normalized content at semantic breakpoints
+func (c *SemanticChunker) Chunk(ctx context.Context, content *models.NormalizedContent) ([]*models.ContentChunk, error) {
+	// Check if context is canceled
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s := newStructuralSplit(c.config, content)
+	units := s.units(0, len(content.Text))
+	if len(units) == 0 {
+		return nil, nil
+	}
+
+	distances, err := c.distances(ctx, content.Text, units)
+	if err != nil {
+		return nil, err
+	}
+	threshold := percentile(distances, c.config.BreakpointPercentile)
+
+	var chunks []*models.ContentChunk
+	add := func(start, end int) {
+		if chunk := newChunk(content, start, end, StrategySemantic); chunk != nil {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	start, tokens := units[0].start, units[0].tokens
+	for i := 1; i < len(units); i++ {
+		u := units[i]
+		breakpoint := distances[i-1] > 0 && distances[i-1] >= threshold && tokens >= c.config.MinTokens
+		if !breakpoint && tokens+u.tokens <= c.config.MaxTokens {
+			if n := s.count(start, u.end); n <= c.config.MaxTokens {
+				tokens = n
+				continue
+			}
+		}
+		add(start, u.start)
+		start, tokens = u.start, u.tokens
+	}
+
+	// A short last chunk joins the one before it when both fit
+	if n := len(chunks); n > 0 && tokens < c.config.MinTokens {
+		last := chunks[n-1]
+		if s.count(last.Start, len(content.Text)) <= c.config.MaxTokens {
+			chunks = chunks[:n-1]
+			start = last.Start
+		}
+	}
+	add(start, len(content.Text))
+
+	return finish(content, chunks, c.config.Tokenizer), nil
+}
+
+// distances embeds the units and returns, for each point between two
+// units, the cosine distance between the windows of WindowSentences units
+// before and after it. A window's vector is the sum of its units' vectors.
+func (c *SemanticChunker) distances(ctx context.Context, text string, units []piece) ([]float64, error) {
+	sentences := make([]*models.ContentChunk, len(units))
+	for i, u := range units {
+		sentences[i] = &models.ContentChunk{Text: text[u.start:u.end], Start: u.start, End: u.end}
+	}
+	embeddings, err := c.config.Embedder.Embed(ctx, sentences)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed sentences: %w", err)
+	}
+	if len(embeddings) != len(units) {
+		return nil, fmt.Errorf("embedder returned %d embeddings for %d sentences", len(embeddings), len(units))
+	}
+
+	window := func(from, to int) []float32 {
+		var sum []float32
+		for _, e := range embeddings[from:to] {
+			if sum == nil {
+				sum = make([]float32, len(e.Vector))
+			}
+			for j := range sum {
+				if j < len(e.Vector) {
+					sum[j] += e.Vector[j]
+				}
+			}
+		}
+		return sum
+	}
+
+	w := c.config.WindowSentences
+	distances := make([]float64, len(units)-1)
+	for i := range distances {
+		before := window(max(i+1-w, 0), i+1)
+		after := window(i+1, min(i+1+w, len(units)))
+		distances[i] = 1 - embedding.CosineSimilarity(before, after)
+	}
+	return distances, nil
+}
+
+// units splits the range start to end into sentences, with each code block
+// and table as a single unit. Sentences longer than MaxTokens are cut into
+// token windows.
+func (s *structuralSplit) units(start, end int) []piece {
+	var units []piece
+	add := func(from, to int) {
+		if from < to && !isSpace(s.content.Text[from:to]) {
+			units = append(units, piece{start: from, end: to, tokens: s.count(from, to)})
+		}
+	}
+
+	pos := start
+	for pos < end {
+		block := s.blockAfter(pos, end)
+		gapEnd := end
+		if block != nil {
+			gapEnd = max(block.Start, pos)
+		}
+		for _, p := range s.sentences(pos, gapEnd) {
+			add(p.start, p.end)
+		}
+		if block == nil {
+			break
+		}
+		blockEnd := min(block.End, end)
+		add(gapEnd, blockEnd)
+		pos = blockEnd
+	}
+	return units
+}
+
+// percentile returns the p-th percentile of values, interpolating between
+// the closest ranks, or 0 when there are no values
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
@@ -0,0 +1,188 @@
+package chunker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/embedding"
+)
+
+// topics are three groups of sentences that share words within a group
+// but not across groups
+var topics = [][]string{
+	{
+		"The cat sleeps on the warm windowsill.",
+		"A sleepy cat purrs on the windowsill.",
+		"The cat stretches and sleeps again.",
+	},
+	{
+		"Rocket engines burn liquid fuel at launch.",
+		"The rocket climbs while engines burn fuel.",
+		"Liquid fuel feeds the rocket engines.",
+	},
+	{
+		"Simmer tomato sauce with garlic and basil.",
+		"Garlic and basil flavor the tomato sauce.",
+		"Stir the sauce until the garlic softens.",
+	},
+}
+
+func topicText() string {
+	var sentences []string
+	for _, topic := range topics {
+		sentences = append(sentences, topic...)
+	}
+	return strings.Join(sentences, " ")
+}
+
+func newHashEmbedder(t *testing.T) *embedding.HashEmbedder {
+	t.Helper()
+	embedder, err := embedding.NewHashEmbedder(256)
+	if err != nil {
+		t.Fatalf("Failed to create embedder: %v", err)
+	}
+	return embedder
+}
+
+func TestSemanticChunker(t *testing.T) {
+	content := normalizedContent(topicText())
+	c, err := NewSemanticChunker(Config{MaxTokens: 100, MinTokens: 5, BreakpointPercentile: 80, WindowSentences: 2, Embedder: newHashEmbedder(t)})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+
+	chunks, err := c.Chunk(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+	checkChunks(t, content, chunks, WhitespaceTokenizer{}, 100)
+
+	if len(chunks) != len(topics) {
+		t.Fatalf("Expected one chunk per topic, got %d: %q", len(chunks), chunkTexts(chunks))
+	}
+	for i, topic := range topics {
+		if want := strings.Join(topic, " "); chunks[i].Text != want {
+			t.Errorf("Chunk %d = %q, want %q", i, chunks[i].Text, want)
+		}
+		if chunks[i].Metadata[MetaStrategy] != StrategySemantic {
+			t.Errorf("Chunk %d has strategy %v", i, chunks[i].Metadata[MetaStrategy])
+		}
+	}
+
+	// The same input always gives the same chunks
+	again, err := c.Chunk(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+	for i := range again {
+		if again[i].ID != chunks[i].ID {
+			t.Errorf("Chunk %d changed ID between runs", i)
+		}
+	}
+}
+
+func TestSemanticChunkerLimits(t *testing.T) {
+	content := normalizedContent(topicText())
+
+	// Every chunk would be a breakpoint, but none may be shorter than
+	// MinTokens except a last one that cannot be merged
+	c, err := NewSemanticChunker(Config{MaxTokens: 30, MinTokens: 20, BreakpointPercentile: 1, Embedder: newHashEmbedder(t)})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+	chunks, err := c.Chunk(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+	checkChunks(t, content, chunks, WhitespaceTokenizer{}, 30)
+	for i, chunk := range chunks[:len(chunks)-1] {
+		if n := (WhitespaceTokenizer{}).Count(chunk.Text); n < 20 {
+			t.Errorf("Chunk %d has %d tokens, fewer than 20", i, n)
+		}
+	}
+
+	// A long sentence is cut into token windows
+	long := normalizedContent(numberedWords(50) + ".")
+	c, err = NewSemanticChunker(Config{MaxTokens: 20, Embedder: newHashEmbedder(t)})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+	chunks, err = c.Chunk(context.Background(), long)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+	checkChunks(t, long, chunks, WhitespaceTokenizer{}, 20)
+	if len(chunks) != 3 {
+		t.Errorf("Expected 3 chunks, got %d", len(chunks))
+	}
+}
+
+func TestSemanticChunkerKeepsBlocks(t *testing.T) {
+	code := "for i := 0; i < 10; i++ {\n\tfmt.Println(i, i*i, i*i*i)\n}"
+	text := strings.Join(topics[0], " ") + "\n\n" + code + "\n\n" + strings.Join(topics[1], " ")
+	content := normalizedContent(text)
+	start := strings.Index(text, code)
+	content.Blocks = []models.Block{{Kind: models.BlockCode, Text: code, Start: start, End: start + len(code)}}
+
+	c, err := NewSemanticChunker(Config{MaxTokens: 8, Embedder: newHashEmbedder(t)})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+	chunks, err := c.Chunk(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+	found := false
+	for _, chunk := range chunks {
+		if strings.Contains(chunk.Text, "fmt.Println") {
+			found = chunk.Text == code
+		}
+	}
+	if !found {
+		t.Errorf("Expected the code block as a chunk of its own, got %q", chunkTexts(chunks))
+	}
+}
+
+// failingEmbedder always returns an error
+type failingEmbedder struct{}
+
+func (failingEmbedder) Embed(ctx context.Context, chunks []*models.ContentChunk) ([]*models.VectorEmbedding, error) {
+	return nil, errors.New("offline")
+}
+
+func TestSemanticChunkerErrors(t *testing.T) {
+	if _, err := NewSemanticChunker(Config{MaxTokens: 10}); err == nil {
+		t.Error("Expected an error without an embedder")
+	}
+	if _, err := NewSemanticChunker(Config{MaxTokens: 10, MinTokens: 11, Embedder: failingEmbedder{}}); err == nil {
+		t.Error("Expected an error for min tokens above max tokens")
+	}
+
+	c, err := NewSemanticChunker(Config{MaxTokens: 10, Embedder: failingEmbedder{}})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+	if _, err := c.Chunk(context.Background(), normalizedContent(topicText())); err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("Expected the embedder error, got %v", err)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{4, 1, 3, 2}
+	for _, tt := range []struct{ p, want float64 }{{0, 1}, {50, 2.5}, {100, 4}} {
+		if got := percentile(values, tt.p); got != tt.want {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
+func chunkTexts(chunks []*models.ContentChunk) []string {
+	var texts []string
+	for _, chunk := range chunks {
+		texts = append(texts, chunk.Text)
+	}
+	return texts
+}
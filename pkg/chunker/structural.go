@@ -0,0 +1,349 @@
+package chunker
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/htmlutil"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/sentence"
+)
+
+// StrategyStructural is the strategy name recorded by StructuralChunker
+const StrategyStructural = "structural"
+
+// StructuralChunker implements the models.Chunker interface by following
+// the structure of the document. Text is split at headings first, then at
+// paragraphs and then at sentences, only as far as needed to stay within
+// MaxTokens, and adjacent small pieces under the same heading are merged
+// up to TargetTokens. Code blocks and tables are only cut when larger than
+// MaxTokens, at line boundaries: every part of a code block is fenced, and
+// every part of a table repeats its header row.
+type StructuralChunker struct {
+	config Config
+}
+
+// NewStructuralChunker creates a StructuralChunker with the given
+// configuration
+func NewStructuralChunker(config Config) (*StructuralChunker, error) {
+	config, err := config.validate()
+	if err != nil {
+		return nil, err
+	}
+	return &StructuralChunker{config: config}, nil
+}
+
+// piece is a range of the text that becomes a chunk unless merged with its
+// neighbors. Pieces that are only part of their paragraph or section are
+// not merged across it, except that a heading is always kept with the text
+// that follows it. Text, when set, is the text of the chunk in place of the
+// range, for the parts of a code block or table.
+type piece struct {
+	start, end int
+	tokens     int
+	mergeable  bool
+	heading    bool
+	text       string
+}
+
+// Chunk splits the normalized content along its structure
+func (c *StructuralChunker) Chunk(ctx context.Context, content *models.NormalizedContent) ([]*models.ContentChunk, error) {
+	// Check if context is canceled
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s := newStructuralSplit(c.config, content)
+	var outline []*models.Section
+	if c.config.RespectHeadings {
+		outline = content.Outline
+	}
+
+	var chunks []*models.ContentChunk
+	for _, p := range s.section(0, len(content.Text), outline, false) {
+		if chunk := s.chunk(p, StrategyStructural); chunk != nil {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return finish(content, chunks, c.config.Tokenizer), nil
+}
+
+// structuralSplit holds the state of chunking one document
+type structuralSplit struct {
+	config    Config
+	content   *models.NormalizedContent
+	segmenter *sentence.Segmenter
+}
+
+// newStructuralSplit prepares to chunk content, splitting sentences by the
+// rules of its language
+func newStructuralSplit(config Config, content *models.NormalizedContent) *structuralSplit {
+	var language string
+	if content.Original != nil {
+		language = content.Original.Language
+	}
+	return &structuralSplit{config: config, content: content, segmenter: sentence.For(language)}
+}
+
+func (s *structuralSplit) count(start, end int) int {
+	return s.config.Tokenizer.Count(s.content.Text[start:end])
+}
+
+// chunk creates the chunk of a piece
+func (s *structuralSplit) chunk(p piece, strategy string) *models.ContentChunk {
+	chunk := newChunk(s.content, p.start, p.end, strategy)
+	if chunk != nil && p.text != "" {
+		chunk.Text = p.text
+	}
+	return chunk
+}
+
+// section splits the range start to end, whose subsections are children.
+// titled reports whether the range starts with the section's heading line.
+func (s *structuralSplit) section(start, end int, children []*models.Section, titled bool) []piece {
+	if tokens := s.count(start, end); tokens <= s.config.TargetTokens {
+		return []piece{{start: start, end: end, tokens: tokens, mergeable: true}}
+	}
+
+	headingEnd := start
+	if titled {
+		headingEnd = end
+		if i := strings.IndexByte(s.content.Text[start:end], '\n'); i >= 0 {
+			headingEnd = start + i
+		}
+	}
+
+	var pieces []piece
+	pos := start
+	for _, child := range children {
+		childStart, childEnd := max(child.Start, pos), min(child.End, end)
+		if childStart >= childEnd {
+			continue
+		}
+		pieces = append(pieces, s.paragraphs(pos, childStart, headingEnd)...)
+		// A subsection that had to be split is not merged with its siblings
+		childPieces := s.section(childStart, childEnd, child.Children, childStart == child.Start)
+		if len(childPieces) > 1 {
+			for i := range childPieces {
+				childPieces[i].mergeable = false
+			}
+		}
+		pieces = append(pieces, childPieces...)
+		pos = childEnd
+	}
+	pieces = append(pieces, s.paragraphs(pos, end, headingEnd)...)
+
+	return s.merge(pieces)
+}
+
+// paragraphs splits text without subsections into paragraphs, each a whole
+// piece unless it has to be split into sentences, or lines for code blocks
+// and tables. A paragraph ending by headingEnd is the heading of the
+// section.
+func (s *structuralSplit) paragraphs(start, end, headingEnd int) []piece {
+	var pieces []piece
+	for _, r := range s.paragraphRanges(start, end) {
+		tokens := s.count(r[0], r[1])
+		if tokens <= s.config.MaxTokens {
+			pieces = append(pieces, piece{start: r[0], end: r[1], tokens: tokens, mergeable: true, heading: r[1] <= headingEnd})
+			continue
+		}
+		if block := s.blockOf(r[0], r[1]); block != nil {
+			pieces = append(pieces, s.blockParts(block, r[0], r[1])...)
+			continue
+		}
+		// The sentences of a long paragraph are merged among themselves only
+		for _, p := range s.merge(s.sentences(r[0], r[1])) {
+			p.mergeable = false
+			pieces = append(pieces, p)
+		}
+	}
+	return s.merge(pieces)
+}
+
+// sentences splits a paragraph into sentences, cutting sentences longer
+// than MaxTokens into token windows
+func (s *structuralSplit) sentences(start, end int) []piece {
+	var pieces []piece
+	for _, span := range s.segmenter.Segment(s.content.Text[start:end]) {
+		from, to := start+span.Start, start+span.End
+		tokens := s.count(from, to)
+		if tokens <= s.config.MaxTokens {
+			pieces = append(pieces, piece{start: from, end: to, tokens: tokens, mergeable: true})
+			continue
+		}
+		windows := s.config
+		windows.Overlap = 0
+		for _, chunk := range splitTokens(s.content, from, to, windows, StrategyStructural) {
+			pieces = append(pieces, piece{start: chunk.Start, end: chunk.End, tokens: s.count(chunk.Start, chunk.End)})
+		}
+	}
+	return pieces
+}
+
+// blockParts splits the range start to end of a code block or table at line
+// boundaries into parts within MaxTokens. Every part of a code block is
+// fenced, and every part of a table repeats its header row. A line too long
+// for a part of its own is cut into token windows.
+func (s *structuralSplit) blockParts(block *models.Block, start, end int) []piece {
+	text := s.content.Text
+	header := text[block.Start:block.End]
+	if i := strings.IndexByte(header, '\n'); i >= 0 {
+		header = header[:i]
+	}
+	render := func(from, to int) string {
+		switch {
+		case block.Kind == models.BlockCode:
+			return htmlutil.FencedCode(text[from:to], block.Language)
+		case block.Kind == models.BlockTable && from > block.Start:
+			return header + "\n" + text[from:to]
+		}
+		return text[from:to]
+	}
+
+	var pieces []piece
+	add := func(from, to int) {
+		if isSpace(text[from:to]) {
+			return
+		}
+		// Drop blank lines around the part but keep its indentation
+		lead := len(text[from:to]) - len(strings.TrimLeftFunc(text[from:to], unicode.IsSpace))
+		if i := strings.LastIndexByte(text[from:from+lead], '\n'); i >= 0 {
+			from += i + 1
+		}
+		to = from + len(strings.TrimRightFunc(text[from:to], unicode.IsSpace))
+		part := render(from, to)
+		if tokens := s.config.Tokenizer.Count(part); tokens <= s.config.MaxTokens {
+			pieces = append(pieces, piece{start: from, end: to, tokens: tokens, text: part})
+			return
+		}
+		windows := s.config
+		windows.Overlap = 0
+		for _, chunk := range splitTokens(s.content, from, to, windows, StrategyStructural) {
+			pieces = append(pieces, piece{start: chunk.Start, end: chunk.End, tokens: s.count(chunk.Start, chunk.End)})
+		}
+	}
+
+	// from is the start of the current part and to the end of its lines
+	from, to := start, start
+	for pos := start; pos < end; {
+		lineEnd := end
+		if i := strings.IndexByte(text[pos:end], '\n'); i >= 0 {
+			lineEnd = pos + i
+		}
+		if to > from && s.config.Tokenizer.Count(render(from, lineEnd)) > s.config.MaxTokens {
+			add(from, to)
+			from = pos
+		}
+		to = lineEnd
+		pos = lineEnd + 1
+	}
+	add(from, to)
+	return pieces
+}
+
+// merge joins runs of adjacent mergeable pieces while the result stays
+// within TargetTokens, and headings with the piece after them while the
+// result stays within MaxTokens
+func (s *structuralSplit) merge(pieces []piece) []piece {
+	var merged []piece
+	for _, p := range pieces {
+		if p.start >= p.end || isSpace(s.content.Text[p.start:p.end]) {
+			continue
+		}
+		if n := len(merged); n > 0 && p.text == "" {
+			last := &merged[n-1]
+			limit := -1
+			switch {
+			case last.mergeable && p.mergeable && last.tokens+p.tokens <= s.config.TargetTokens:
+				limit = s.config.TargetTokens
+			case last.heading:
+				limit = s.config.MaxTokens
+			}
+			if limit >= 0 && last.tokens+p.tokens <= limit {
+				if tokens := s.count(last.start, p.end); tokens <= limit {
+					last.end = p.end
+					last.tokens = tokens
+					last.mergeable = last.mergeable && p.mergeable
+					last.heading = false
+					continue
+				}
+			}
+		}
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// paragraphRanges splits the range start to end at blank lines and around
+// code blocks and tables, never inside them. Without RespectParagraphs
+// only blocks are split out.
+func (s *structuralSplit) paragraphRanges(start, end int) [][2]int {
+	text := s.content.Text
+	var ranges [][2]int
+	add := func(from, to int) {
+		if from < to {
+			ranges = append(ranges, [2]int{from, to})
+		}
+	}
+
+	pos := start
+	for pos < end {
+		block := s.blockAfter(pos, end)
+		gapEnd := end
+		if block != nil {
+			gapEnd = max(block.Start, pos)
+		}
+
+		from := pos
+		if s.config.RespectParagraphs {
+			for {
+				i := strings.Index(text[from:gapEnd], "\n\n")
+				if i < 0 {
+					break
+				}
+				add(from, from+i)
+				from += i + 2
+			}
+		}
+		add(from, gapEnd)
+
+		if block == nil {
+			break
+		}
+		blockEnd := min(block.End, end)
+		add(gapEnd, blockEnd)
+		pos = blockEnd
+	}
+	return ranges
+}
+
+// blockAfter returns the first block that ends after pos and starts before
+// end
+func (s *structuralSplit) blockAfter(pos, end int) *models.Block {
+	var first *models.Block
+	for i := range s.content.Blocks {
+		block := &s.content.Blocks[i]
+		if block.End > pos && block.Start < end && (first == nil || block.Start < first.Start) {
+			first = block
+		}
+	}
+	return first
+}
+
+// blockOf returns the code block or table the range lies within, if any
+func (s *structuralSplit) blockOf(start, end int) *models.Block {
+	for i := range s.content.Blocks {
+		if block := &s.content.Blocks[i]; start >= block.Start && end <= block.End {
+			return block
+		}
+	}
+	return nil
+}
+
+func isSpace(text string) bool {
+	return strings.TrimSpace(text) == ""
+}
@@ -0,0 +1,167 @@
+package chunker
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// sentencesOf returns n sentences of ten words each
+func sentencesOf(n int) string {
+	var sentences []string
+	for i := 0; i < n; i++ {
+		sentences = append(sentences, fmt.Sprintf("Sentence %d has exactly ten words in it for testing.", i))
+	}
+	return strings.Join(sentences, " ")
+}
+
+// structuredContent returns a guide with a short setup section, a long
+// usage section and a code block with a blank line inside
+func structuredContent() (*models.NormalizedContent, string) {
+	code := "func main() {\n\tx := 1 + 2 + 3 + 4 + 5\n\n\ty := x * x * x * x\n\tfmt.Println(x, y, \"done\")\n}"
+	text := "Guide\n\nshort intro here.\n\n" +
+		"Setup\n\nsetup para one two three.\n\n" +
+		"Usage\n\n" + sentencesOf(6) + "\n\n" + code + "\n\nend words."
+
+	content := normalizedContent(text)
+	setup, usage := strings.Index(text, "Setup"), strings.Index(text, "Usage")
+	content.Outline = []*models.Section{{
+		Heading: "Guide", Level: 1, Start: 0, End: len(text),
+		Children: []*models.Section{
+			{Heading: "Setup", Level: 2, Start: setup, End: usage},
+			{Heading: "Usage", Level: 2, Start: usage, End: len(text)},
+		},
+	}}
+	codeStart := strings.Index(text, code)
+	content.Blocks = []models.Block{{Kind: models.BlockCode, Text: code, Start: codeStart, End: codeStart + len(code)}}
+	return content, code
+}
+
+func TestStructuralChunker(t *testing.T) {
+	content, code := structuredContent()
+	c, err := NewStructuralChunker(Config{MaxTokens: 20, TargetTokens: 15, RespectHeadings: true, RespectParagraphs: true})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+
+	chunks, err := c.Chunk(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+
+	var texts []string
+	for _, chunk := range chunks {
+		texts = append(texts, chunk.Text)
+	}
+	want := []string{
+		"Guide\n\nshort intro here.\n\nSetup\n\nsetup para one two three.",
+		"Usage\n\n" + sentencesOf(1),
+		strings.TrimPrefix(sentencesOf(2), sentencesOf(1)+" "),
+	}
+	if len(texts) < len(want) || !reflect.DeepEqual(texts[:len(want)], want) {
+		t.Fatalf("Chunks = %q, want them to start with %q", texts, want)
+	}
+
+	// The code block is larger than MaxTokens, so it is cut between lines
+	// into fenced parts
+	var codeLines []string
+	codeChunks := 0
+	for i, chunk := range chunks {
+		if n := (WhitespaceTokenizer{}).Count(chunk.Text); n > 20 || n != chunk.Metadata[MetaTokens] {
+			t.Errorf("Chunk %d has %d tokens", i, n)
+		}
+		if strings.HasPrefix(chunk.Text, "```") {
+			codeChunks++
+			inner := strings.TrimSuffix(strings.TrimPrefix(chunk.Text, "```\n"), "\n```")
+			codeLines = append(codeLines, strings.Split(inner, "\n")...)
+		}
+	}
+	want = strings.Split(strings.ReplaceAll(code, "\n\n", "\n"), "\n")
+	if codeChunks < 2 || !reflect.DeepEqual(codeLines, want) {
+		t.Errorf("Code parts hold %q, want %q in several parts", codeLines, want)
+	}
+
+	if path := chunks[0].Metadata[MetaSectionPath]; !reflect.DeepEqual(path, []string{"Guide"}) {
+		t.Errorf("First chunk path = %v", path)
+	}
+	if path := chunks[1].Metadata[MetaSectionPath]; !reflect.DeepEqual(path, []string{"Guide", "Usage"}) {
+		t.Errorf("Second chunk path = %v", path)
+	}
+	if last := chunks[len(chunks)-1]; !strings.HasSuffix(last.Text, "end words.") {
+		t.Errorf("Last chunk = %q", last.Text)
+	}
+}
+
+func TestStructuralChunkerSplitsTables(t *testing.T) {
+	rows := []string{"Name | Version | License"}
+	for i := 0; i < 12; i++ {
+		rows = append(rows, fmt.Sprintf("lib%d | v1.%d | MIT", i, i))
+	}
+	table := strings.Join(rows, "\n")
+	text := "Dependencies\n\n" + table + "\n\nThat is all."
+	content := normalizedContent(text)
+	tableStart := strings.Index(text, table)
+	content.Blocks = []models.Block{{Kind: models.BlockTable, Text: table, Start: tableStart, End: tableStart + len(table)}}
+
+	c, err := NewStructuralChunker(Config{MaxTokens: 20, RespectParagraphs: true})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+	chunks, err := c.Chunk(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+
+	var tableRows []string
+	for i, chunk := range chunks {
+		if n := (WhitespaceTokenizer{}).Count(chunk.Text); n > 20 {
+			t.Errorf("Chunk %d has %d tokens: %q", i, n, chunk.Text)
+		}
+		if lines := strings.Split(chunk.Text, "\n"); strings.Contains(chunk.Text, "lib") {
+			if lines[0] != rows[0] {
+				t.Errorf("Chunk %d does not start with the table header: %q", i, chunk.Text)
+			}
+			tableRows = append(tableRows, lines[1:]...)
+		}
+	}
+	if !reflect.DeepEqual(tableRows, rows[1:]) {
+		t.Errorf("Table parts hold rows %q, want %q", tableRows, rows[1:])
+	}
+}
+
+func TestStructuralChunkerWholeDocument(t *testing.T) {
+	content, _ := structuredContent()
+	c, err := NewStructuralChunker(Config{MaxTokens: 200})
+	if err != nil {
+		t.Fatalf("Failed to create chunker: %v", err)
+	}
+
+	chunks, err := c.Chunk(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to chunk: %v", err)
+	}
+	if len(chunks) != 1 || chunks[0].Text != content.Text {
+		t.Errorf("Expected the document as one chunk, got %d chunks", len(chunks))
+	}
+	if chunks[0].Metadata[MetaStrategy] != StrategyStructural {
+		t.Errorf("Unexpected strategy %v", chunks[0].Metadata[MetaStrategy])
+	}
+}
+
+func TestStructuralSentences(t *testing.T) {
+	text := "Use a tool, e.g. a hammer. Version 1.5 is out! Dr. Smith agrees.\nNext line"
+	s := newStructuralSplit(Config{MaxTokens: 20, Tokenizer: WhitespaceTokenizer{}}, normalizedContent(text))
+
+	var got []string
+	for _, p := range s.sentences(0, len(text)) {
+		got = append(got, text[p.start:p.end])
+	}
+	want := []string{"Use a tool, e.g. a hammer.", "Version 1.5 is out!", "Dr. Smith agrees.", "Next line"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sentences() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package chunker
+
+import (
+	"context"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// StrategyToken is the strategy name recorded by TokenChunker
+const StrategyToken = "token"
+
+// TokenChunker implements the models.Chunker interface by cutting the text
+// into windows of at most MaxTokens tokens, each starting Overlap tokens
+// before the end of the previous one
+type TokenChunker struct {
+	config Config
+}
+
+// NewTokenChunker creates a TokenChunker with the given configuration
+func NewTokenChunker(config Config) (*TokenChunker, error) {
+	config, err := config.validate()
+	if err != nil {
+		return nil, err
+	}
+	return &TokenChunker{config: config}, nil
+}
+
+// Chunk splits the normalized content into token windows
+func (c *TokenChunker) Chunk(ctx context.Context, content *models.NormalizedContent) ([]*models.ContentChunk, error) {
+	// Check if context is canceled
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	chunks := splitTokens(content, 0, len(content.Text), c.config, StrategyToken)
+	return finish(content, chunks, c.config.Tokenizer), nil
+}
+
+// splitTokens cuts the range start to end of the content's text into
+// windows of at most config.MaxTokens tokens overlapping by config.Overlap.
+// A window is shrunk until the tokenizer counts no more than MaxTokens in
+// its text, since tokenizing a window on its own can differ from
+// tokenizing it in context.
+func splitTokens(content *models.NormalizedContent, start, end int, config Config, strategy string) []*models.ContentChunk {
+	text := content.Text
+	tokens := config.Tokenizer.Tokenize(text[start:end])
+
+	var chunks []*models.ContentChunk
+	for i := 0; i < len(tokens); {
+		j := min(i+config.MaxTokens, len(tokens))
+		var chunk *models.ContentChunk
+		for {
+			windowEnd := end
+			if j < len(tokens) {
+				windowEnd = runeStart(text, start+tokens[j-1].End)
+			}
+			chunk = newChunk(content, runeStart(text, start+tokens[i].Start), windowEnd, strategy)
+			if chunk == nil || j == i+1 || config.Tokenizer.Count(chunk.Text) <= config.MaxTokens {
+				break
+			}
+			j--
+		}
+		if chunk != nil {
+			chunks = append(chunks, chunk)
+		}
+
+		if j >= len(tokens) {
+			break
+		}
+		i = max(j-config.Overlap, i+1)
+	}
+	return chunks
+}
@@ -0,0 +1,54 @@
+package chunker
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token is the byte range of a token in the text it was read from
+type Token struct {
+	Start int
+	End   int
+}
+
+// Tokenizer splits text into the tokens chunk sizes are measured in.
+// Implementations must be safe for concurrent use.
+type Tokenizer interface {
+	// Tokenize returns the tokens of text in order. Tokens need not cover
+	// the whitespace between them.
+	Tokenize(text string) []Token
+	// Count returns the number of tokens in text
+	Count(text string) int
+}
+
+// WhitespaceTokenizer treats every run of non-space characters as a token.
+// It needs no vocabulary and is the fallback when no BPE vocabulary is
+// configured.
+type WhitespaceTokenizer struct{}
+
+// Tokenize returns the runs of non-space characters of text
+func (WhitespaceTokenizer) Tokenize(text string) []Token {
+	var tokens []Token
+	start := -1
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				tokens = append(tokens, Token{Start: start, End: i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+		i += size
+	}
+	if start >= 0 {
+		tokens = append(tokens, Token{Start: start, End: len(text)})
+	}
+	return tokens
+}
+
+// Count returns the number of runs of non-space characters in text
+func (t WhitespaceTokenizer) Count(text string) int {
+	return len(t.Tokenize(text))
+}
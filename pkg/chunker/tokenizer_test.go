@@ -0,0 +1,60 @@
+package chunker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func tokenStrings(text string, tokens []Token) []string {
+	var out []string
+	for _, token := range tokens {
+		out = append(out, text[token.Start:token.End])
+	}
+	return out
+}
+
+func TestWhitespaceTokenizer(t *testing.T) {
+	text := "  one two three\n\nfour "
+	got := tokenStrings(text, WhitespaceTokenizer{}.Tokenize(text))
+	if want := []string{"one", "two", "three", "four"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize() = %q, want %q", got, want)
+	}
+}
+
+func TestPretokenize(t *testing.T) {
+	text := "Hello world's  test 12345!\n\n(ok)  "
+	got := tokenStrings(text, pretokenize(text))
+	want := []string{"Hello", " world", "'s", " ", " test", " ", "123", "45", "!\n\n", "(ok", ")", "  "}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pretokenize() = %q, want %q", got, want)
+	}
+}
+
+func TestBPETokenizer(t *testing.T) {
+	tokenizer, err := LoadBPETokenizer("testdata/tiny.tiktoken")
+	if err != nil {
+		t.Fatalf("Failed to load vocabulary: %v", err)
+	}
+
+	text := "the chunk and the token thing é"
+	got := tokenStrings(text, tokenizer.Tokenize(text))
+	want := []string{"the", " chunk", " and", " the", " token", " ", "th", "ing", " ", "\xc3", "\xa9"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize() = %q, want %q", got, want)
+	}
+	if tokenizer.Count(text) != len(want) {
+		t.Errorf("Count() = %d, want %d", tokenizer.Count(text), len(want))
+	}
+}
+
+func TestNewBPETokenizerErrors(t *testing.T) {
+	for _, vocab := range []string{"", "dGhl\n", "!!! 1\n", "dGhl x\n"} {
+		if _, err := NewBPETokenizer(strings.NewReader(vocab)); err == nil {
+			t.Errorf("NewBPETokenizer(%q) should fail", vocab)
+		}
+	}
+	if _, err := LoadBPETokenizer("testdata/missing.tiktoken"); err == nil {
+		t.Error("LoadBPETokenizer should fail for a missing file")
+	}
+}
@@ -0,0 +1,287 @@
+package dedup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// MetaURLs is set by ContentEmbedder on every chunk to the sorted URLs its
+// text was seen at
+const MetaURLs = "urls"
+
+func init() {
+	models.RegisterMetadataType[[]string](MetaURLs)
+}
+
+// ContentKey returns the key of text embedded by a model and version. Runs
+// of whitespace are collapsed, so copies that differ only in spacing have
+// the same key.
+func ContentKey(model, version, text string) string {
+	return models.ContentHash(model + "\x00" + version + "\x00" + strings.Join(strings.Fields(text), " "))
+}
+
+// ContentEntry records the embedding of a text and every URL it was seen at
+type ContentEntry struct {
+	EmbeddingID string   `json:"embedding_id"`
+	ChunkID     string   `json:"chunk_id"`
+	Model       string   `json:"model"`
+	Version     string   `json:"version"`
+	URLs        []string `json:"urls"`
+}
+
+// ContentIndex maps the ContentKey of embedded texts to their embeddings. It is
+// loaded from a JSON file on creation and saved there by Save, and is safe
+// for concurrent use.
+type ContentIndex struct {
+	mu      sync.RWMutex
+	path    string
+	entries map[string]*ContentEntry
+}
+
+// NewContentIndex creates a ContentIndex persisted at path, loading the
+// entries saved there before. Without a path it is kept in memory only.
+func NewContentIndex(path string) (*ContentIndex, error) {
+	x := &ContentIndex{path: path, entries: make(map[string]*ContentEntry)}
+	if path == "" {
+		return x, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return x, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content index: %w", err)
+	}
+	if err := json.Unmarshal(data, &x.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse content index: %w", err)
+	}
+	return x, nil
+}
+
+// Len returns the number of distinct texts in the index
+func (x *ContentIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.entries)
+}
+
+// Lookup returns a copy of the entry for key, if any
+func (x *ContentIndex) Lookup(key string) (ContentEntry, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	entry, ok := x.entries[key]
+	if !ok {
+		return ContentEntry{}, false
+	}
+	copied := *entry
+	copied.URLs = append([]string(nil), entry.URLs...)
+	return copied, true
+}
+
+// Add records that the text with the given key was embedded as e, and adds
+// the URL of e's chunk to its entry. An entry of another embedding takes
+// e's embedding and keeps its URLs. Add returns the URLs of the entry.
+func (x *ContentIndex) Add(key string, e *models.VectorEmbedding) []string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	entry, ok := x.entries[key]
+	if !ok {
+		entry = &ContentEntry{}
+		x.entries[key] = entry
+	}
+	entry.EmbeddingID, entry.Model, entry.Version = e.ID, e.Model, e.Version
+	entry.ChunkID = ""
+	if e.Chunk != nil {
+		entry.ChunkID = e.Chunk.ID
+		entry.addURL(e.Chunk.Source)
+	}
+	return append([]string(nil), entry.URLs...)
+}
+
+// AddURL adds url to the entry of the text with the given key, if any, and
+// returns the URLs of the entry
+func (x *ContentIndex) AddURL(key, url string) []string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	entry, ok := x.entries[key]
+	if !ok {
+		return nil
+	}
+	entry.addURL(url)
+	return append([]string(nil), entry.URLs...)
+}
+
+// Save writes the index to its path. It does nothing when the index has no
+// path.
+func (x *ContentIndex) Save() error {
+	if x.path == "" {
+		return nil
+	}
+
+	x.mu.RLock()
+	data, err := json.Marshal(x.entries)
+	x.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode content index: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(x.path), 0755); err != nil {
+		return fmt.Errorf("failed to create content index directory: %w", err)
+	}
+	// Write to a temporary file first so a crash cannot truncate the index
+	tmp := x.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write content index: %w", err)
+	}
+	if err := os.Rename(tmp, x.path); err != nil {
+		return fmt.Errorf("failed to write content index: %w", err)
+	}
+	return nil
+}
+
+// Close saves the index
+func (x *ContentIndex) Close() error {
+	return x.Save()
+}
+
+// addURL adds url to the entry, keeping its URLs sorted and distinct. The
+// caller must hold the write lock.
+func (e *ContentEntry) addURL(url string) {
+	if url == "" {
+		return
+	}
+	i := sort.SearchStrings(e.URLs, url)
+	if i < len(e.URLs) && e.URLs[i] == url {
+		return
+	}
+	e.URLs = append(e.URLs, "")
+	copy(e.URLs[i+1:], e.URLs[i:])
+	e.URLs[i] = url
+}
+
+// ContentEmbedder wraps a models.Embedder so that text already embedded is
+// not embedded again. It returns an embedding of its own for every chunk,
+// like any embedder, but a chunk whose text is in its ContentIndex gets the
+// stored vector of that text instead of a new one. Every chunk lists the
+// URLs its text was seen at under MetaURLs.
+type ContentEmbedder struct {
+	embedder models.VersionedEmbedder
+	index    *ContentIndex
+	storage  models.LookupStorage
+}
+
+// NewContentEmbedder creates a ContentEmbedder that embeds new text with
+// embedder, records it in index and looks up the embeddings of text seen
+// before in storage. Text is keyed by the model and version of embedder,
+// so entries of another model are never reused.
+func NewContentEmbedder(embedder models.VersionedEmbedder, index *ContentIndex, storage models.LookupStorage) (*ContentEmbedder, error) {
+	if embedder == nil || index == nil || storage == nil {
+		return nil, fmt.Errorf("content embedder requires an embedder, an index and a storage")
+	}
+	return &ContentEmbedder{embedder: embedder, index: index, storage: storage}, nil
+}
+
+// Embed returns the embedding of every chunk, in order. A chunk whose
+// embedding text is in the index and whose embedding is still stored, or
+// repeats a text earlier in chunks, gets that embedding's vector under the
+// ID of its own chunk. Only the remaining chunks are passed to the wrapped
+// embedder.
+func (e *ContentEmbedder) Embed(ctx context.Context, chunks []*models.ContentChunk) ([]*models.VectorEmbedding, error) {
+	model, version := e.embedder.Model()
+	keys := make([]string, len(chunks))
+	var ids []string
+	for i, chunk := range chunks {
+		keys[i] = ContentKey(model, version, chunk.EmbeddingText())
+		if entry, ok := e.index.Lookup(keys[i]); ok {
+			ids = append(ids, entry.EmbeddingID)
+		}
+	}
+
+	// sources holds the embedding shared by the chunks of each key
+	sources := make(map[string]*models.VectorEmbedding)
+	if len(ids) > 0 {
+		stored, err := e.storage.Get(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up stored embeddings: %w", err)
+		}
+		byID := make(map[string]*models.VectorEmbedding, len(stored))
+		for _, s := range stored {
+			byID[s.ID] = s
+		}
+		for _, key := range keys {
+			if entry, ok := e.index.Lookup(key); ok && byID[entry.EmbeddingID] != nil {
+				sources[key] = byID[entry.EmbeddingID]
+			}
+		}
+	}
+
+	var fresh []*models.ContentChunk
+	var freshAt []int
+	pending := make(map[string]bool)
+	for i, chunk := range chunks {
+		if sources[keys[i]] != nil || pending[keys[i]] {
+			continue
+		}
+		pending[keys[i]] = true
+		fresh = append(fresh, chunk)
+		freshAt = append(freshAt, i)
+	}
+	var embedded []*models.VectorEmbedding
+	if len(fresh) > 0 {
+		var err error
+		embedded, err = e.embedder.Embed(ctx, fresh)
+		if err != nil {
+			return nil, err
+		}
+		if len(embedded) != len(fresh) {
+			return nil, fmt.Errorf("embedder returned %d embeddings for %d chunks", len(embedded), len(fresh))
+		}
+	}
+
+	embeddings := make([]*models.VectorEmbedding, len(chunks))
+	for n, i := range freshAt {
+		embeddings[i] = embedded[n]
+		sources[keys[i]] = embedded[n]
+	}
+	for i, chunk := range chunks {
+		embedding := embeddings[i]
+		var urls []string
+		if embedding != nil {
+			urls = e.index.Add(keys[i], embedding)
+		} else {
+			source := sources[keys[i]]
+			embedding = &models.VectorEmbedding{
+				Chunk:   chunk,
+				ID:      models.EmbeddingID(chunk.ID, source.Model, source.Version),
+				Model:   source.Model,
+				Version: source.Version,
+				Vector:  source.Vector,
+			}
+			urls = e.index.AddURL(keys[i], chunk.Source)
+		}
+		if chunk.Metadata == nil {
+			chunk.Metadata = make(map[string]interface{})
+		}
+		chunk.Metadata[MetaURLs] = urls
+		embeddings[i] = embedding
+	}
+	return embeddings, nil
+}
+
+// Close saves the index
+func (e *ContentEmbedder) Close() error {
+	return e.index.Close()
+}
@@ -0,0 +1,200 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/chunker"
+	"github.com/ncolesummers/scrape-pipeline/pkg/storage"
+)
+
+// countingEmbedder records the chunks it is asked to embed
+type countingEmbedder struct {
+	version  string
+	embedded []string
+	err      error
+}
+
+func (e *countingEmbedder) Model() (string, string) {
+	return "test", e.version
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, chunks []*models.ContentChunk) ([]*models.VectorEmbedding, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	embeddings := make([]*models.VectorEmbedding, len(chunks))
+	for i, chunk := range chunks {
+		e.embedded = append(e.embedded, chunk.ID)
+		embeddings[i] = &models.VectorEmbedding{
+			Chunk:   chunk,
+			ID:      models.EmbeddingID(chunk.ID, "test", e.version),
+			Model:   "test",
+			Version: e.version,
+			Vector:  []float32{float32(len(e.embedded))},
+		}
+	}
+	return embeddings, nil
+}
+
+// embedAndStore embeds chunks and stores the embeddings, as the pipeline does
+func embedAndStore(t *testing.T, e *ContentEmbedder, s models.VectorStorage, chunks ...*models.ContentChunk) []*models.VectorEmbedding {
+	t.Helper()
+	embeddings, err := e.Embed(context.Background(), chunks)
+	if err != nil {
+		t.Fatalf("Failed to embed: %v", err)
+	}
+	if len(embeddings) != len(chunks) {
+		t.Fatalf("Expected %d embeddings, got %d", len(chunks), len(embeddings))
+	}
+	for i, embedding := range embeddings {
+		if embedding.Chunk != chunks[i] || len(embedding.Vector) == 0 {
+			t.Fatalf("Embedding %d = %+v, want a vector for chunk %s", i, embedding, chunks[i].ID)
+		}
+	}
+	if err := s.Store(context.Background(), embeddings); err != nil {
+		t.Fatalf("Failed to store: %v", err)
+	}
+	return embeddings
+}
+
+func TestContentEmbedder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "content.json")
+	index, err := NewContentIndex(path)
+	if err != nil {
+		t.Fatalf("Failed to create content index: %v", err)
+	}
+	store := storage.NewMemoryStorage()
+	inner := &countingEmbedder{version: "1"}
+	e, err := NewContentEmbedder(inner, index, store)
+	if err != nil {
+		t.Fatalf("Failed to create content embedder: %v", err)
+	}
+
+	chunks := []*models.ContentChunk{
+		{ID: "a1", Text: "Shared paragraph.", Source: "https://a.example/post"},
+		{ID: "a2", Text: "Only on site A.", Source: "https://a.example/post"},
+		{ID: "b1", Text: "Shared   paragraph.\n", Source: "https://b.example/copy"},
+	}
+	embeddings := embedAndStore(t, e, store, chunks...)
+	if !reflect.DeepEqual(inner.embedded, []string{"a1", "a2"}) {
+		t.Fatalf("Expected only a1 and a2 to be embedded, got %v", inner.embedded)
+	}
+	want := models.EmbeddingID("a1", "test", "1")
+	if embeddings[0].ID != want || embeddings[2].ID != models.EmbeddingID("b1", "test", "1") || embeddings[2].Vector[0] != embeddings[0].Vector[0] {
+		t.Errorf("Expected the repeat to reuse the vector of %s under its own ID, got %+v", want, embeddings[2])
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Failed to save content index: %v", err)
+	}
+
+	// A later run reuses the saved embeddings and tracks the new URL
+	index, err = NewContentIndex(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen content index: %v", err)
+	}
+	e, _ = NewContentEmbedder(inner, index, store)
+	c := &models.ContentChunk{ID: "c1", Text: "Shared paragraph.", Source: "https://c.example/again"}
+	embeddings = embedAndStore(t, e, store, c)
+	if len(inner.embedded) != 2 || embeddings[0].ID != models.EmbeddingID("c1", "test", "1") || embeddings[0].Vector[0] != 1 {
+		t.Fatalf("Expected the stored vector to be reused, got %+v after embedding %v", embeddings[0], inner.embedded)
+	}
+	wantURLs := []string{"https://a.example/post", "https://b.example/copy", "https://c.example/again"}
+	if !reflect.DeepEqual(c.Metadata[MetaURLs], wantURLs) {
+		t.Errorf("Expected the chunk to list every URL, got %v", c.Metadata[MetaURLs])
+	}
+	entry, ok := index.Lookup(ContentKey("test", "1", c.EmbeddingText()))
+	if !ok {
+		t.Fatal("Expected the shared text in the index")
+	}
+	if entry.EmbeddingID != want || entry.ChunkID != "a1" || !reflect.DeepEqual(entry.URLs, wantURLs) {
+		t.Errorf("Entry = %+v", entry)
+	}
+	if index.Len() != 2 {
+		t.Errorf("Expected 2 distinct texts, got %d", index.Len())
+	}
+}
+
+func TestContentEmbedderKeepsEveryChunk(t *testing.T) {
+	index, _ := NewContentIndex("")
+	store := storage.NewMemoryStorage()
+	e, _ := NewContentEmbedder(&countingEmbedder{version: "1"}, index, store)
+	ctx := context.Background()
+
+	// Two hierarchical documents share a child chunk
+	parentA := &models.ContentChunk{ID: "pa", Text: "Post A, in full.", Source: "https://a.example/post"}
+	childA := &models.ContentChunk{ID: "ca", Text: "Shared paragraph.", Source: "https://a.example/post",
+		Metadata: map[string]interface{}{chunker.MetaParentID: "pa"}}
+	parentB := &models.ContentChunk{ID: "pb", Text: "Post B, in full.", Source: "https://b.example/post"}
+	childB := &models.ContentChunk{ID: "cb", Text: "Shared paragraph.", Source: "https://b.example/post",
+		Metadata: map[string]interface{}{chunker.MetaParentID: "pb"}}
+	embedAndStore(t, e, store, parentA, childA)
+	embedAndStore(t, e, store, parentB, childB)
+
+	for _, child := range []*models.ContentChunk{childA, childB} {
+		stored, err := store.Get(ctx, []string{models.EmbeddingID(child.ID, "test", "1")})
+		if err != nil || len(stored) != 1 || stored[0].Chunk.Source != child.Source {
+			t.Errorf("Expected %s stored with its own source, got %v, %v", child.ID, stored, err)
+		}
+		parent, err := store.Parent(ctx, child.ID)
+		if err != nil || parent == nil || parent.Chunk.ID != child.Metadata[chunker.MetaParentID] {
+			t.Errorf("Expected the parent of %s, got %v, %v", child.ID, parent, err)
+		}
+	}
+}
+
+func TestContentEmbedderKeys(t *testing.T) {
+	index, _ := NewContentIndex("")
+	store := storage.NewMemoryStorage()
+	inner := &countingEmbedder{version: "1"}
+	e, _ := NewContentEmbedder(inner, index, store)
+	embedAndStore(t, e, store, &models.ContentChunk{ID: "a", Text: "Same text."})
+
+	// The same text under another context header is embedded again
+	embedAndStore(t, e, store, &models.ContentChunk{ID: "b", Text: "Same text.", Context: "Other post"})
+	// So is text whose embedding was deleted from storage
+	store.Delete(context.Background(), []string{models.EmbeddingID("a", "test", "1")})
+	embedAndStore(t, e, store, &models.ContentChunk{ID: "c", Text: "Same text."})
+	// And text embedded by another model version
+	inner.version = "2"
+	embedAndStore(t, e, store, &models.ContentChunk{ID: "d", Text: "Same text."})
+
+	if want := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(inner.embedded, want) {
+		t.Errorf("Embedded %v, want %v", inner.embedded, want)
+	}
+}
+
+func TestContentEmbedderError(t *testing.T) {
+	index, _ := NewContentIndex("")
+	store := storage.NewMemoryStorage()
+	e, _ := NewContentEmbedder(&countingEmbedder{err: errors.New("quota exceeded")}, index, store)
+
+	if _, err := e.Embed(context.Background(), []*models.ContentChunk{{ID: "a", Text: "text"}}); err == nil {
+		t.Fatal("Expected the embedder error")
+	}
+	if index.Len() != 0 {
+		t.Error("Text that failed to embed must not be indexed")
+	}
+	if _, err := NewContentEmbedder(nil, index, store); err == nil {
+		t.Error("Expected an error without an embedder")
+	}
+	if _, err := NewContentEmbedder(&countingEmbedder{}, index, nil); err == nil {
+		t.Error("Expected an error without a storage")
+	}
+}
+
+func TestContentKey(t *testing.T) {
+	if ContentKey("m", "1", "a  b\n") != ContentKey("m", "1", "a b") {
+		t.Error("Whitespace should not change the key")
+	}
+	if ContentKey("m", "1", "a b") == ContentKey("m", "1", "A b") {
+		t.Error("Case should change the key")
+	}
+	if ContentKey("m", "1", "a b") == ContentKey("m", "2", "a b") {
+		t.Error("The model version should change the key")
+	}
+}
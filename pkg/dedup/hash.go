@@ -0,0 +1,121 @@
+package dedup
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// shingles returns the hashes of the distinct runs of size consecutive
+// words of text, compared case-insensitively and ignoring punctuation.
+// Texts shorter than size words have their whole text as one shingle.
+func shingles(text string, size int) []uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return nil
+	}
+	if len(words) < size {
+		size = len(words)
+	}
+
+	seen := make(map[uint64]bool)
+	var hashes []uint64
+	for i := 0; i+size <= len(words); i++ {
+		h := fnv.New64a()
+		for j, word := range words[i : i+size] {
+			if j > 0 {
+				h.Write([]byte{' '})
+			}
+			h.Write([]byte(word))
+		}
+		sum := h.Sum64()
+		if !seen[sum] {
+			seen[sum] = true
+			hashes = append(hashes, sum)
+		}
+	}
+	return hashes
+}
+
+// mix is the splitmix64 finalizer, a fast bijective scrambling of x
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// seeds returns n fixed seeds, one per MinHash function
+func seeds(n int) []uint64 {
+	s := make([]uint64, n)
+	state := uint64(0x9e3779b97f4a7c15)
+	for i := range s {
+		state += 0x9e3779b97f4a7c15
+		s[i] = mix(state)
+	}
+	return s
+}
+
+// minHash returns the MinHash signature of a set of shingles: for every
+// seed, the minimum over the shingles of the shingle hash scrambled with
+// the seed
+func minHash(shingles []uint64, seeds []uint64) []uint32 {
+	signature := make([]uint32, len(seeds))
+	for i := range signature {
+		signature[i] = ^uint32(0)
+	}
+	for _, s := range shingles {
+		for i, seed := range seeds {
+			if v := uint32(mix(s ^ seed)); v < signature[i] {
+				signature[i] = v
+			}
+		}
+	}
+	return signature
+}
+
+// jaccard estimates the Jaccard similarity of the shingle sets of two
+// signatures as the share of positions where they agree
+func jaccard(a, b []uint32) float64 {
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(a))
+}
+
+// simHash returns the 64-bit SimHash of a set of shingles: bit i is set
+// when more shingle hashes have bit i set than not, so similar sets get
+// fingerprints that differ in few bits
+func simHash(shingles []uint64) uint64 {
+	var counts [64]int
+	for _, s := range shingles {
+		h := mix(s)
+		for i := range counts {
+			if h&(1<<i) != 0 {
+				counts[i]++
+			} else {
+				counts[i]--
+			}
+		}
+	}
+	var fingerprint uint64
+	for i, c := range counts {
+		if c > 0 {
+			fingerprint |= 1 << i
+		}
+	}
+	return fingerprint
+}
+
+// hamming returns the number of bits in which a and b differ
+func hamming(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
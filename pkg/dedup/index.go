@@ -0,0 +1,400 @@
+// Package dedup finds near-duplicate text, such as syndicated and
+// cross-posted articles, among previously seen chunks. Each chunk is
+// reduced to word shingles and indexed two ways: a MinHash signature in a
+// locality-sensitive hashing (LSH) index, which estimates the Jaccard
+// similarity of shingle sets, and a 64-bit SimHash fingerprint in
+// permuted block tables, which catches copies that differ in a few bits.
+// Lookups only compare a query with the chunks sharing an LSH band or a
+// fingerprint block, so they stay fast on large corpora.
+//
+// For exact copies, a ContentIndex maps the hash of each chunk text to its
+// embedding and the URLs carrying it, and a ContentEmbedder uses it to skip
+// text embedded before.
+package dedup
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// MetaDuplicateOf is set by IsDuplicate on a duplicate chunk to the ID of
+// the chunk it duplicates
+const MetaDuplicateOf = "duplicate_of"
+
+// Methods that can find a match
+const (
+	MethodMinHash = "minhash"
+	MethodSimHash = "simhash"
+)
+
+// fileVersion changes whenever the format of saved indexes does
+const fileVersion = 1
+
+// Config holds configuration for an Index. A chunk is a duplicate when
+// either signal says so: its estimated Jaccard similarity reaches Threshold,
+// or its SimHash fingerprint differs in at most SimHashDistance bits. The
+// two are kept apart since they are on different scales.
+type Config struct {
+	// Threshold is the estimated Jaccard similarity of shingle sets,
+	// between 0 and 1, from which a chunk is a duplicate
+	Threshold float64
+	// ShingleSize is the number of words in a shingle
+	ShingleSize int
+	// Bands and Rows shape the LSH index: signatures have Bands*Rows
+	// values, and two chunks are compared when all Rows values of a band
+	// agree. Chunks with a Jaccard similarity s share a band with
+	// probability 1-(1-s^Rows)^Bands.
+	Bands int
+	Rows  int
+	// SimHashDistance is the largest number of differing fingerprint bits
+	// for a SimHash match. It is negative to disable SimHash.
+	SimHashDistance int
+	// Path is the file the index is loaded from and saved to. The index is
+	// kept in memory only when it is empty.
+	Path string
+}
+
+// DefaultConfig returns the configuration used when none is given
+func DefaultConfig() Config {
+	return Config{
+		Threshold:       0.85,
+		ShingleSize:     5,
+		Bands:           16,
+		Rows:            8,
+		SimHashDistance: 3,
+	}
+}
+
+// Match is the most similar indexed chunk found for a text
+type Match struct {
+	ID string
+	// Similarity is the estimated Jaccard similarity of the shingle sets
+	Similarity float64
+	// Distance is the number of differing SimHash fingerprint bits
+	Distance int
+	// Method is MethodMinHash or MethodSimHash, the signal that makes the
+	// match a duplicate, or empty when it is not one
+	Method string
+}
+
+// Index holds the MinHash signatures and SimHash fingerprints of chunks.
+// It implements quality.DuplicateDetector and is safe for concurrent use.
+type Index struct {
+	mu     sync.RWMutex
+	config Config
+	seeds  []uint64
+
+	ids  []string
+	byID map[string]int32
+	// signatures holds the MinHash signature of every chunk, one after
+	// another, to save the overhead of a slice per chunk
+	signatures   []uint32
+	fingerprints []uint64
+
+	bands  []map[uint64][]int32
+	blocks []map[uint64][]int32
+}
+
+// NewIndex creates an Index, loading it from config.Path when that file
+// exists
+func NewIndex(config Config) (*Index, error) {
+	if config.Threshold < 0 || config.Threshold > 1 {
+		return nil, fmt.Errorf("duplicate threshold must be between 0 and 1, got %g", config.Threshold)
+	}
+	if config.ShingleSize <= 0 || config.Bands <= 0 || config.Rows <= 0 {
+		return nil, fmt.Errorf("shingle size, bands and rows must be positive")
+	}
+	if config.SimHashDistance >= 63 {
+		return nil, fmt.Errorf("simhash distance must be less than 63, got %d", config.SimHashDistance)
+	}
+
+	x := &Index{
+		config: config,
+		seeds:  seeds(config.Bands * config.Rows),
+		byID:   make(map[string]int32),
+		bands:  make([]map[uint64][]int32, config.Bands),
+	}
+	for i := range x.bands {
+		x.bands[i] = make(map[uint64][]int32)
+	}
+	if config.SimHashDistance >= 0 {
+		x.blocks = make([]map[uint64][]int32, config.SimHashDistance+1)
+		for i := range x.blocks {
+			x.blocks[i] = make(map[uint64][]int32)
+		}
+	}
+
+	if config.Path != "" {
+		if err := x.load(); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+// Len returns the number of indexed chunks
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.ids)
+}
+
+// Add indexes text under id. Texts without words and IDs already indexed
+// are ignored.
+func (x *Index) Add(id, text string) {
+	sh := shingles(text, x.config.ShingleSize)
+	if len(sh) == 0 {
+		return
+	}
+	signature := minHash(sh, x.seeds)
+	fingerprint := simHash(sh)
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.add(id, signature, fingerprint)
+}
+
+// Find returns the indexed chunk most similar to text, other than the one
+// indexed under exclude, and whether there is any candidate at all.
+// Duplicates come before other matches, which have an empty Method.
+func (x *Index) Find(text, exclude string) (Match, bool) {
+	sh := shingles(text, x.config.ShingleSize)
+	if len(sh) == 0 {
+		return Match{}, false
+	}
+	signature := minHash(sh, x.seeds)
+	fingerprint := simHash(sh)
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.find(signature, fingerprint, exclude)
+}
+
+// IsDuplicate reports whether chunk duplicates an indexed chunk, and the
+// estimated Jaccard similarity of the most similar one. A duplicate
+// gets the ID of that chunk under MetaDuplicateOf; any other chunk is
+// indexed, so later copies of it are found.
+func (x *Index) IsDuplicate(ctx context.Context, chunk *models.ContentChunk) (bool, float64, error) {
+	// Check if context is canceled
+	select {
+	case <-ctx.Done():
+		return false, 0, ctx.Err()
+	default:
+	}
+
+	sh := shingles(chunk.Text, x.config.ShingleSize)
+	if len(sh) == 0 {
+		return false, 0, nil
+	}
+	signature := minHash(sh, x.seeds)
+	fingerprint := simHash(sh)
+
+	// The lookup and the insertion happen under one lock, so two copies
+	// checked at the same time cannot both be taken for originals
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	match, ok := x.find(signature, fingerprint, chunk.ID)
+	if ok && match.Method != "" {
+		if chunk.Metadata == nil {
+			chunk.Metadata = make(map[string]interface{})
+		}
+		chunk.Metadata[MetaDuplicateOf] = match.ID
+		return true, match.Similarity, nil
+	}
+	x.add(chunk.ID, signature, fingerprint)
+	return false, match.Similarity, nil
+}
+
+// add indexes a signature and fingerprint under id. The caller must hold
+// the write lock.
+func (x *Index) add(id string, signature []uint32, fingerprint uint64) {
+	if _, ok := x.byID[id]; ok {
+		return
+	}
+	n := int32(len(x.ids))
+	x.ids = append(x.ids, id)
+	x.byID[id] = n
+	x.signatures = append(x.signatures, signature...)
+	x.fingerprints = append(x.fingerprints, fingerprint)
+
+	for band := range x.bands {
+		key := x.bandKey(signature, band)
+		x.bands[band][key] = append(x.bands[band][key], n)
+	}
+	for block := range x.blocks {
+		key := x.blockKey(fingerprint, block)
+		x.blocks[block][key] = append(x.blocks[block][key], n)
+	}
+}
+
+// find compares a signature and fingerprint with the chunks sharing a band
+// or block with them. The caller must hold the lock.
+func (x *Index) find(signature []uint32, fingerprint uint64, exclude string) (Match, bool) {
+	candidates := make(map[int32]bool)
+	for band := range x.bands {
+		for _, n := range x.bands[band][x.bandKey(signature, band)] {
+			candidates[n] = true
+		}
+	}
+	for block := range x.blocks {
+		for _, n := range x.blocks[block][x.blockKey(fingerprint, block)] {
+			candidates[n] = true
+		}
+	}
+
+	var best Match
+	found := false
+	size := len(x.seeds)
+	for n := range candidates {
+		id := x.ids[n]
+		if id == exclude {
+			continue
+		}
+		m := Match{
+			ID:         id,
+			Similarity: jaccard(signature, x.signatures[int(n)*size:int(n+1)*size]),
+			Distance:   hamming(fingerprint, x.fingerprints[n]),
+		}
+		switch {
+		case m.Similarity >= x.config.Threshold:
+			m.Method = MethodMinHash
+		case x.blocks != nil && m.Distance <= x.config.SimHashDistance:
+			m.Method = MethodSimHash
+		}
+		if !found || better(m, best) {
+			best, found = m, true
+		}
+	}
+	return best, found
+}
+
+// better reports whether a is a better match than b: duplicates first, then
+// the most similar, the closest fingerprint and, so results do not depend on
+// map order, the earlier ID
+func better(a, b Match) bool {
+	if (a.Method != "") != (b.Method != "") {
+		return a.Method != ""
+	}
+	if a.Similarity != b.Similarity {
+		return a.Similarity > b.Similarity
+	}
+	if a.Distance != b.Distance {
+		return a.Distance < b.Distance
+	}
+	return a.ID < b.ID
+}
+
+// bandKey hashes the rows of a band of a signature
+func (x *Index) bandKey(signature []uint32, band int) uint64 {
+	key := uint64(band)
+	for _, v := range signature[band*x.config.Rows : (band+1)*x.config.Rows] {
+		key = mix(key ^ uint64(v))
+	}
+	return key
+}
+
+// blockKey returns one of the SimHashDistance+1 blocks of a fingerprint.
+// Two fingerprints that differ in at most SimHashDistance bits agree on at
+// least one block.
+func (x *Index) blockKey(fingerprint uint64, block int) uint64 {
+	n := len(x.blocks)
+	from, to := block*64/n, (block+1)*64/n
+	value := (fingerprint >> from) & (1<<(to-from) - 1)
+	return uint64(block)<<58 ^ value
+}
+
+// savedIndex is the format of saved indexes. The LSH and block tables are
+// rebuilt on load.
+type savedIndex struct {
+	Version      int
+	ShingleSize  int
+	Bands        int
+	Rows         int
+	IDs          []string
+	Signatures   []uint32
+	Fingerprints []uint64
+}
+
+// Save writes the index to config.Path. It does nothing when the index has
+// no path.
+func (x *Index) Save() error {
+	if x.config.Path == "" {
+		return nil
+	}
+
+	x.mu.RLock()
+	saved := savedIndex{
+		Version:      fileVersion,
+		ShingleSize:  x.config.ShingleSize,
+		Bands:        x.config.Bands,
+		Rows:         x.config.Rows,
+		IDs:          x.ids,
+		Signatures:   x.signatures,
+		Fingerprints: x.fingerprints,
+	}
+	defer x.mu.RUnlock()
+
+	if err := os.MkdirAll(filepath.Dir(x.config.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	// Write to a temporary file first so a crash cannot truncate the index
+	tmp := x.config.Path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to write duplicate index: %w", err)
+	}
+	if err := gob.NewEncoder(f).Encode(saved); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode duplicate index: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write duplicate index: %w", err)
+	}
+	if err := os.Rename(tmp, x.config.Path); err != nil {
+		return fmt.Errorf("failed to write duplicate index: %w", err)
+	}
+	return nil
+}
+
+// Close saves the index
+func (x *Index) Close() error {
+	return x.Save()
+}
+
+// load reads the index from config.Path, if it exists
+func (x *Index) load() error {
+	f, err := os.Open(x.config.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open duplicate index: %w", err)
+	}
+	defer f.Close()
+
+	var saved savedIndex
+	if err := gob.NewDecoder(f).Decode(&saved); err != nil {
+		return fmt.Errorf("failed to decode duplicate index: %w", err)
+	}
+	if saved.Version != fileVersion || saved.ShingleSize != x.config.ShingleSize ||
+		saved.Bands != x.config.Bands || saved.Rows != x.config.Rows {
+		return fmt.Errorf("duplicate index %s was built with other settings; delete it to rebuild", x.config.Path)
+	}
+	size := len(x.seeds)
+	if len(saved.Signatures) != len(saved.IDs)*size || len(saved.Fingerprints) != len(saved.IDs) {
+		return fmt.Errorf("duplicate index %s is corrupt", x.config.Path)
+	}
+
+	for i, id := range saved.IDs {
+		x.add(id, saved.Signatures[i*size:(i+1)*size], saved.Fingerprints[i])
+	}
+	return nil
+}
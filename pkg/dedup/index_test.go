@@ -0,0 +1,192 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+const article = "Syndicated posts appear on many blogs at once, often with a new title and a few words changed. " +
+	"Embedding every copy wastes money and fills search results with the same passage, so the pipeline " +
+	"keeps the first copy it sees and marks the others as duplicates of it before they reach the embedder. " +
+	"Copies are rarely exact: a site adds its own byline, trims the closing paragraph or fixes a typo, " +
+	"so the check compares overlapping runs of words rather than whole texts and accepts small edits."
+
+// variant changes one word of article
+func variant() string {
+	return strings.Replace(article, "wastes money", "wastes time", 1)
+}
+
+func newIndex(t *testing.T, config Config) *Index {
+	t.Helper()
+	x, err := NewIndex(config)
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	return x
+}
+
+func TestIsDuplicate(t *testing.T) {
+	ctx := context.Background()
+	x := newIndex(t, DefaultConfig())
+
+	original := &models.ContentChunk{ID: "original", Text: article}
+	if dup, _, err := x.IsDuplicate(ctx, original); dup || err != nil {
+		t.Fatalf("The first copy is not a duplicate: %v, %v", dup, err)
+	}
+
+	copied := &models.ContentChunk{ID: "copy", Text: variant()}
+	dup, similarity, err := x.IsDuplicate(ctx, copied)
+	if err != nil || !dup {
+		t.Fatalf("Expected the edited copy to be a duplicate, got %v, %v, %v", dup, similarity, err)
+	}
+	if similarity < 0.85 || similarity >= 1 {
+		t.Errorf("Similarity = %v", similarity)
+	}
+	if copied.Metadata[MetaDuplicateOf] != "original" {
+		t.Errorf("Expected the duplicate to name the original, got %v", copied.Metadata)
+	}
+
+	unrelated := &models.ContentChunk{ID: "other", Text: "A recipe for tomato soup with basil, garlic and a little cream, simmered for twenty minutes."}
+	if dup, _, _ := x.IsDuplicate(ctx, unrelated); dup {
+		t.Error("Expected unrelated text not to be a duplicate")
+	}
+
+	// Rechecking an indexed chunk does not match it against itself
+	if dup, _, _ := x.IsDuplicate(ctx, original); dup {
+		t.Error("A chunk is not a duplicate of itself")
+	}
+	if x.Len() != 2 {
+		t.Errorf("Expected the original and unrelated chunks to be indexed, got %d", x.Len())
+	}
+}
+
+func TestFindMethods(t *testing.T) {
+	x := newIndex(t, DefaultConfig())
+	x.Add("original", article)
+
+	// An exact copy matches with similarity 1 and identical fingerprints
+	match, ok := x.Find(strings.ToUpper(article), "")
+	if !ok || match.ID != "original" || match.Similarity != 1 || match.Distance != 0 || match.Method != MethodMinHash {
+		t.Errorf("Find(exact copy) = %+v, %v", match, ok)
+	}
+
+	// MinHash alone, with SimHash disabled
+	config := DefaultConfig()
+	config.SimHashDistance = -1
+	x = newIndex(t, config)
+	x.Add("original", article)
+	match, ok = x.Find(variant(), "")
+	if !ok || match.Method != MethodMinHash || match.Similarity < 0.7 {
+		t.Errorf("Find(variant) = %+v, %v", match, ok)
+	}
+
+	if _, ok := x.Find("...", ""); ok {
+		t.Error("Expected no match for text without words")
+	}
+}
+
+func TestFindSignals(t *testing.T) {
+	config := DefaultConfig()
+	x := newIndex(t, config)
+	size := len(x.seeds)
+	signature := func(same int) []uint32 {
+		s := make([]uint32, size)
+		for i := range s {
+			if i >= same {
+				s[i] = uint32(i + 1)
+			}
+		}
+		return s
+	}
+	query := signature(size)
+
+	// Low Jaccard similarity with a fingerprint within SimHashDistance bits
+	// is a SimHash duplicate; the similarity is still the Jaccard estimate
+	x.add("near", signature(size/4), 0b111)
+	match, ok := x.find(query, 0, "")
+	if !ok || match.Method != MethodSimHash || match.Distance != 3 || match.Similarity != 0.25 {
+		t.Errorf("find() = %+v, %v", match, ok)
+	}
+
+	// A fingerprint a few more bits away is no duplicate, however high
+	// 1-distance/64 would be
+	x = newIndex(t, config)
+	x.add("far", signature(size/4), 0b1111111)
+	if match, _ := x.find(query, 0, ""); match.Method != "" {
+		t.Errorf("Expected no duplicate 7 bits away, got %+v", match)
+	}
+
+	// A MinHash duplicate is preferred over a closer fingerprint
+	x.add("similar", signature(size*9/10), ^uint64(0))
+	x.add("near", signature(0), 0b1)
+	if match, _ := x.find(query, 0, ""); match.ID != "similar" || match.Method != MethodMinHash {
+		t.Errorf("Expected the MinHash duplicate, got %+v", match)
+	}
+}
+
+func TestSimHash(t *testing.T) {
+	a := simHash(shingles(article, 5))
+	b := simHash(shingles(variant(), 5))
+	c := simHash(shingles("Completely different words about rockets, engines and liquid fuel at launch time.", 5))
+	if d := hamming(a, b); d > 16 {
+		t.Errorf("Near-duplicates differ in %d bits", d)
+	}
+	if hamming(a, c) <= hamming(a, b) {
+		t.Errorf("Unrelated text is closer (%d bits) than a near-duplicate (%d bits)", hamming(a, c), hamming(a, b))
+	}
+}
+
+func TestIndexPersistence(t *testing.T) {
+	config := DefaultConfig()
+	config.Path = filepath.Join(t.TempDir(), "dedup", "index.gob")
+
+	x := newIndex(t, config)
+	for i := 0; i < 50; i++ {
+		x.Add(fmt.Sprintf("filler-%d", i), fmt.Sprintf("Filler text number %d talks about topic %d in its own words.", i, i*7))
+	}
+	x.Add("original", article)
+	if err := x.Close(); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	reopened := newIndex(t, config)
+	if reopened.Len() != 51 {
+		t.Fatalf("Expected 51 chunks after reopening, got %d", reopened.Len())
+	}
+	match, ok := reopened.Find(variant(), "")
+	if !ok || match.ID != "original" {
+		t.Errorf("Find after reopening = %+v, %v", match, ok)
+	}
+
+	config.Rows = 4
+	if _, err := NewIndex(config); err == nil {
+		t.Error("Expected an error for an index saved with other settings")
+	}
+}
+
+func TestNewIndexInvalid(t *testing.T) {
+	for _, config := range []Config{{Threshold: 2, ShingleSize: 5, Bands: 1, Rows: 1}, {Threshold: 0.5}} {
+		if _, err := NewIndex(config); err == nil {
+			t.Errorf("NewIndex(%+v) should fail", config)
+		}
+	}
+}
+
+func BenchmarkFind(b *testing.B) {
+	x, err := NewIndex(DefaultConfig())
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 10000; i++ {
+		x.Add(fmt.Sprintf("chunk-%d", i), fmt.Sprintf("Chunk %d of the corpus covers subject %d and mentions item %d.", i, i%97, i%13))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.Find(article, "")
+	}
+}
@@ -0,0 +1,124 @@
+// Package embedding converts content chunks into vector embeddings.
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// HashModel is the model name recorded by HashEmbedder
+const HashModel = "feature-hash"
+
+// hashVersion changes whenever HashEmbedder computes different vectors
+const hashVersion = "1"
+
+// HashEmbedder implements the models.Embedder interface with feature
+// hashing: every lowercased word and pair of adjacent words is hashed to a
+// signed dimension of the vector, which is then scaled to unit length. It
+// needs no model or network access and always returns the same vector for
+// the same text, which makes it suitable for tests and offline runs, but it
+// only captures word overlap, not meaning.
+type HashEmbedder struct {
+	dimensions int
+}
+
+// NewHashEmbedder creates a HashEmbedder producing vectors with the given
+// number of dimensions
+func NewHashEmbedder(dimensions int) (*HashEmbedder, error) {
+	if dimensions <= 0 {
+		return nil, fmt.Errorf("dimensions must be positive, got %d", dimensions)
+	}
+	return &HashEmbedder{dimensions: dimensions}, nil
+}
+
+// Model returns HashModel and the version of the hashing scheme
+func (e *HashEmbedder) Model() (name, version string) {
+	return HashModel, hashVersion
+}
+
+// Embed returns the embedding of the embedding text of every chunk, in
+// order
+func (e *HashEmbedder) Embed(ctx context.Context, chunks []*models.ContentChunk) ([]*models.VectorEmbedding, error) {
+	embeddings := make([]*models.VectorEmbedding, len(chunks))
+	for i, chunk := range chunks {
+		// Check if context is canceled
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		embeddings[i] = &models.VectorEmbedding{
+			Chunk:   chunk,
+			ID:      models.EmbeddingID(chunk.ID, HashModel, hashVersion),
+			Model:   HashModel,
+			Version: hashVersion,
+			Vector:  e.vector(chunk.EmbeddingText()),
+		}
+	}
+	return embeddings, nil
+}
+
+// vector returns the unit-length feature hash of text
+func (e *HashEmbedder) vector(text string) []float32 {
+	vector := make([]float32, e.dimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	add := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		// The top bit picks the sign so unrelated features cancel out on
+		// average instead of accumulating
+		if sum>>63 == 1 {
+			vector[sum%uint64(e.dimensions)]--
+		} else {
+			vector[sum%uint64(e.dimensions)]++
+		}
+	}
+	for i, word := range words {
+		add(word)
+		if i > 0 {
+			add(words[i-1] + " " + word)
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return vector
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vector {
+		vector[i] *= scale
+	}
+	return vector
+}
+
+// CosineSimilarity returns the cosine of the angle between a and b, or 0
+// when either is a zero vector or their lengths differ
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
@@ -0,0 +1,82 @@
+package embedding
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+func TestHashEmbedder(t *testing.T) {
+	e, err := NewHashEmbedder(64)
+	if err != nil {
+		t.Fatalf("Failed to create embedder: %v", err)
+	}
+
+	chunks := []*models.ContentChunk{
+		{ID: "a", Text: "The cat sleeps on the mat."},
+		{ID: "b", Text: "the CAT sleeps on the mat"},
+		{ID: "c", Text: "Rocket engines burn liquid fuel."},
+		{ID: "d", Text: "..."},
+	}
+	embeddings, err := e.Embed(context.Background(), chunks)
+	if err != nil {
+		t.Fatalf("Failed to embed: %v", err)
+	}
+	if len(embeddings) != len(chunks) {
+		t.Fatalf("Expected %d embeddings, got %d", len(chunks), len(embeddings))
+	}
+
+	for i, emb := range embeddings {
+		if emb.Chunk != chunks[i] || len(emb.Vector) != 64 || emb.Model != HashModel {
+			t.Errorf("Embedding %d = %+v", i, emb)
+		}
+		if emb.ID != models.EmbeddingID(chunks[i].ID, HashModel, hashVersion) {
+			t.Errorf("Embedding %d has ID %q", i, emb.ID)
+		}
+	}
+
+	// Case and punctuation do not change the vector
+	if sim := CosineSimilarity(embeddings[0].Vector, embeddings[1].Vector); math.Abs(sim-1) > 1e-6 {
+		t.Errorf("Expected identical vectors, got similarity %v", sim)
+	}
+	if sim := CosineSimilarity(embeddings[0].Vector, embeddings[2].Vector); sim > 0.5 {
+		t.Errorf("Expected unrelated text to be dissimilar, got similarity %v", sim)
+	}
+	if sim := CosineSimilarity(embeddings[3].Vector, embeddings[0].Vector); sim != 0 {
+		t.Errorf("Expected a zero vector for text without words, got similarity %v", sim)
+	}
+
+	// Vectors are deterministic
+	again, _ := e.Embed(context.Background(), chunks[:1])
+	for j, v := range again[0].Vector {
+		if v != embeddings[0].Vector[j] {
+			t.Fatalf("Vector changed between runs at %d", j)
+		}
+	}
+}
+
+func TestNewHashEmbedderInvalid(t *testing.T) {
+	if _, err := NewHashEmbedder(0); err == nil {
+		t.Error("Expected an error for zero dimensions")
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b []float32
+		want float64
+	}{
+		{[]float32{1, 0}, []float32{1, 0}, 1},
+		{[]float32{1, 0}, []float32{0, 1}, 0},
+		{[]float32{1, 0}, []float32{-2, 0}, -1},
+		{[]float32{1, 0}, []float32{1}, 0},
+		{[]float32{0, 0}, []float32{1, 0}, 0},
+	}
+	for _, tt := range tests {
+		if got := CosineSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("CosineSimilarity(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
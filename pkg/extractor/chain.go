@@ -0,0 +1,183 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/htmlutil"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// Names of the strategies a ChainExtractor can be configured with
+const (
+	StrategySiteRules   = "site-rules"
+	StrategyReadability = "readability"
+	StrategyHeuristic   = "heuristic"
+)
+
+// DefaultFallbackChain runs the most precise strategy first
+var DefaultFallbackChain = []string{StrategySiteRules, StrategyReadability, StrategyHeuristic}
+
+// Strategy is a named extractor in a fallback chain
+type Strategy struct {
+	Extractor models.Extractor
+	Name      string
+}
+
+// ChainExtractor runs several extraction strategies over the same page,
+// scores every result and keeps the best one. The winning strategy and its
+// score are recorded on the extracted content.
+type ChainExtractor struct {
+	strategies []Strategy
+	templates  *TemplateStore
+}
+
+// NewChainExtractor creates a ChainExtractor running the strategies named
+// in config.FallbackChain, or DefaultFallbackChain when it is empty. The
+// strategies share config.Templates, which the chain closes.
+func NewChainExtractor(config Config) (*ChainExtractor, error) {
+	names := config.FallbackChain
+	if len(names) == 0 {
+		names = DefaultFallbackChain
+	}
+
+	builder := newContentBuilder(config)
+	builder.ownsTemplates = false
+	strategies := make([]Strategy, 0, len(names))
+	for _, name := range names {
+		var extractor models.Extractor
+		switch name {
+		case StrategySiteRules:
+			extractor = newSiteRuleExtractor(config, builder)
+		case StrategyReadability:
+			extractor = newReadabilityExtractor(config, builder)
+		case StrategyHeuristic:
+			extractor = newHeuristicExtractor(builder)
+		default:
+			return nil, fmt.Errorf("unknown extraction strategy %q", name)
+		}
+		strategies = append(strategies, Strategy{Name: name, Extractor: extractor})
+	}
+
+	chain := NewChainExtractorWithStrategies(strategies...)
+	chain.templates = config.Templates
+	return chain, nil
+}
+
+// NewChainExtractorWithStrategies creates a ChainExtractor from explicit strategies
+func NewChainExtractorWithStrategies(strategies ...Strategy) *ChainExtractor {
+	return &ChainExtractor{strategies: strategies}
+}
+
+// Close closes the strategies that implement io.Closer and the template
+// store, if any
+func (e *ChainExtractor) Close() error {
+	var errs []error
+	for _, strategy := range e.strategies {
+		if closer, ok := strategy.Extractor.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	if e.templates != nil {
+		errs = append(errs, e.templates.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Extract runs every strategy and returns the highest scoring result.
+// Strategy failures are only reported when no strategy succeeds.
+func (e *ChainExtractor) Extract(ctx context.Context, rawContent *models.RawContent) (*models.ExtractedContent, error) {
+	doc, err := html.Parse(strings.NewReader(rawContent.HTML))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	pageText := htmlutil.TextContent(doc)
+
+	var best *models.ExtractedContent
+	var errs []error
+	for _, strategy := range e.strategies {
+		content, err := strategy.Extractor.Extract(ctx, rawContent)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", strategy.Name, err))
+			continue
+		}
+
+		content.ExtractionStrategy = strategy.Name
+		content.ExtractionScore = ScoreExtraction(content, pageText)
+		if best == nil || content.ExtractionScore > best.ExtractionScore {
+			best = content
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("all extraction strategies failed: %w", errors.Join(errs...))
+	}
+	return best, nil
+}
+
+// Score weights. Length relative to the page matters most since the
+// typical failure is picking a comment section or sidebar, which is short
+// compared to the article.
+const (
+	textDensityWeight = 0.25
+	linkDensityWeight = 0.30
+	lengthWeight      = 0.45
+
+	// targetTextDensity is the share of text in the markup of a clean article
+	targetTextDensity = 0.5
+	// targetPageShare is the share of the page text a main article usually covers
+	targetPageShare = 0.4
+	// targetWords is the length from which a result counts as a full article
+	targetWords = 150
+)
+
+// ScoreExtraction rates an extraction result between 0 and 1 from its text
+// density (text versus markup), link density (text inside links versus all
+// text) and its length relative to the text of the whole page
+func ScoreExtraction(content *models.ExtractedContent, pageText string) float64 {
+	text := strings.Join(strings.Fields(content.Text), " ")
+	if text == "" {
+		return 0
+	}
+
+	textDensity := 1.0
+	if len(content.Content) > 0 {
+		textDensity = math.Min(1, float64(len(text))/float64(len(content.Content))/targetTextDensity)
+	}
+
+	linkScore := 1 - math.Min(1, 2*linkDensity(content.Content))
+
+	lengthScore := math.Min(1, float64(len(strings.Fields(text)))/targetWords)
+	if pageLen := len(pageText); pageLen > 0 {
+		lengthScore *= math.Min(1, float64(len(text))/float64(pageLen)/targetPageShare)
+	}
+
+	return textDensityWeight*textDensity + linkDensityWeight*linkScore + lengthWeight*lengthScore
+}
+
+// linkDensity returns the share of the text in an HTML fragment that sits
+// inside links
+func linkDensity(fragment string) float64 {
+	doc, err := html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		return 0
+	}
+	total := len(htmlutil.TextContent(doc))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	for _, link := range htmlutil.FindAll(doc, "a") {
+		linked += len(htmlutil.TextContent(link))
+	}
+	return math.Min(1, float64(linked)/float64(total))
+}
@@ -0,0 +1,126 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// stubExtractor returns fixed content or a fixed error
+type stubExtractor struct {
+	content *models.ExtractedContent
+	err     error
+}
+
+func (s *stubExtractor) Extract(ctx context.Context, rawContent *models.RawContent) (*models.ExtractedContent, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	copied := *s.content
+	return &copied, nil
+}
+
+func TestChainExtractorPicksBestResult(t *testing.T) {
+	article := strings.Repeat("The article explains the idea in detail with examples. ", 40)
+	comments := `<a href="/u/1">alice</a> Great post! <a href="/u/2">bob</a> Thanks`
+
+	chain := NewChainExtractorWithStrategies(
+		Strategy{Name: "broken", Extractor: &stubExtractor{err: errors.New("boom")}},
+		Strategy{Name: "comments", Extractor: &stubExtractor{content: &models.ExtractedContent{
+			Content: "<div>" + comments + "</div>",
+			Text:    "alice Great post! bob Thanks",
+		}}},
+		Strategy{Name: "article", Extractor: &stubExtractor{content: &models.ExtractedContent{
+			Content: "<div><p>" + article + "</p></div>",
+			Text:    article,
+		}}},
+	)
+
+	page := "<html><body><p>" + article + "</p><div>" + comments + "</div></body></html>"
+	content, err := chain.Extract(context.Background(), &models.RawContent{URL: "https://example.com/post", HTML: page})
+	if err != nil {
+		t.Fatalf("Failed to extract content: %v", err)
+	}
+
+	if content.ExtractionStrategy != "article" {
+		t.Errorf("Expected the article strategy to win, got %q", content.ExtractionStrategy)
+	}
+	if content.ExtractionScore <= 0.8 || content.ExtractionScore > 1 {
+		t.Errorf("Expected a high score for the article, got %f", content.ExtractionScore)
+	}
+}
+
+func TestChainExtractorAllStrategiesFail(t *testing.T) {
+	chain := NewChainExtractorWithStrategies(
+		Strategy{Name: "first", Extractor: &stubExtractor{err: errors.New("first failed")}},
+		Strategy{Name: "second", Extractor: &stubExtractor{err: errors.New("second failed")}},
+	)
+
+	_, err := chain.Extract(context.Background(), &models.RawContent{URL: "https://example.com", HTML: "<p>x</p>"})
+	if err == nil || !strings.Contains(err.Error(), "first failed") || !strings.Contains(err.Error(), "second failed") {
+		t.Errorf("Expected an error reporting every strategy, got %v", err)
+	}
+}
+
+func TestDefaultChainUsesSiteRules(t *testing.T) {
+	page := strings.Replace(testArticle, `<article>`, `<article>
+        <div class="byline"><span class="author-name">Jane Doe</span> <time class="published-date" datetime="2024-03-01">March 1</time></div>`, 1)
+
+	rules := map[string]SiteRule{
+		"blog.example.com": {
+			ArticleSelector: "article",
+			AuthorSelector:  "span.author-name",
+			DateSelector:    "time.published-date",
+		},
+	}
+
+	chain, err := NewChainExtractor(Config{SiteSpecificRules: rules})
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+
+	content, err := chain.Extract(context.Background(), &models.RawContent{
+		URL:  "https://blog.example.com/posts/gc-tuning",
+		HTML: page,
+	})
+	if err != nil {
+		t.Fatalf("Failed to extract content: %v", err)
+	}
+
+	if content.ExtractionStrategy != StrategySiteRules {
+		t.Errorf("Expected the site rule to win, got %q", content.ExtractionStrategy)
+	}
+	if content.Author != "Jane Doe" || content.Published != "2024-03-01" {
+		t.Errorf("Expected metadata from site rule selectors, got %q/%q", content.Author, content.Published)
+	}
+
+	// Without a rule for the host the chain falls back to the other strategies
+	chain, _ = NewChainExtractor(Config{})
+	content, err = chain.Extract(context.Background(), &models.RawContent{
+		URL:  "https://other.example.com/posts/gc-tuning",
+		HTML: page,
+	})
+	if err != nil {
+		t.Fatalf("Failed to extract content: %v", err)
+	}
+	if content.ExtractionStrategy == StrategySiteRules {
+		t.Errorf("Site rules should not run for hosts without a rule")
+	}
+}
+
+func TestSiteRuleExtractorWithoutRule(t *testing.T) {
+	e := NewSiteRuleExtractor(Config{})
+	_, err := e.Extract(context.Background(), &models.RawContent{URL: "https://example.com", HTML: testArticle})
+	if !errors.Is(err, ErrNoSiteRule) {
+		t.Errorf("Expected ErrNoSiteRule, got %v", err)
+	}
+}
+
+func TestNewChainExtractorUnknownStrategy(t *testing.T) {
+	if _, err := NewChainExtractor(Config{FallbackChain: []string{"magic"}}); err == nil {
+		t.Errorf("Expected an error for an unknown strategy")
+	}
+}
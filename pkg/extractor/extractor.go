@@ -5,16 +5,19 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	readability "github.com/go-shiori/go-readability"
+	"golang.org/x/net/html"
 
+	"github.com/ncolesummers/scrape-pipeline/internal/htmlutil"
 	"github.com/ncolesummers/scrape-pipeline/internal/models"
 )
 
 // ReadabilityExtractor implements the Extractor interface using go-readability
 type ReadabilityExtractor struct {
 	siteRules       map[string]SiteRule
-	extractImages   bool
+	builder         contentBuilder
 	extractMetadata bool
 }
 
@@ -29,21 +32,117 @@ type SiteRule struct {
 // Config holds configuration for the extractor
 type Config struct {
 	SiteSpecificRules map[string]SiteRule
-	ExtractImages     bool
-	ExtractMetadata   bool
+	// FallbackChain lists the strategies a ChainExtractor runs, by name.
+	// Empty uses DefaultFallbackChain.
+	FallbackChain []string
+	// MaxKeyphrases limits how many statistically extracted keyphrases are
+	// added to the tags. Zero uses the default, a negative value disables them.
+	MaxKeyphrases   int
+	ExtractImages   bool
+	ExtractMetadata bool
+	// ExtractMarkdown adds a CommonMark rendering of the article next to the
+	// HTML and plain-text renderings
+	ExtractMarkdown bool
+	// KeepComments stores the text of reader comment threads in
+	// ExtractedContent.Comments instead of discarding it
+	KeepComments bool
+	// Templates, when set, learns the recurring blocks of each host from the
+	// extracted pages and strips them before extraction. Closing the
+	// extractor closes it.
+	Templates *TemplateStore
 }
 
 // NewReadabilityExtractor creates a new ReadabilityExtractor with the given configuration
 func NewReadabilityExtractor(config Config) *ReadabilityExtractor {
+	return newReadabilityExtractor(config, newContentBuilder(config))
+}
+
+func newReadabilityExtractor(config Config, builder contentBuilder) *ReadabilityExtractor {
 	return &ReadabilityExtractor{
-		extractImages:   config.ExtractImages,
-		extractMetadata: config.ExtractMetadata,
 		siteRules:       config.SiteSpecificRules,
+		builder:         builder,
+		extractMetadata: config.ExtractMetadata,
 	}
 }
 
+// Close saves the templates learned by the extractor, if any
+func (e *ReadabilityExtractor) Close() error {
+	return e.builder.close()
+}
+
 // Extract extracts the main content from raw HTML
 func (e *ReadabilityExtractor) Extract(ctx context.Context, rawContent *models.RawContent) (*models.ExtractedContent, error) {
+	// Parse the full page separately since readability modifies its own copy
+	// and discards the page metadata we need for tags
+	p, err := parsePage(ctx, rawContent)
+	if err != nil {
+		return nil, err
+	}
+
+	readabilityDoc, segments, err := e.builder.cleanDoc(p)
+	if err != nil {
+		return nil, err
+	}
+	protectSpecialBlocks(readabilityDoc)
+
+	// Extract the article using go-readability
+	article, err := readability.FromDocument(readabilityDoc, p.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract content: %w", err)
+	}
+
+	// Render the article from its DOM so the text keeps block boundaries
+	articleNode := article.Node
+	if articleNode == nil {
+		articleNode, err = html.Parse(strings.NewReader(article.Content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse article content: %w", err)
+		}
+	}
+
+	// Create the extracted content
+	extracted := e.builder.build(p, articleNode, segments)
+	extracted.Title = article.Title
+	if extracted.Description == "" {
+		extracted.Description = article.Excerpt
+	}
+	// Extract more metadata if available. Readability also reads JSON-LD
+	// and other meta elements for the dates and site name.
+	extracted.Author = article.Byline
+	if article.PublishedTime != nil {
+		extracted.Published = article.PublishedTime.Format(time.RFC3339)
+	}
+	if article.ModifiedTime != nil {
+		extracted.Updated = article.ModifiedTime.Format(time.RFC3339)
+	}
+	if article.SiteName != "" && extracted.Metadata["og:site_name"] == "" {
+		extracted.Metadata["og:site_name"] = article.SiteName
+	}
+
+	// Add the lead image if images are configured
+	if e.builder.extractImages {
+		extracted.Images = addLeadImage(extracted.Images, article.Image, p.url)
+	}
+
+	// Apply site-specific extraction for additional metadata
+	if e.extractMetadata {
+		if rule, ok := lookupSiteRule(e.siteRules, p.url.Hostname()); ok {
+			applySiteRuleMetadata(extracted, p.doc, rule)
+		}
+	}
+
+	return extracted, nil
+}
+
+// page is a fetched document parsed once for an extraction
+type page struct {
+	raw *models.RawContent
+	url *url.URL
+	doc *html.Node
+}
+
+// parsePage parses the URL and HTML of raw content
+func parsePage(ctx context.Context, rawContent *models.RawContent) (*page, error) {
 	// Check if context is canceled
 	select {
 	case <-ctx.Done():
@@ -57,69 +156,118 @@ func (e *ReadabilityExtractor) Extract(ctx context.Context, rawContent *models.R
 		return nil, fmt.Errorf("failed to parse URL: %w", err)
 	}
 
-	// Check if we have site-specific rules
-	hostname := parsedURL.Hostname()
+	doc, err := html.Parse(strings.NewReader(rawContent.HTML))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
 
-	// Apply site-specific rules if they exist
-	// Note: In a more complete implementation, we would use these rules
-	// to customize the extraction process
-	_ = e.siteRules[hostname] // Placeholder for future implementation
+	return &page{raw: rawContent, url: parsedURL, doc: doc}, nil
+}
 
-	// Extract the article using go-readability
-	article, err := readability.FromReader(strings.NewReader(rawContent.HTML), parsedURL)
+// cleanDoc parses a fresh copy of the page with comments, author bios,
+// related posts, calls to action, navigation and learned site template
+// blocks removed. The page's own document stays intact for metadata lookups.
+func (b contentBuilder) cleanDoc(p *page) (*html.Node, *segmentation, error) {
+	doc, err := html.Parse(strings.NewReader(p.raw.HTML))
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract content: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
+	segments := stripSegments(doc)
+
+	if b.templates != nil {
+		b.templates.Observe(p.raw.URL, p.url.Hostname(), p.doc)
+		segments.segments = append(segments.segments, b.templates.Strip(p.url.Hostname(), doc)...)
+	}
+
+	return doc, segments, nil
+}
+
+// contentBuilder turns the article node chosen by an extraction strategy
+// into ExtractedContent, so every strategy renders text, outline, tags and
+// images the same way
+type contentBuilder struct {
+	maxKeyphrases   int
+	extractImages   bool
+	extractMarkdown bool
+	keepComments    bool
+	templates       *TemplateStore
+	// ownsTemplates is unset for strategies sharing the template store of
+	// a ChainExtractor, which closes it
+	ownsTemplates bool
+}
+
+func newContentBuilder(config Config) contentBuilder {
+	return contentBuilder{
+		maxKeyphrases:   config.MaxKeyphrases,
+		extractImages:   config.ExtractImages,
+		extractMarkdown: config.ExtractMarkdown,
+		keepComments:    config.KeepComments,
+		templates:       config.Templates,
+		ownsTemplates:   true,
+	}
+}
+
+// close closes the template store, if any and owned
+func (b contentBuilder) close() error {
+	if b.templates == nil || !b.ownsTemplates {
+		return nil
+	}
+	return b.templates.Close()
+}
+
+// build renders articleNode as the article HTML, text and optional
+// Markdown. segments are the regions removed from the page before the
+// article was chosen.
+func (b contentBuilder) build(p *page, articleNode *html.Node, segments *segmentation) *models.ExtractedContent {
+	rendering := renderText(articleNode, p.url)
+	content, textOffsets := htmlutil.RenderWithOffsets(articleNode)
 
-	// Create the extracted content
 	extracted := &models.ExtractedContent{
-		URL:     rawContent.URL,
-		Title:   article.Title,
-		Content: article.Content,
-		// Extract more metadata if available
-		Author:    article.Byline,
-		Published: article.SiteName,
-		Language:  detectLanguage(article.TextContent),
-		Tags:      extractTags(article.TextContent),
+		URL:         p.raw.URL,
+		Description: documentDescription(p.doc),
+		Content:     content,
+		Text:        rendering.Text,
+		Outline:     rendering.Outline,
+		Blocks:      rendering.Blocks,
+		Language:    detectLanguage(rendering.Text),
+		Tags:        extractTags(p.doc, rendering.Text, b.maxKeyphrases),
+		Links:       extractLinks(articleNode, p.url),
+		Segments:    segments.segments,
+		SourceMap:   buildSourceMap(rendering.Text, articleNode, textOffsets),
+		Metadata:    documentMetadata(p.doc),
 	}
+	extracted.Published = extracted.Metadata["article:published_time"]
+	extracted.Updated = extracted.Metadata["article:modified_time"]
 
-	// Extract images if configured
-	if e.extractImages && article.Image != "" {
-		imgURL := article.Image
-		if !strings.HasPrefix(imgURL, "http") && !strings.HasPrefix(imgURL, "//") {
-			// Convert relative URL to absolute
-			base, err := url.Parse(rawContent.URL)
-			if err == nil {
-				imgURLObj, err := url.Parse(imgURL)
-				if err == nil {
-					imgURL = base.ResolveReference(imgURLObj).String()
-				}
-			}
-		}
+	if b.keepComments {
+		extracted.Comments = segments.commentsText(p)
+	}
 
-		extracted.Images = append(extracted.Images, models.ImageInfo{
-			URL:         imgURL,
-			Alt:         article.Title,
-			Description: article.Excerpt,
-		})
+	if b.extractMarkdown {
+		extracted.Markdown = renderMarkdown(articleNode, p.url)
 	}
 
-	// Apply site-specific extraction for additional metadata
-	if e.extractMetadata {
-		e.extractAdditionalMetadata(ctx, extracted, rawContent.HTML, hostname)
+	// Extract images if configured
+	if b.extractImages {
+		extracted.Images = htmlutil.Images(articleNode, p.url)
 	}
 
-	return extracted, nil
+	return extracted
 }
 
-// extractAdditionalMetadata extracts additional metadata from the HTML using site-specific rules
-func (e *ReadabilityExtractor) extractAdditionalMetadata(ctx context.Context, content *models.ExtractedContent, html, hostname string) {
-	// This is a placeholder for more sophisticated metadata extraction
-	// In a real implementation, this would use the site-specific rules to extract
-	// additional metadata like author, publication date, tags, etc.
-
-	// For example, using a DOM parser to extract specific elements based on selectors
-	// from the site-specific rules.
+// addLeadImage appends the page's lead image when readability found one
+// that is not part of the article
+func addLeadImage(images []models.ImageInfo, leadImage string, base *url.URL) []models.ImageInfo {
+	lead := htmlutil.ResolveURL(base, leadImage)
+	if lead == "" || htmlutil.IsDecorativeImage(lead) {
+		return images
+	}
+	for _, image := range images {
+		if image.URL == lead {
+			return images
+		}
+	}
+	return append(images, models.ImageInfo{URL: lead})
 }
 
 // detectLanguage is a simple placeholder for language detection
@@ -129,10 +277,3 @@ func detectLanguage(text string) string {
 	// For now, we'll just return "en" (English)
 	return "en"
 }
-
-// extractTags is a simple placeholder for tag extraction
-// In a real implementation, this would use a more sophisticated approach
-func extractTags(text string) []string {
-	// This is a placeholder for more sophisticated tag extraction
-	return []string{}
-}
@@ -0,0 +1,348 @@
+package extractor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// testArticle is a blog post with enough body text for readability to
+// select the article element
+const testArticle = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <title>Tuning Garbage Collection in Go</title>
+    <meta property="article:tag" content="Go">
+    <meta property="article:tag" content="Performance ">
+    <meta name="keywords" content="golang, garbage collection">
+    <script type="application/ld+json">
+    {"@context": "https://schema.org", "@graph": [
+        {"@type": "BlogPosting", "headline": "Tuning Garbage Collection in Go", "keywords": ["Memory", "#performance"]}
+    ]}
+    </script>
+</head>
+<body>
+    <nav><a href="/">Home</a> <a href="/about">About</a></nav>
+    <article>
+        <h1>Tuning Garbage Collection in Go</h1>
+        <p>The garbage collector in Go trades memory for latency. Tuning the garbage collector
+        starts with measuring heap growth, because heap growth drives how often the garbage
+        collector runs and how much CPU time each cycle consumes in a busy service.</p>
+        <p>The GOGC setting controls heap growth. Raising GOGC lets the heap grow further between
+        cycles, which lowers CPU time spent in the garbage collector but raises peak memory usage.
+        A memory limit puts a ceiling on heap growth so a service cannot run out of memory.</p>
+        <p>Profiling heap growth with pprof shows which allocations matter. Reducing allocations in
+        hot paths is usually the most effective way to lower garbage collector overhead, and it
+        improves latency for every request the service handles.</p>
+        <p>Filed under <a rel="tag" href="/tag/go">Go</a> and <a rel="category tag" href="/tag/runtime">Runtime</a>.</p>
+    </article>
+</body>
+</html>`
+
+func extract(t *testing.T, cfg Config, page string) *models.ExtractedContent {
+	t.Helper()
+
+	e := NewReadabilityExtractor(cfg)
+	content, err := e.Extract(context.Background(), &models.RawContent{
+		URL:  "https://blog.example.com/posts/gc-tuning",
+		HTML: page,
+	})
+	if err != nil {
+		t.Fatalf("Failed to extract content: %v", err)
+	}
+	return content
+}
+
+func TestExtractSiteNameAndDate(t *testing.T) {
+	page := strings.Replace(testArticle, "<title>", `<meta property="og:site_name" content="Example Engineering">
+    <meta property="article:published_time" content="2024-03-05T10:00:00Z">
+    <title>`, 1)
+	for name, e := range map[string]models.Extractor{
+		"readability": NewReadabilityExtractor(Config{}),
+		"heuristic":   NewHeuristicExtractor(Config{}),
+	} {
+		content, err := e.Extract(context.Background(), &models.RawContent{URL: "https://blog.example.com/posts/gc-tuning", HTML: page})
+		if err != nil {
+			t.Fatalf("Failed to extract content with %s: %v", name, err)
+		}
+		if content.Metadata["og:site_name"] != "Example Engineering" || !strings.HasPrefix(content.Published, "2024-03-05") {
+			t.Errorf("%s: site name %q, published %q", name, content.Metadata["og:site_name"], content.Published)
+		}
+	}
+}
+
+func TestExtractTags(t *testing.T) {
+	content := extract(t, Config{}, testArticle)
+
+	tags := make(map[string]int)
+	for _, tag := range content.Tags {
+		tags[tag]++
+	}
+
+	for _, want := range []string{"go", "performance", "golang", "garbage collection", "memory", "runtime"} {
+		if tags[want] == 0 {
+			t.Errorf("Expected tag %q in %v", want, content.Tags)
+		}
+	}
+
+	for tag, count := range tags {
+		if count > 1 {
+			t.Errorf("Tag %q appears %d times, expected tags to be deduplicated", tag, count)
+		}
+		if tag != strings.ToLower(tag) || tag != strings.TrimSpace(tag) {
+			t.Errorf("Tag %q is not normalized", tag)
+		}
+	}
+
+	// Statistical keyphrases from the body text are appended to the page tags
+	if tags["heap growth"] == 0 {
+		t.Errorf("Expected keyphrase 'heap growth' in %v", content.Tags)
+	}
+}
+
+func TestExtractTagsWithoutKeyphrases(t *testing.T) {
+	content := extract(t, Config{MaxKeyphrases: -1}, testArticle)
+
+	if len(content.Tags) != 6 {
+		t.Errorf("Expected only the 6 on-page tags, got %v", content.Tags)
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	tests := map[string]string{
+		"C#":           "c#",
+		".NET":         ".net",
+		"C++":          "c++",
+		"Node.js":      "node.js",
+		"#golang":      "golang",
+		"Go.":          "go",
+		` "Rust"; `:    "rust",
+		"Web   Dev":    "web dev",
+		"(F#)":         "f#",
+		"#":            "",
+		"ASP.NET Core": "asp.net core",
+	}
+	for tag, want := range tests {
+		if got := normalizeTag(tag); got != want {
+			t.Errorf("normalizeTag(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestJSONLDKeywords(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"string", `{"keywords": "a, b"}`, []string{"a", " b"}},
+		{"array", `{"keywords": ["a", "b"]}`, []string{"a", "b"}},
+		{"nested", `[{"@graph": [{"keywords": ["c"]}]}]`, []string{"c"}},
+		{"invalid", `{"keywords":`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := jsonLDKeywords(tt.data)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("jsonLDKeywords() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractMarkdown(t *testing.T) {
+	content := extract(t, Config{ExtractMarkdown: true}, testArticle)
+
+	if !strings.Contains(content.Markdown, "[Go](https://blog.example.com/tag/go)") {
+		t.Errorf("Expected markdown with absolute links, got:\n%s", content.Markdown)
+	}
+	if !strings.Contains(content.Text, "The GOGC setting controls heap growth.") {
+		t.Errorf("Expected plain text rendering, got:\n%s", content.Text)
+	}
+	if strings.Contains(content.Text, "](") {
+		t.Errorf("Plain text should not contain Markdown links, got:\n%s", content.Text)
+	}
+
+	content = extract(t, Config{}, testArticle)
+	if content.Markdown != "" {
+		t.Errorf("Markdown should only be rendered when ExtractMarkdown is set")
+	}
+}
+
+func TestExtractPreservesCodeAndTables(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html>
+<head><title>Benchmarking Maps</title></head>
+<body>
+    <article>
+        <h1>Benchmarking Maps</h1>
+        <p>Benchmarks in Go live next to the code they measure, and the testing package runs them
+        with a loop count it picks on its own. Writing a benchmark therefore only needs a function
+        whose name starts with Benchmark and that loops b.N times over the operation.</p>
+        <div class="highlight"><pre class="chroma"><code class="language-go">func BenchmarkMap(b *testing.B) {
+	m := make(map[int]int)
+	for i := 0; i &lt; b.N; i++ {
+		m[i] = i
+	}
+}</code></pre></div>
+        <p>Running the benchmark on two machines gives the following results, which show that the
+        larger cache of the second machine matters far more than its clock speed when the map
+        grows beyond a few thousand entries and stops fitting in the first level cache.</p>
+        <div><table>
+            <tr><td>Machine</td><td>ns/op</td></tr>
+            <tr><td>laptop</td><td>41</td></tr>
+            <tr><td>server</td><td>23</td></tr>
+        </table></div>
+        <p>The difference is consistent across runs, so the benchmark is stable enough to be used
+        as a regression check in continuous integration without producing noisy failures.</p>
+    </article>
+</body>
+</html>`
+
+	content := extract(t, Config{ExtractMarkdown: true}, page)
+
+	var code, table *models.Block
+	for i := range content.Blocks {
+		switch content.Blocks[i].Kind {
+		case models.BlockCode:
+			code = &content.Blocks[i]
+		case models.BlockTable:
+			table = &content.Blocks[i]
+		}
+	}
+
+	if code == nil {
+		t.Fatalf("Expected the code block to be preserved, got text:\n%s", content.Text)
+	}
+	if code.Language != "go" {
+		t.Errorf("Expected language hint 'go', got %q", code.Language)
+	}
+	if !strings.Contains(content.Text[code.Start:code.End], "\tfor i := 0; i < b.N; i++ {\n") {
+		t.Errorf("Code should keep its indentation, got %q", content.Text[code.Start:code.End])
+	}
+	if !strings.Contains(content.Markdown, "```go\nfunc BenchmarkMap") {
+		t.Errorf("Expected a fenced code block with language hint, got:\n%s", content.Markdown)
+	}
+
+	if table == nil {
+		t.Fatalf("Expected the table to be preserved, got text:\n%s", content.Text)
+	}
+	if len(table.Rows) != 3 || table.Rows[2][1] != "23" {
+		t.Errorf("Unexpected table rows %v", table.Rows)
+	}
+	if !strings.HasPrefix(table.Markdown, "| Machine | ns/op |\n| --- | --- |") {
+		t.Errorf("Unexpected table markdown %q", table.Markdown)
+	}
+}
+
+func TestExtractImages(t *testing.T) {
+	page := strings.Replace(testArticle, `<p>The GOGC setting`, `<figure>
+            <img src="/img/heap-small.png" srcset="/img/heap-small.png 640w, /img/heap.png 1280w" width="640" height="360" alt="Heap profile">
+            <figcaption>Heap size over time</figcaption>
+        </figure>
+        <img src="https://stats.example.com/pixel.gif" width="1" height="1">
+        <p>The GOGC setting`, 1)
+
+	content := extract(t, Config{ExtractImages: true}, page)
+
+	if len(content.Images) != 1 {
+		t.Fatalf("Expected 1 image, got %+v", content.Images)
+	}
+
+	image := content.Images[0]
+	if image.URL != "https://blog.example.com/img/heap.png" {
+		t.Errorf("Expected the largest srcset candidate, got %s", image.URL)
+	}
+	if image.Alt != "Heap profile" || image.Description != "Heap size over time" {
+		t.Errorf("Expected alt text and figure caption, got %q/%q", image.Alt, image.Description)
+	}
+	if image.Width != 640 || image.Height != 360 {
+		t.Errorf("Expected dimensions from attributes, got %dx%d", image.Width, image.Height)
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	page := strings.Replace(testArticle, `<p>Profiling heap growth with pprof`,
+		`<p>See the <a href="https://go.dev/doc/gc-guide?utm_source=blog">GC guide</a> for the details,
+        and our <a href="../posts/escape-analysis#intro">escape analysis post</a> for allocations.
+        <a href="mailto:jane@example.com">Email me</a> or <a href="#top">jump up</a>.</p>
+        <p>Profiling heap growth with pprof`, 1)
+
+	content := extract(t, Config{}, page)
+
+	links := make(map[string]models.Link)
+	for _, link := range content.Links {
+		links[link.URL] = link
+	}
+
+	guide, ok := links["https://go.dev/doc/gc-guide"]
+	if !ok || guide.Internal || guide.Text != "GC guide" {
+		t.Errorf("Expected a normalized external link, got %+v", content.Links)
+	}
+	if !strings.Contains(guide.Context, "See the GC guide for the details") {
+		t.Errorf("Unexpected context %q", guide.Context)
+	}
+
+	post, ok := links["https://blog.example.com/posts/escape-analysis"]
+	if !ok || !post.Internal || post.Text != "escape analysis post" {
+		t.Errorf("Expected a resolved internal link, got %+v", content.Links)
+	}
+
+	if tag, ok := links["https://blog.example.com/tag/go"]; !ok || tag.Rel != "tag" {
+		t.Errorf("Expected the tag link with its rel, got %+v", content.Links)
+	}
+
+	for _, link := range content.Links {
+		if strings.HasPrefix(link.URL, "mailto:") || strings.Contains(link.URL, "gc-tuning") {
+			t.Errorf("Unexpected link %+v", link)
+		}
+	}
+}
+
+func TestLinkContext(t *testing.T) {
+	long := strings.Repeat("word ", 40)
+	doc := `<p>` + long + `<a href="/x">anchor</a> ` + long + `</p>`
+	content := extract(t, Config{}, strings.Replace(testArticle, `<p>Filed under`, doc+`<p>Filed under`, 1))
+
+	for _, link := range content.Links {
+		if link.Text != "anchor" {
+			continue
+		}
+		if !strings.HasPrefix(link.Context, "…word") || !strings.HasSuffix(link.Context, "word…") ||
+			!strings.Contains(link.Context, " anchor ") || len(link.Context) > 2*linkContextRadius+len("anchor")+len("……") {
+			t.Errorf("Unexpected context %q", link.Context)
+		}
+		return
+	}
+	t.Errorf("Link not found in %+v", content.Links)
+}
+
+func TestSourceMap(t *testing.T) {
+	content := extract(t, Config{}, testArticle)
+	if len(content.SourceMap) == 0 {
+		t.Fatal("Expected a source map")
+	}
+
+	for _, span := range content.SourceMap {
+		word := content.Text[span.Start:span.End]
+		source := html.UnescapeString(content.Content[span.HTMLStart:span.HTMLEnd])
+		if word != source {
+			t.Fatalf("Span %+v maps %q to %q", span, word, source)
+		}
+	}
+
+	offset := strings.Index(content.Text, "GOGC setting")
+	span, ok := content.SourceMap.Span(offset)
+	if !ok || span.Start != offset || !strings.HasSuffix(span.Path, "/p[2]") {
+		t.Errorf("Span(%d) = %+v, want the second paragraph", offset, span)
+	}
+	htmlOffset, _ := content.SourceMap.HTMLOffset(offset)
+	if !strings.HasPrefix(content.Content[htmlOffset:], "GOGC setting") {
+		t.Errorf("HTMLOffset(%d) points at %q", offset, content.Content[htmlOffset:htmlOffset+20])
+	}
+}
@@ -0,0 +1,77 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/net/html"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/htmlutil"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// boilerplateElements are removed from the node chosen by HeuristicExtractor.
+// Headers are only removed outside <article>, where they hold the site
+// banner rather than the post title.
+var boilerplateElements = []string{"nav", "footer", "aside", "form", "script", "style", "noscript"}
+
+// HeuristicExtractor extracts content the way the simple extractor does:
+// the first <article> element, otherwise <main>, otherwise the whole body,
+// with navigation, footers and sidebars stripped. It never picks a
+// wrong candidate the way scoring can, which makes it a safe last resort.
+type HeuristicExtractor struct {
+	builder contentBuilder
+}
+
+// NewHeuristicExtractor creates a new HeuristicExtractor with the given configuration
+func NewHeuristicExtractor(config Config) *HeuristicExtractor {
+	return newHeuristicExtractor(newContentBuilder(config))
+}
+
+func newHeuristicExtractor(builder contentBuilder) *HeuristicExtractor {
+	return &HeuristicExtractor{builder: builder}
+}
+
+// Close saves the templates learned by the extractor, if any
+func (e *HeuristicExtractor) Close() error {
+	return e.builder.close()
+}
+
+// Extract extracts the main content from raw HTML
+func (e *HeuristicExtractor) Extract(ctx context.Context, rawContent *models.RawContent) (*models.ExtractedContent, error) {
+	p, err := parsePage(ctx, rawContent)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, segments, err := e.builder.cleanDoc(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidate *html.Node
+	stripped := boilerplateElements
+	for _, tag := range []string{"article", "main", "body"} {
+		if nodes := htmlutil.FindAll(doc, tag); len(nodes) > 0 {
+			candidate = nodes[0]
+			if tag != "article" {
+				stripped = append(stripped, "header")
+			}
+			break
+		}
+	}
+	if candidate == nil {
+		return nil, errors.New("document has no body")
+	}
+
+	for _, node := range htmlutil.FindAll(candidate, stripped...) {
+		if node.Parent != nil {
+			node.Parent.RemoveChild(node)
+		}
+	}
+
+	extracted := e.builder.build(p, candidate, segments)
+	extracted.Title = documentTitle(p.doc)
+
+	return extracted, nil
+}
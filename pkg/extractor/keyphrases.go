@@ -0,0 +1,193 @@
+package extractor
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxKeyphraseWords bounds keyphrase length. Longer runs without a stopword
+// are usually sentence fragments rather than topics.
+const maxKeyphraseWords = 3
+
+// stopwords splits candidate phrases for RAKE. The list is intentionally
+// small; it only has to break text into plausible noun-phrase candidates.
+var stopwords = toSet(`a about above after again against all also am an and any are as at be
+because been before being below between both but by can could did do does doing down
+during each even every few for from further get gets got had has have having he her here
+hers herself him himself his how however i if in into is it its itself just let like
+many may me might more most much must my myself new no nor not now of off on once one
+only or other our ours ourselves out over own per same she should since so some such
+than that the their theirs them themselves then there these they this those through to
+too under until up upon us use used using very was we well were what when where which
+while who whom why will with within without would yet you your yours yourself
+yourselves`)
+
+// extractKeyphrases ranks candidate phrases in text using RAKE (Rapid
+// Automatic Keyword Extraction). Candidates are maximal runs of content words
+// between stopwords and punctuation; each word is scored by degree/frequency.
+// Phrases (including sub-phrases of longer candidates) that occur at least
+// twice score the sum of their words weighted by their frequency, which keeps
+// verbs glued to a noun phrase from hiding a recurring topic.
+func extractKeyphrases(text string, limit int) []string {
+	candidates := keyphraseCandidates(text)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	freq := make(map[string]int)
+	degree := make(map[string]int)
+	for _, phrase := range candidates {
+		for _, word := range phrase {
+			freq[word]++
+			degree[word] += len(phrase)
+		}
+	}
+
+	type scored struct {
+		words []string
+		score float64
+		count int
+		first int
+	}
+
+	byPhrase := make(map[string]*scored)
+	order := 0
+	for _, phrase := range candidates {
+		for i := range phrase {
+			for j := i + 1; j <= len(phrase) && j-i <= maxKeyphraseWords; j++ {
+				key := strings.Join(phrase[i:j], " ")
+				if s, ok := byPhrase[key]; ok {
+					s.count++
+					continue
+				}
+				byPhrase[key] = &scored{words: phrase[i:j], count: 1, first: order}
+				order++
+			}
+		}
+	}
+
+	ranked := make([]*scored, 0, len(byPhrase))
+	for _, s := range byPhrase {
+		// A phrase seen once is rarely a topic of the document
+		if s.count < 2 {
+			continue
+		}
+		for _, word := range s.words {
+			s.score += float64(degree[word]) / float64(freq[word])
+		}
+		s.score *= float64(s.count)
+		ranked = append(ranked, s)
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].first < ranked[j].first
+	})
+
+	// Skip phrases whose words are all covered by a higher ranked phrase,
+	// so "heap" is not reported next to "heap growth"
+	var phrases []string
+	covered := make(map[string]bool)
+	for _, s := range ranked {
+		if len(phrases) == limit {
+			break
+		}
+		redundant := true
+		for _, word := range s.words {
+			if !covered[word] {
+				redundant = false
+			}
+		}
+		if redundant {
+			continue
+		}
+		for _, word := range s.words {
+			covered[word] = true
+		}
+		phrases = append(phrases, strings.Join(s.words, " "))
+	}
+	return phrases
+}
+
+// keyphraseCandidates splits text into lowercase candidate phrases
+func keyphraseCandidates(text string) [][]string {
+	var candidates [][]string
+	var current []string
+
+	flush := func() {
+		if len(current) > 0 {
+			candidates = append(candidates, current)
+		}
+		current = nil
+	}
+
+	for _, token := range tokenizeWords(text) {
+		if token == "" {
+			flush()
+			continue
+		}
+		word := strings.ToLower(token)
+		if stopwords[word] || !isKeywordCandidate(word) {
+			flush()
+			continue
+		}
+		current = append(current, word)
+	}
+	flush()
+
+	return candidates
+}
+
+// tokenizeWords splits text into words. Phrase boundaries such as
+// sentence punctuation are returned as empty strings.
+func tokenizeWords(text string) []string {
+	var tokens []string
+	var sb strings.Builder
+
+	emit := func() {
+		if sb.Len() > 0 {
+			tokens = append(tokens, sb.String())
+			sb.Reset()
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+' || r == '#' ||
+			(sb.Len() > 0 && (r == '-' || r == '\'')):
+			sb.WriteRune(r)
+		case unicode.IsSpace(r):
+			emit()
+		default:
+			emit()
+			tokens = append(tokens, "")
+		}
+	}
+	emit()
+
+	return tokens
+}
+
+// isKeywordCandidate filters words that cannot be part of a keyphrase
+func isKeywordCandidate(word string) bool {
+	if len([]rune(word)) < 3 {
+		return false
+	}
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func toSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
@@ -0,0 +1,112 @@
+package extractor
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/htmlutil"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/internal/urlutil"
+)
+
+// linkContextRadius is how much text around the anchor is kept as context,
+// on each side
+const linkContextRadius = 80
+
+// contextElements are the blocks whose text gives a link its context
+var contextElements = []string{
+	"p", "li", "td", "th", "dd", "dt", "blockquote", "figcaption",
+	"h1", "h2", "h3", "h4", "h5", "h6",
+}
+
+// extractLinks returns the http(s) links of the article with normalized
+// URLs, anchor text and context. Each target is listed once, with the
+// anchor and context of its first occurrence. Links to the page itself,
+// including in-page anchors, are skipped.
+func extractLinks(articleNode *html.Node, base *url.URL) []models.Link {
+	self := ""
+	if base != nil {
+		self, _ = urlutil.Normalize(base.String())
+	}
+
+	var links []models.Link
+	seen := make(map[string]bool)
+	for _, a := range htmlutil.FindAll(articleNode, "a") {
+		target, ok := urlutil.Resolve(base, htmlutil.Attr(a, "href"))
+		if !ok || target == self || seen[target] {
+			continue
+		}
+		seen[target] = true
+
+		internal := false
+		if base != nil {
+			if parsed, err := url.Parse(target); err == nil {
+				internal = urlutil.SameSite(parsed.Host, base.Host)
+			}
+		}
+
+		text := htmlutil.TextContent(a)
+		if text == "" {
+			text = imageAlt(a)
+		}
+
+		links = append(links, models.Link{
+			URL:      target,
+			Text:     text,
+			Context:  linkContext(a, text),
+			Rel:      strings.ToLower(strings.Join(strings.Fields(htmlutil.Attr(a, "rel")), " ")),
+			Internal: internal,
+		})
+	}
+	return links
+}
+
+// imageAlt returns the alt text of the first image in an image-only link
+func imageAlt(a *html.Node) string {
+	for _, img := range htmlutil.FindAll(a, "img") {
+		if alt := strings.TrimSpace(htmlutil.Attr(img, "alt")); alt != "" {
+			return alt
+		}
+	}
+	return ""
+}
+
+// linkContext returns the text of the block around a, trimmed to a window
+// around the anchor text
+func linkContext(a *html.Node, anchor string) string {
+	block := a
+	for n := a.Parent; n != nil; n = n.Parent {
+		if htmlutil.IsElement(n, contextElements...) {
+			block = n
+			break
+		}
+	}
+
+	text := htmlutil.TextContent(block)
+	if anchor == "" {
+		return excerpt(text, 2*linkContextRadius)
+	}
+	i := strings.Index(text, anchor)
+	if i < 0 {
+		return excerpt(text, 2*linkContextRadius)
+	}
+
+	// Cut the window at word boundaries, which also keeps runes intact
+	start, end := 0, len(text)
+	prefix, suffix := "", ""
+	if i > linkContextRadius {
+		start, prefix = i, "…"
+		if space := strings.Index(text[i-linkContextRadius:i], " "); space >= 0 {
+			start = i - linkContextRadius + space + 1
+		}
+	}
+	if after := i + len(anchor); len(text)-after > linkContextRadius {
+		end, suffix = after, "…"
+		if space := strings.LastIndex(text[after:after+linkContextRadius], " "); space >= 0 {
+			end = after + space
+		}
+	}
+	return prefix + text[start:end] + suffix
+}
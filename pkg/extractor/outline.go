@@ -0,0 +1,78 @@
+package extractor
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// buildOutline nests the headings recorded while rendering into a section
+// tree. Each section ends where the next heading of the same or a higher
+// level starts, or at the end of the text.
+func buildOutline(headings []renderedHeading, textLen int) []*models.Section {
+	var outline []*models.Section
+	var stack []*models.Section
+	anchors := make(map[string]int)
+
+	for _, h := range headings {
+		section := &models.Section{
+			Heading: h.text,
+			Anchor:  uniqueAnchor(h.id, h.text, anchors),
+			Level:   h.level,
+			Start:   h.offset,
+			End:     textLen,
+		}
+
+		// Close every open section that this heading ends
+		for len(stack) > 0 && stack[len(stack)-1].Level >= h.level {
+			stack[len(stack)-1].End = h.offset
+			stack = stack[:len(stack)-1]
+		}
+
+		if len(stack) == 0 {
+			outline = append(outline, section)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, section)
+		}
+		stack = append(stack, section)
+	}
+
+	return outline
+}
+
+// uniqueAnchor returns the heading's id attribute, or a slug of its text
+// when it has none. Repeated anchors get a numeric suffix the way most
+// Markdown renderers generate them.
+func uniqueAnchor(id, text string, seen map[string]int) string {
+	anchor := id
+	if anchor == "" {
+		anchor = slugify(text)
+	}
+	if anchor == "" {
+		anchor = "section"
+	}
+
+	count := seen[anchor]
+	seen[anchor] = count + 1
+	if count == 0 {
+		return anchor
+	}
+	return anchor + "-" + strconv.Itoa(count)
+}
+
+// slugify lowercases text, drops punctuation and joins words with hyphens
+func slugify(text string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			sb.WriteRune(r)
+		case unicode.IsSpace(r):
+			sb.WriteRune('-')
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,93 @@
+package extractor
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/htmlutil"
+)
+
+// protectSpecialBlocks prepares a page so readability keeps its code
+// samples and data tables intact:
+//   - the language hint of every <pre> is copied to a data-lang attribute,
+//     since readability strips class names from the article
+//   - single-child wrappers around code and tables are unwrapped, because
+//     readability judges short wrapper divs as clutter and removes them
+//   - tables that look like data get an empty <colgroup>, which readability
+//     treats as a sign of a data table rather than a layout table
+func protectSpecialBlocks(doc *html.Node) {
+	for _, pre := range htmlutil.FindAll(doc, "pre") {
+		if lang := htmlutil.CodeLanguage(pre); lang != "" && htmlutil.Attr(pre, "data-lang") == "" {
+			pre.Attr = append(pre.Attr, html.Attribute{Key: "data-lang", Val: lang})
+		}
+		unwrap(pre)
+	}
+
+	for _, table := range htmlutil.FindAll(doc, "table") {
+		if !looksLikeDataTable(table) {
+			continue
+		}
+		if len(htmlutil.FindAll(table, "col", "colgroup", "tfoot", "thead", "th")) == 0 {
+			colgroup := &html.Node{Type: html.ElementNode, Data: "colgroup", DataAtom: atom.Colgroup}
+			table.InsertBefore(colgroup, table.FirstChild)
+		}
+		unwrap(table)
+	}
+}
+
+// looksLikeDataTable reports whether a table holds data rather than
+// page layout: at least two rows and two columns, no nested tables and no
+// presentation role
+func looksLikeDataTable(table *html.Node) bool {
+	if htmlutil.Attr(table, "role") == "presentation" {
+		return false
+	}
+	for _, nested := range htmlutil.FindAll(table, "table") {
+		if nested != table {
+			return false
+		}
+	}
+
+	rows := htmlutil.TableRows(table)
+	if len(rows) < 2 {
+		return false
+	}
+	for _, row := range rows {
+		if len(row) >= 2 {
+			return true
+		}
+	}
+	return false
+}
+
+// unwrap replaces div wrappers whose only content is n with n itself
+func unwrap(n *html.Node) {
+	for {
+		parent := n.Parent
+		if !htmlutil.IsElement(parent, "div", "span") || parent.Parent == nil || !onlyChild(parent, n) {
+			return
+		}
+		parent.RemoveChild(n)
+		parent.Parent.InsertBefore(n, parent)
+		parent.Parent.RemoveChild(parent)
+	}
+}
+
+// onlyChild reports whether n is the only non-whitespace child of parent
+func onlyChild(parent, n *html.Node) bool {
+	for c := parent.FirstChild; c != nil; c = c.NextSibling {
+		if c == n {
+			continue
+		}
+		if c.Type == html.TextNode && strings.TrimSpace(c.Data) == "" {
+			continue
+		}
+		if c.Type == html.CommentNode {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,551 @@
+package extractor
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/htmlutil"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// renderer turns an article DOM into either CommonMark or plain text.
+// Both renderings share the same block structure so offsets and block
+// boundaries line up between them.
+type renderer struct {
+	base   *url.URL
+	out    strings.Builder
+	frames []*prefixFrame
+	plain  bool
+	// tight suppresses the blank line before the next block, which keeps
+	// list items on consecutive lines
+	tight     bool
+	listDepth int
+	headings  []renderedHeading
+	special   []models.Block
+}
+
+// renderedHeading records where a heading was written in the output
+type renderedHeading struct {
+	text   string
+	id     string
+	level  int
+	offset int
+}
+
+// textRendering is the plain-text rendering of an article together with
+// the structure recorded while rendering it
+type textRendering struct {
+	Text    string
+	Outline []*models.Section
+	Blocks  []models.Block
+}
+
+// prefixFrame is a line prefix contributed by an enclosing list item or
+// blockquote. The first line written inside the frame uses first, every
+// following line uses rest.
+type prefixFrame struct {
+	first   string
+	rest    string
+	started bool
+}
+
+// renderMarkdown renders the DOM below n as CommonMark. Links and images
+// are resolved against base so the output never contains relative URLs.
+func renderMarkdown(n *html.Node, base *url.URL) string {
+	r := &renderer{base: base}
+	r.blocks(n)
+	return r.String()
+}
+
+// renderText renders the DOM below n as plain text with blank lines between
+// blocks and "- " markers for list items
+func renderText(n *html.Node, base *url.URL) *textRendering {
+	r := &renderer{base: base, plain: true}
+	r.blocks(n)
+	text := r.String()
+	return &textRendering{
+		Text:    text,
+		Outline: buildOutline(r.headings, len(text)),
+		Blocks:  r.special,
+	}
+}
+
+// String returns the rendered document
+func (r *renderer) String() string {
+	if r.out.Len() == 0 {
+		return ""
+	}
+	return r.out.String() + "\n"
+}
+
+// skippedElements never contribute to the rendered output
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "head": true,
+	"iframe": true, "object": true, "embed": true, "button": true, "input": true,
+	"select": true, "textarea": true, "svg": true, "canvas": true,
+}
+
+// blockElements start a new block when they appear inside a container
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true,
+	"dd": true, "details": true, "div": true, "dl": true, "dt": true, "fieldset": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true, "h1": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+	"hr": true, "html": true, "li": true, "main": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "section": true, "summary": true, "table": true, "ul": true,
+}
+
+// blocks renders the children of a container element. Runs of inline
+// children are gathered into a single paragraph.
+func (r *renderer) blocks(n *html.Node) {
+	var inline []*html.Node
+
+	flush := func() {
+		if len(inline) == 0 {
+			return
+		}
+		r.paragraph(r.inlineNodes(inline))
+		inline = nil
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && skippedElements[c.Data] {
+			continue
+		}
+		if c.Type == html.ElementNode && blockElements[c.Data] {
+			flush()
+			r.block(c)
+			continue
+		}
+		inline = append(inline, c)
+	}
+	flush()
+}
+
+// block renders a single block-level element
+func (r *renderer) block(n *html.Node) {
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.heading(n)
+	case "p", "dt", "summary", "figcaption":
+		r.paragraph(r.inline(n))
+	case "pre":
+		r.code(n)
+	case "ul", "ol":
+		r.list(n)
+	case "blockquote":
+		r.push("> ", "> ")
+		r.blocks(n)
+		r.pop()
+	case "table":
+		r.table(n)
+	case "hr":
+		if !r.plain {
+			r.write("---")
+		}
+	default:
+		r.blocks(n)
+	}
+}
+
+func (r *renderer) heading(n *html.Node) {
+	text := r.inline(n)
+	if text == "" {
+		return
+	}
+	level := int(n.Data[1] - '0')
+	if !r.plain {
+		r.write(strings.Repeat("#", level) + " " + text)
+		return
+	}
+	offset := r.write(text)
+	r.headings = append(r.headings, renderedHeading{
+		text:   text,
+		id:     htmlutil.Attr(n, "id"),
+		level:  level,
+		offset: offset,
+	})
+}
+
+func (r *renderer) paragraph(text string) {
+	if text == "" {
+		return
+	}
+	if !r.plain {
+		text = escapeLineStart(text)
+	}
+	r.write(text)
+}
+
+// code writes a preformatted block verbatim. Markdown output uses a fenced
+// code block annotated with the language hint when one is present.
+func (r *renderer) code(n *html.Node) {
+	text := htmlutil.PreformattedText(n)
+	if text == "" {
+		return
+	}
+	language := htmlutil.CodeLanguage(n)
+	fenced := htmlutil.FencedCode(text, language)
+	if !r.plain {
+		r.write(fenced)
+		return
+	}
+
+	start := r.write(text)
+	r.special = append(r.special, models.Block{
+		Kind:     models.BlockCode,
+		Language: language,
+		Text:     text,
+		Markdown: fenced,
+		Start:    start,
+		End:      r.out.Len(),
+	})
+}
+
+func (r *renderer) list(n *html.Node) {
+	ordered := n.Data == "ol"
+	number := 1
+	if start, err := strconv.Atoi(htmlutil.Attr(n, "start")); err == nil && ordered {
+		number = start
+	}
+
+	r.listDepth++
+	index := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if !htmlutil.IsElement(c, "li") {
+			continue
+		}
+
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+
+		// Items after the first, and nested lists directly below their parent
+		// item's text, stay on consecutive lines
+		r.tight = index > 0 || r.listDepth > 1
+		r.push(marker, strings.Repeat(" ", len(marker)))
+		r.blocks(c)
+		r.pop()
+		index++
+	}
+	r.listDepth--
+}
+
+// table writes a table as a GitHub-flavored Markdown table. Plain text
+// separates cells with " | " and omits the delimiter row.
+func (r *renderer) table(n *html.Node) {
+	if !r.plain {
+		if markdown := htmlutil.MarkdownTable(r.tableRows(n)); markdown != "" {
+			r.write(markdown)
+		}
+		return
+	}
+
+	rows := htmlutil.TableRows(n)
+	if len(rows) == 0 {
+		return
+	}
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = strings.TrimSpace(strings.Join(row, " | "))
+	}
+
+	text := strings.Join(lines, "\n")
+	start := r.write(text)
+	markdownRenderer := &renderer{base: r.base}
+	r.special = append(r.special, models.Block{
+		Kind:     models.BlockTable,
+		Text:     text,
+		Markdown: htmlutil.MarkdownTable(markdownRenderer.tableRows(n)),
+		Rows:     rows,
+		Start:    start,
+		End:      r.out.Len(),
+	})
+}
+
+// tableRows returns the text of every cell, row by row
+func (r *renderer) tableRows(n *html.Node) [][]string {
+	var rows [][]string
+	for _, tr := range htmlutil.FindAll(n, "tr") {
+		var cells []string
+		for c := tr.FirstChild; c != nil; c = c.NextSibling {
+			if htmlutil.IsElement(c, "td", "th") {
+				cells = append(cells, strings.ReplaceAll(r.inline(c), "\n", " "))
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+	}
+	return rows
+}
+
+// write emits one block, separated from the previous block by a blank line
+// and with every line prefixed by the enclosing list items and blockquotes.
+// It returns the offset at which the block's text starts.
+func (r *renderer) write(text string) int {
+	if r.out.Len() > 0 {
+		r.out.WriteString("\n")
+		if !r.tight {
+			r.out.WriteString(strings.TrimRight(r.separatorPrefix(), " "))
+			r.out.WriteString("\n")
+		}
+	}
+	r.tight = false
+
+	start := -1
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			r.out.WriteString("\n")
+		}
+		prefix := r.linePrefix()
+		if line == "" {
+			r.out.WriteString(strings.TrimRight(prefix, " "))
+			continue
+		}
+		r.out.WriteString(prefix)
+		if start < 0 {
+			start = r.out.Len()
+		}
+		r.out.WriteString(line)
+	}
+	return start
+}
+
+func (r *renderer) push(first, rest string) {
+	if r.plain && first == "> " {
+		first, rest = "", ""
+	}
+	r.frames = append(r.frames, &prefixFrame{first: first, rest: rest})
+}
+
+func (r *renderer) pop() {
+	r.frames = r.frames[:len(r.frames)-1]
+}
+
+// linePrefix returns the prefix for the next line and marks every frame
+// as started
+func (r *renderer) linePrefix() string {
+	var sb strings.Builder
+	for _, frame := range r.frames {
+		if frame.started {
+			sb.WriteString(frame.rest)
+			continue
+		}
+		sb.WriteString(frame.first)
+		frame.started = true
+	}
+	return sb.String()
+}
+
+// separatorPrefix returns the prefix for a blank line between blocks.
+// Frames that have not written anything yet are still ahead of the
+// separator and do not contribute.
+func (r *renderer) separatorPrefix() string {
+	var sb strings.Builder
+	for _, frame := range r.frames {
+		if frame.started {
+			sb.WriteString(frame.rest)
+		}
+	}
+	return sb.String()
+}
+
+// inline renders the children of n as inline text
+func (r *renderer) inline(n *html.Node) string {
+	var children []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		children = append(children, c)
+	}
+	return r.inlineNodes(children)
+}
+
+// inlineNodes renders a run of inline nodes and normalizes the whitespace
+// between them
+func (r *renderer) inlineNodes(nodes []*html.Node) string {
+	var sb strings.Builder
+	for _, n := range nodes {
+		r.inlineNode(n, &sb)
+	}
+	if r.plain {
+		return tidyInline(sb.String(), "\n")
+	}
+	return tidyInline(sb.String(), "\\\n")
+}
+
+var whitespaceRun = regexp.MustCompile(`[ \t\r\n\f]+`)
+
+func (r *renderer) inlineNode(n *html.Node, sb *strings.Builder) {
+	switch n.Type {
+	case html.TextNode:
+		text := whitespaceRun.ReplaceAllString(n.Data, " ")
+		if !r.plain {
+			text = escapeMarkdown(text)
+		}
+		sb.WriteString(text)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if skippedElements[n.Data] {
+		return
+	}
+
+	switch n.Data {
+	case "br":
+		sb.WriteString("\n")
+		return
+	case "img":
+		if !r.plain {
+			if src := r.resolve(imageSource(n)); src != "" {
+				fmt.Fprintf(sb, "![%s](%s)", escapeMarkdown(htmlutil.Attr(n, "alt")), markdownURL(src))
+			}
+		}
+		return
+	case "code", "kbd", "samp", "tt":
+		text := whitespaceRun.ReplaceAllString(htmlutil.TextContent(n), " ")
+		if r.plain {
+			sb.WriteString(text)
+		} else {
+			sb.WriteString(inlineCode(text))
+		}
+		return
+	}
+
+	if r.plain {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			r.inlineNode(c, sb)
+		}
+		return
+	}
+
+	var inner strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.inlineNode(c, &inner)
+	}
+	text := inner.String()
+
+	switch n.Data {
+	case "strong", "b":
+		sb.WriteString(wrapEmphasis(text, "**"))
+	case "em", "i", "cite":
+		sb.WriteString(wrapEmphasis(text, "*"))
+	case "del", "s", "strike":
+		sb.WriteString(wrapEmphasis(text, "~~"))
+	case "a":
+		href := r.resolve(htmlutil.Attr(n, "href"))
+		label := strings.TrimSpace(text)
+		if href == "" || label == "" {
+			sb.WriteString(text)
+			return
+		}
+		fmt.Fprintf(sb, "[%s](%s)", label, markdownURL(href))
+	default:
+		sb.WriteString(text)
+	}
+}
+
+// resolve returns ref as an absolute URL, or an empty string for references
+// that cannot be followed such as javascript: links
+func (r *renderer) resolve(ref string) string {
+	return htmlutil.ResolveURL(r.base, ref)
+}
+
+// imageSource returns the URL an image is loaded from, including the
+// data-src attribute used by lazy loading scripts
+func imageSource(n *html.Node) string {
+	if src := htmlutil.Attr(n, "src"); src != "" && !strings.HasPrefix(src, "data:") {
+		return src
+	}
+	return htmlutil.Attr(n, "data-src")
+}
+
+// tidyInline trims a paragraph, collapses the spaces left behind where
+// inline elements met, and joins the lines produced by <br> elements with
+// hardBreak
+func tidyInline(text, hardBreak string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" && len(lines) == 0 {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, hardBreak)
+}
+
+var markdownSpecial = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+)
+
+// escapeMarkdown escapes characters that would otherwise start emphasis,
+// code spans or links
+func escapeMarkdown(text string) string {
+	return markdownSpecial.Replace(text)
+}
+
+var blockStart = regexp.MustCompile(`^(#{1,6}\s|>|[-+]\s|\d+[.)]\s)`)
+
+// escapeLineStart escapes a paragraph that would otherwise be parsed as a
+// heading, blockquote or list item
+func escapeLineStart(text string) string {
+	if loc := blockStart.FindStringIndex(text); loc != nil {
+		for i, r := range text {
+			if r < '0' || r > '9' {
+				return text[:i] + `\` + text[i:]
+			}
+		}
+	}
+	return text
+}
+
+// wrapEmphasis surrounds text with a delimiter, keeping surrounding spaces
+// outside so the delimiters stay valid CommonMark
+func wrapEmphasis(text, delimiter string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	leading := text[:strings.Index(text, trimmed)]
+	trailing := text[len(leading)+len(trimmed):]
+	return leading + delimiter + trimmed + delimiter + trailing
+}
+
+// inlineCode wraps text in a code span using a backtick fence longer than
+// any run of backticks inside it
+func inlineCode(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		return fence + " " + text + " " + fence
+	}
+	return fence + text + fence
+}
+
+// markdownURL wraps destinations containing spaces or parentheses in angle
+// brackets so they survive as a single link destination
+func markdownURL(u string) string {
+	if strings.ContainsAny(u, " ()") {
+		return "<" + u + ">"
+	}
+	return u
+}
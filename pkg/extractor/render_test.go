@@ -0,0 +1,145 @@
+package extractor
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+const renderFixture = `<div>
+<h2>Getting <em>started</em></h2>
+<p>Read the <a href="/docs/intro">intro</a> and the <a href="https://go.dev/doc">Go docs</a>.
+Use <code>go build</code> to compile.<br>Then run it.</p>
+<ul>
+  <li>First <strong>item</strong></li>
+  <li>Second item
+    <ol start="3"><li>Nested three</li><li>Nested four</li></ol>
+  </li>
+</ul>
+<blockquote><p>Quoted text</p><p>Second quote</p></blockquote>
+<pre><code class="language-go">func main() {
+	fmt.Println("hi")
+}</code></pre>
+<table>
+  <thead><tr><th>Name</th><th>Speed</th></tr></thead>
+  <tbody><tr><td>fast|er</td><td>1ms</td></tr><tr><td>slow</td></tr></tbody>
+</table>
+<p><img src="img/chart.png" alt="A chart"></p>
+<p># not a heading</p>
+</div>`
+
+const wantMarkdown = "## Getting *started*\n" +
+	"\n" +
+	"Read the [intro](https://blog.example.com/docs/intro) and the [Go docs](https://go.dev/doc). Use `go build` to compile.\\\n" +
+	"Then run it.\n" +
+	"\n" +
+	"- First **item**\n" +
+	"- Second item\n" +
+	"  3. Nested three\n" +
+	"  4. Nested four\n" +
+	"\n" +
+	"> Quoted text\n" +
+	">\n" +
+	"> Second quote\n" +
+	"\n" +
+	"```go\n" +
+	"func main() {\n" +
+	"\tfmt.Println(\"hi\")\n" +
+	"}\n" +
+	"```\n" +
+	"\n" +
+	"| Name | Speed |\n" +
+	"| --- | --- |\n" +
+	"| fast\\|er | 1ms |\n" +
+	"| slow |  |\n" +
+	"\n" +
+	"![A chart](https://blog.example.com/posts/img/chart.png)\n" +
+	"\n" +
+	"\\# not a heading\n"
+
+func parseFragment(t *testing.T, fragment string) *html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		t.Fatalf("Failed to parse HTML: %v", err)
+	}
+	return doc
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	base, _ := url.Parse("https://blog.example.com/posts/intro")
+	got := renderMarkdown(parseFragment(t, renderFixture), base)
+
+	if got != wantMarkdown {
+		t.Errorf("Unexpected markdown.\nGot:\n%s\nWant:\n%s", got, wantMarkdown)
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	base, _ := url.Parse("https://blog.example.com/posts/intro")
+	got := renderText(parseFragment(t, renderFixture), base).Text
+
+	for _, want := range []string{
+		"Getting started\n\nRead the intro and the Go docs. Use go build to compile.\nThen run it.",
+		"- First item\n- Second item\n  3. Nested three",
+		"Quoted text\n\nSecond quote",
+		"\tfmt.Println(\"hi\")",
+		"Name | Speed\nfast|er | 1ms",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected text to contain %q, got:\n%s", want, got)
+		}
+	}
+
+	if strings.ContainsAny(got, "*>`[") {
+		t.Errorf("Plain text should not contain Markdown syntax, got:\n%s", got)
+	}
+}
+
+func TestRenderOutline(t *testing.T) {
+	fragment := `<div>
+<p>Intro before any heading.</p>
+<h2>Install</h2><p>Pick a platform.</p>
+<h3 id="linux-setup">Linux</h3><p>Use the package manager.</p>
+<h3>Windows</h3><p>Use the installer.</p>
+<h2>Install</h2><p>Again.</p>
+</div>`
+
+	rendering := renderText(parseFragment(t, fragment), nil)
+	outline := rendering.Outline
+
+	if len(outline) != 2 {
+		t.Fatalf("Expected 2 top-level sections, got %d", len(outline))
+	}
+	if len(outline[0].Children) != 2 {
+		t.Fatalf("Expected 2 subsections, got %d", len(outline[0].Children))
+	}
+
+	linux := outline[0].Children[0]
+	if linux.Anchor != "linux-setup" || linux.Level != 3 {
+		t.Errorf("Unexpected section %+v", linux)
+	}
+	if got := rendering.Text[linux.Start:linux.End]; got != "Linux\n\nUse the package manager.\n\n" {
+		t.Errorf("Unexpected section span %q", got)
+	}
+	if outline[0].End != outline[1].Start {
+		t.Errorf("Section should end where the next same-level heading starts")
+	}
+	if outline[1].Anchor != "install-1" {
+		t.Errorf("Expected duplicate anchor to be suffixed, got %q", outline[1].Anchor)
+	}
+
+	offset := strings.Index(rendering.Text, "Use the installer")
+	path := models.HeadingPath(outline, offset)
+	if strings.Join(path, " > ") != "Install > Windows" {
+		t.Errorf("Unexpected heading path %v", path)
+	}
+	if path := models.HeadingPath(outline, 0); len(path) != 0 {
+		t.Errorf("Text before the first heading should have no path, got %v", path)
+	}
+}
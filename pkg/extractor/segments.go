@@ -0,0 +1,147 @@
+package extractor
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/htmlutil"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// segmentExcerptLength bounds the text kept for a segment
+const segmentExcerptLength = 200
+
+// maxBoilerplateShare is the largest share of the page text a segment may
+// hold and still be treated as boilerplate. Anything larger is more likely
+// the article wearing a misleading class name such as "has-comments".
+const maxBoilerplateShare = 0.5
+
+// segmentRule labels elements whose id or class tokens match pattern
+type segmentRule struct {
+	label   models.SegmentLabel
+	pattern *regexp.Regexp
+}
+
+// segmentRules are checked in order; the first match wins
+var segmentRules = []segmentRule{
+	{models.SegmentComments, regexp.MustCompile(`(?i)^(comments?|comment[-_]?(list|area|section|thread|s[-_]?area|respond)|disqus[-_]?thread|respond|discussion|replies)$`)},
+	{models.SegmentBio, regexp.MustCompile(`(?i)^(author[-_]?(bio|box|info|card|profile|details)|about[-_]?(the[-_]?)?author|bio|byline[-_]?bio)$`)},
+	{models.SegmentRelated, regexp.MustCompile(`(?i)^(related([-_]?(posts?|articles?|content|stories))?|more[-_]?(posts|stories|articles)|recommended([-_]?posts)?|read[-_]?next|you[-_]?may[-_]?also[-_]?like|yarpp[-_\w]*|jp[-_]?relatedposts)$`)},
+	{models.SegmentCTA, regexp.MustCompile(`(?i)^(newsletter([-_]?(signup|form|box|cta))?|subscribe([-_]?(box|form))?|sign[-_]?up([-_]?form)?|cta|call[-_]?to[-_]?action|mc[-_]?embed[-_]?signup|opt[-_]?in|optin)$`)},
+	{models.SegmentNav, regexp.MustCompile(`(?i)^(nav|navbar|navigation|menu|main[-_]?menu|breadcrumbs?|pagination|pager|post[-_]?navigation|skip[-_]?link)$`)},
+}
+
+// segmentation is the result of labeling and removing page segments
+type segmentation struct {
+	segments []models.Segment
+	comments []*html.Node
+}
+
+// stripSegments labels the regions of doc and removes every region that is
+// not main content. Removed comment threads are returned so they can be
+// kept separately.
+func stripSegments(doc *html.Node) *segmentation {
+	result := &segmentation{}
+	pageLen := len(htmlutil.TextContent(doc))
+
+	var removed []*html.Node
+	htmlutil.Walk(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		label, ok := classifySegment(n)
+		if !ok {
+			return true
+		}
+
+		text := htmlutil.TextContent(n)
+		if label != models.SegmentMain && label != models.SegmentNav &&
+			pageLen > 0 && float64(len(text)) > maxBoilerplateShare*float64(pageLen) {
+			return true
+		}
+
+		result.segments = append(result.segments, models.Segment{
+			Label:   label,
+			Path:    htmlutil.NodePath(n),
+			Excerpt: excerpt(text, segmentExcerptLength),
+			Length:  len(text),
+		})
+
+		// Keep looking for boilerplate inside the main content
+		if label == models.SegmentMain {
+			return true
+		}
+		if label == models.SegmentComments {
+			result.comments = append(result.comments, n)
+		}
+		removed = append(removed, n)
+		return false
+	})
+
+	for _, n := range removed {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+
+	return result
+}
+
+// classifySegment labels an element by its tag, ARIA role, id and class names
+func classifySegment(n *html.Node) (models.SegmentLabel, bool) {
+	role := htmlutil.Attr(n, "role")
+	switch {
+	case n.Data == "article" || n.Data == "main" || role == "main":
+		return models.SegmentMain, true
+	case n.Data == "nav" || role == "navigation":
+		return models.SegmentNav, true
+	case n.Data == "body" || n.Data == "html" || n.Data == "head":
+		return "", false
+	}
+
+	tokens := strings.Fields(htmlutil.Attr(n, "class"))
+	if id := htmlutil.Attr(n, "id"); id != "" {
+		tokens = append(tokens, id)
+	}
+
+	for _, rule := range segmentRules {
+		for _, token := range tokens {
+			if rule.pattern.MatchString(token) {
+				return rule.label, true
+			}
+		}
+	}
+	return "", false
+}
+
+// commentsText renders removed comment threads as plain text
+func (s *segmentation) commentsText(p *page) string {
+	var threads []string
+	for _, n := range s.comments {
+		if text := strings.TrimSpace(renderText(n, p.url).Text); text != "" {
+			threads = append(threads, text)
+		}
+	}
+	if len(threads) == 0 {
+		return ""
+	}
+	return strings.Join(threads, "\n\n") + "\n"
+}
+
+// excerpt returns at most limit bytes of text, cut at a word boundary
+func excerpt(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := strings.LastIndex(text[:limit], " ")
+	if cut <= 0 {
+		cut = limit
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+	}
+	return text[:cut] + "…"
+}
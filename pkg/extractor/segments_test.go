@@ -0,0 +1,93 @@
+package extractor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// segmentedArticle surrounds testArticle's body with the regions a blog
+// theme typically adds around a post
+var segmentedArticle = strings.Replace(testArticle, `</article>`, `    <div class="author-bio"><p>Jane writes about runtimes and profilers.</p></div>
+        <div class="newsletter-signup"><p>Subscribe to our weekly newsletter for more tips.</p></div>
+    </article>
+    <aside class="related-posts"><ul><li><a href="/posts/escape-analysis">Escape analysis explained</a></li></ul></aside>
+    <section id="comments">
+        <div class="comment"><p>Setting GOMEMLIMIT fixed our OOM kills, thanks!</p></div>
+        <div class="comment"><p>What about GOGC=off with a memory limit?</p></div>
+    </section>`, 1)
+
+func TestExtractSegments(t *testing.T) {
+	content := extract(t, Config{}, segmentedArticle)
+
+	for _, boilerplate := range []string{"Jane writes", "weekly newsletter", "Escape analysis", "GOMEMLIMIT", "About"} {
+		if strings.Contains(content.Text, boilerplate) {
+			t.Errorf("Expected %q to be removed from the article text, got:\n%s", boilerplate, content.Text)
+		}
+	}
+	if !strings.Contains(content.Text, "The GOGC setting controls heap growth.") {
+		t.Errorf("Expected the article body to be kept, got:\n%s", content.Text)
+	}
+
+	labels := make(map[models.SegmentLabel]models.Segment)
+	for _, segment := range content.Segments {
+		labels[segment.Label] = segment
+	}
+	for _, want := range []models.SegmentLabel{
+		models.SegmentMain, models.SegmentNav, models.SegmentBio,
+		models.SegmentCTA, models.SegmentRelated, models.SegmentComments,
+	} {
+		if _, ok := labels[want]; !ok {
+			t.Errorf("Expected a %s segment in %+v", want, content.Segments)
+		}
+	}
+
+	comments := labels[models.SegmentComments]
+	if comments.Path != "/html[1]/body[1]/section[1]" {
+		t.Errorf("Unexpected comments path %q", comments.Path)
+	}
+	if !strings.HasPrefix(comments.Excerpt, "Setting GOMEMLIMIT") || comments.Length == 0 {
+		t.Errorf("Unexpected comments excerpt %q (length %d)", comments.Excerpt, comments.Length)
+	}
+
+	if content.Comments != "" {
+		t.Errorf("Comments should only be kept when KeepComments is set, got %q", content.Comments)
+	}
+}
+
+func TestExtractKeepComments(t *testing.T) {
+	content := extract(t, Config{KeepComments: true}, segmentedArticle)
+
+	if !strings.Contains(content.Comments, "Setting GOMEMLIMIT fixed our OOM kills, thanks!") ||
+		!strings.Contains(content.Comments, "What about GOGC=off") {
+		t.Errorf("Expected both comments to be kept, got %q", content.Comments)
+	}
+	if strings.Contains(content.Text, "GOMEMLIMIT") {
+		t.Errorf("Comments should stay out of the article text")
+	}
+}
+
+func TestStripSegmentsKeepsDominantRegion(t *testing.T) {
+	// A theme that marks the whole post container as "has comments" must not
+	// lose the article
+	page := strings.Replace(testArticle, `<article>`, `<div class="comments"><article>`, 1)
+	page = strings.Replace(page, `</article>`, `</article></div>`, 1)
+
+	content := extract(t, Config{}, page)
+	if !strings.Contains(content.Text, "The GOGC setting controls heap growth.") {
+		t.Errorf("Expected the article to survive, got:\n%s", content.Text)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	if got := excerpt("short text", 20); got != "short text" {
+		t.Errorf("excerpt() = %q", got)
+	}
+	if got := excerpt("one two three", 8); got != "one two…" {
+		t.Errorf("excerpt() = %q", got)
+	}
+	if got := excerpt("ééééé", 3); got != "é…" {
+		t.Errorf("excerpt() should not split runes, got %q", got)
+	}
+}
@@ -0,0 +1,180 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/htmlutil"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// ErrNoSiteRule is returned by SiteRuleExtractor for hosts without a rule
+var ErrNoSiteRule = errors.New("no site-specific rule for host")
+
+// SiteRuleExtractor extracts content using the CSS selectors configured for
+// a site. It is the most precise strategy when a rule exists and fails with
+// ErrNoSiteRule otherwise.
+type SiteRuleExtractor struct {
+	siteRules map[string]SiteRule
+	builder   contentBuilder
+}
+
+// NewSiteRuleExtractor creates a new SiteRuleExtractor with the given configuration
+func NewSiteRuleExtractor(config Config) *SiteRuleExtractor {
+	return newSiteRuleExtractor(config, newContentBuilder(config))
+}
+
+func newSiteRuleExtractor(config Config, builder contentBuilder) *SiteRuleExtractor {
+	return &SiteRuleExtractor{
+		siteRules: config.SiteSpecificRules,
+		builder:   builder,
+	}
+}
+
+// Close saves the templates learned by the extractor, if any
+func (e *SiteRuleExtractor) Close() error {
+	return e.builder.close()
+}
+
+// Extract extracts the element matched by the site's article selector
+func (e *SiteRuleExtractor) Extract(ctx context.Context, rawContent *models.RawContent) (*models.ExtractedContent, error) {
+	p, err := parsePage(ctx, rawContent)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname := p.url.Hostname()
+	rule, ok := lookupSiteRule(e.siteRules, hostname)
+	if !ok || rule.ArticleSelector == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNoSiteRule, hostname)
+	}
+
+	doc, segments, err := e.builder.cleanDoc(p)
+	if err != nil {
+		return nil, err
+	}
+
+	articleNode, err := selectFirst(doc, rule.ArticleSelector)
+	if err != nil {
+		return nil, err
+	}
+	if articleNode == nil {
+		return nil, fmt.Errorf("article selector %q matched nothing on %s", rule.ArticleSelector, rawContent.URL)
+	}
+
+	extracted := e.builder.build(p, articleNode, segments)
+	extracted.Title = documentTitle(p.doc)
+	applySiteRuleMetadata(extracted, p.doc, rule)
+
+	return extracted, nil
+}
+
+// lookupSiteRule returns the rule for hostname, ignoring a leading "www."
+func lookupSiteRule(rules map[string]SiteRule, hostname string) (SiteRule, bool) {
+	if rule, ok := rules[hostname]; ok {
+		return rule, true
+	}
+	rule, ok := rules[strings.TrimPrefix(hostname, "www.")]
+	return rule, ok
+}
+
+// applySiteRuleMetadata overrides the title, author and publication date
+// with the elements matched by the rule's selectors
+func applySiteRuleMetadata(content *models.ExtractedContent, doc *html.Node, rule SiteRule) {
+	if text := selectText(doc, rule.TitleSelector); text != "" {
+		content.Title = text
+	}
+	if text := selectText(doc, rule.AuthorSelector); text != "" {
+		content.Author = text
+	}
+	if node, _ := selectFirst(doc, rule.DateSelector); node != nil {
+		// Prefer the machine-readable datetime of <time> elements
+		if datetime := htmlutil.Attr(node, "datetime"); datetime != "" {
+			content.Published = datetime
+		} else if text := htmlutil.TextContent(node); text != "" {
+			content.Published = text
+		}
+	}
+}
+
+// selectFirst returns the first element matching a CSS selector. An empty
+// selector matches nothing.
+func selectFirst(doc *html.Node, selector string) (*html.Node, error) {
+	if selector == "" {
+		return nil, nil
+	}
+	sel, err := cascadia.Compile(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+	return sel.MatchFirst(doc), nil
+}
+
+func selectText(doc *html.Node, selector string) string {
+	node, err := selectFirst(doc, selector)
+	if err != nil || node == nil {
+		return ""
+	}
+	return htmlutil.TextContent(node)
+}
+
+// documentTitle returns the text of the page's <title> element
+func documentTitle(doc *html.Node) string {
+	for _, title := range htmlutil.FindAll(doc, "title") {
+		return htmlutil.TextContent(title)
+	}
+	return ""
+}
+
+// metadataProperties are the meta elements, by property or name, kept in
+// ExtractedContent.Metadata
+var metadataProperties = map[string]bool{
+	"og:site_name":           true,
+	"og:type":                true,
+	"og:locale":              true,
+	"application-name":       true,
+	"article:published_time": true,
+	"article:modified_time":  true,
+	"article:section":        true,
+}
+
+// documentMetadata returns the metadataProperties meta elements of doc. The
+// first element of each property wins.
+func documentMetadata(doc *html.Node) map[string]string {
+	metadata := make(map[string]string)
+	for _, meta := range htmlutil.FindAll(doc, "meta") {
+		key := htmlutil.Attr(meta, "property")
+		if key == "" {
+			key = strings.ToLower(htmlutil.Attr(meta, "name"))
+		}
+		content := strings.TrimSpace(htmlutil.Attr(meta, "content"))
+		if metadataProperties[key] && content != "" && metadata[key] == "" {
+			metadata[key] = content
+		}
+	}
+	return metadata
+}
+
+// documentDescription returns the description meta element of doc,
+// falling back to its Open Graph description
+func documentDescription(doc *html.Node) string {
+	var description, ogDescription string
+	for _, meta := range htmlutil.FindAll(doc, "meta") {
+		content := strings.TrimSpace(htmlutil.Attr(meta, "content"))
+		switch {
+		case strings.EqualFold(htmlutil.Attr(meta, "name"), "description") && description == "":
+			description = content
+		case htmlutil.Attr(meta, "property") == "og:description" && ogDescription == "":
+			ogDescription = content
+		}
+	}
+	if description != "" {
+		return description
+	}
+	return ogDescription
+}
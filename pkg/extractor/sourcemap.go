@@ -0,0 +1,86 @@
+package extractor
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/htmlutil"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// sourceMapWindow bounds how far ahead of the last aligned word the next
+// word is looked for, so a word missing from the text cannot pull the
+// alignment far ahead
+const sourceMapWindow = 256
+
+// buildSourceMap aligns the words of text, the rendering of root, with the
+// text nodes they came from. offsets holds the position of each text node
+// in the serialization of root, as returned by htmlutil.RenderWithOffsets.
+func buildSourceMap(text string, root *html.Node, offsets map[*html.Node]int) models.SourceMap {
+	var spans models.SourceMap
+	cursor := 0
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && skippedElements[n.Data] {
+			return
+		}
+		if n.Type == html.TextNode {
+			start, ok := offsets[n]
+			if !ok || n.Parent == nil {
+				return
+			}
+			path := htmlutil.RelativeNodePath(root, n.Parent)
+			for _, word := range wordRanges(n.Data) {
+				w := n.Data[word[0]:word[1]]
+				limit := min(cursor+sourceMapWindow+len(w), len(text))
+				i := strings.Index(text[cursor:limit], w)
+				if i < 0 {
+					continue
+				}
+				textStart := cursor + i
+				cursor = textStart + len(w)
+				htmlStart := start + len(html.EscapeString(n.Data[:word[0]]))
+				spans = append(spans, models.SourceSpan{
+					Path:      path,
+					Start:     textStart,
+					End:       cursor,
+					HTMLStart: htmlStart,
+					HTMLEnd:   htmlStart + len(html.EscapeString(w)),
+				})
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	return spans
+}
+
+// wordRanges returns the byte ranges of the whitespace-separated words of s
+func wordRanges(s string) [][2]int {
+	var ranges [][2]int
+	start := -1
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				ranges = append(ranges, [2]int{start, i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+		i += size
+	}
+	if start >= 0 {
+		ranges = append(ranges, [2]int{start, len(s)})
+	}
+	return ranges
+}
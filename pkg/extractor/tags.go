@@ -0,0 +1,156 @@
+package extractor
+
+import (
+	"encoding/json"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/htmlutil"
+)
+
+// defaultMaxKeyphrases is used when Config.MaxKeyphrases is left at zero
+const defaultMaxKeyphrases = 10
+
+// extractTags merges the categories a page declares about itself with
+// keyphrases extracted statistically from the article text.
+// On-page tags come first since they are curated by the author.
+func extractTags(doc *html.Node, text string, maxKeyphrases int) []string {
+	tags := []string{}
+	seen := make(map[string]bool)
+
+	add := func(tag string) {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] {
+			return
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	if doc != nil {
+		for _, tag := range pageTags(doc) {
+			add(tag)
+		}
+	}
+
+	if maxKeyphrases == 0 {
+		maxKeyphrases = defaultMaxKeyphrases
+	}
+	if maxKeyphrases > 0 {
+		for _, phrase := range extractKeyphrases(text, maxKeyphrases) {
+			add(phrase)
+		}
+	}
+
+	return tags
+}
+
+// pageTags collects tags from rel=tag links, article:tag and keywords meta
+// elements, and JSON-LD keywords
+func pageTags(doc *html.Node) []string {
+	var tags []string
+
+	htmlutil.Walk(doc, func(n *html.Node) bool {
+		switch {
+		case htmlutil.IsElement(n, "a") && hasToken(htmlutil.Attr(n, "rel"), "tag"):
+			tags = append(tags, htmlutil.TextContent(n))
+		case htmlutil.IsElement(n, "meta"):
+			content := htmlutil.Attr(n, "content")
+			switch {
+			case htmlutil.Attr(n, "property") == "article:tag":
+				tags = append(tags, content)
+			case strings.EqualFold(htmlutil.Attr(n, "name"), "keywords"):
+				tags = append(tags, strings.Split(content, ",")...)
+			}
+		case htmlutil.IsElement(n, "script") && htmlutil.Attr(n, "type") == "application/ld+json":
+			if n.FirstChild != nil {
+				tags = append(tags, jsonLDKeywords(n.FirstChild.Data)...)
+			}
+			return false
+		}
+		return true
+	})
+
+	return tags
+}
+
+// jsonLDKeywords returns every "keywords" value found in a JSON-LD document.
+// Keywords may be a comma separated string or an array, and may be nested
+// inside @graph or arrays of objects.
+func jsonLDKeywords(data string) []string {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		return nil
+	}
+
+	var keywords []string
+	var visit func(v interface{})
+	visit = func(v interface{}) {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for key, child := range val {
+				if key == "keywords" {
+					keywords = append(keywords, keywordValues(child)...)
+					continue
+				}
+				visit(child)
+			}
+		case []interface{}:
+			for _, child := range val {
+				visit(child)
+			}
+		}
+	}
+	visit(doc)
+
+	return keywords
+}
+
+func keywordValues(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		return strings.Split(val, ",")
+	case []interface{}:
+		var values []string
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// tagQuoteChars are stripped from both ends of a tag, and
+// tagTrailingChars from its end. Symbols such as '+', '#' and '.' are kept
+// elsewhere so tags like "c++", "c#" and ".net" survive normalization.
+const (
+	tagQuoteChars    = " \"'()[]{}"
+	tagTrailingChars = ".,;:!?"
+)
+
+// normalizeTag lowercases a tag, strips surrounding quotes, a leading
+// hashtag sign and trailing punctuation, and collapses internal whitespace
+// so variants of the same tag deduplicate
+func normalizeTag(tag string) string {
+	tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+	tag = strings.Trim(tag, tagQuoteChars)
+	tag = strings.TrimLeft(tag, "#")
+	tag = strings.TrimRight(tag, tagTrailingChars+tagQuoteChars)
+	if len(tag) > 64 {
+		return ""
+	}
+	return tag
+}
+
+// hasToken reports whether a space separated attribute value contains token
+func hasToken(value, token string) bool {
+	for _, field := range strings.Fields(value) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,328 @@
+package extractor
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/htmlutil"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+const (
+	// defaultTemplateMinPages is how many pages of a host must be seen
+	// before its template is used to strip anything
+	defaultTemplateMinPages = 5
+	// defaultTemplateThreshold is the share of pages a block must appear on
+	// to count as part of the site template
+	defaultTemplateThreshold = 0.6
+	// defaultTemplateDecay weights older pages down each time a new page is
+	// observed, so blocks that disappear from a redesigned site fade out
+	defaultTemplateDecay = 0.98
+	// minTemplateBlockText is the shortest text a block needs to be
+	// fingerprinted. Shorter blocks are too generic to match reliably.
+	minTemplateBlockText = 20
+	// maxTrackedPages bounds how many page versions a host remembers to
+	// avoid counting the same page twice
+	maxTrackedPages = 2000
+	// minBlockWeight drops fingerprints that have faded below relevance
+	minBlockWeight = 0.01
+)
+
+// templateBlockTags are the elements fingerprinted for template learning
+var templateBlockTags = map[string]bool{
+	"header": true, "footer": true, "nav": true, "aside": true, "section": true,
+	"div": true, "form": true, "ul": true, "ol": true, "p": true, "table": true,
+	"figure": true, "blockquote": true,
+}
+
+// TemplateConfig holds the tuning parameters of a TemplateStore. Zero values
+// use the defaults.
+type TemplateConfig struct {
+	// MinPages is how many pages of a host must be observed before blocks
+	// are stripped
+	MinPages int
+	// Threshold is the share of a host's pages, between 0 and 1, a block must
+	// appear on to be stripped
+	Threshold float64
+	// Decay is the weight, between 0 and 1, kept by earlier pages each time a
+	// page is observed. Lower values adapt to site changes faster.
+	Decay float64
+}
+
+// TemplateStore learns the site-wide template of each host from the pages
+// extracted for it. Blocks whose tag and text recur on most pages of a host,
+// such as banners, sidebars and footers, are stripped before extraction.
+// The store is safe for concurrent use and can be saved to and loaded from
+// a JSON file so the model carries over between runs.
+type TemplateStore struct {
+	mu     sync.Mutex
+	config TemplateConfig
+	hosts  map[string]*hostTemplate
+	// path is the file the store was loaded from, which Close saves to
+	path string
+}
+
+// hostTemplate is the learned template of one host. Observed counts the
+// pages seen; Weight and the block weights are decayed counts, and a
+// block's frequency is its weight divided by Weight.
+type hostTemplate struct {
+	Observed int                `json:"observed"`
+	Weight   float64            `json:"weight"`
+	Blocks   map[string]float64 `json:"blocks"`
+	// Versions maps a page URL hash to the hash of the HTML last observed
+	// for it
+	Versions map[string]string `json:"versions"`
+}
+
+// templateFile is the on-disk format of a TemplateStore
+type templateFile struct {
+	Hosts map[string]*hostTemplate `json:"hosts"`
+}
+
+// NewTemplateStore creates an empty TemplateStore
+func NewTemplateStore(config TemplateConfig) *TemplateStore {
+	if config.MinPages <= 0 {
+		config.MinPages = defaultTemplateMinPages
+	}
+	if config.Threshold <= 0 || config.Threshold > 1 {
+		config.Threshold = defaultTemplateThreshold
+	}
+	if config.Decay <= 0 || config.Decay > 1 {
+		config.Decay = defaultTemplateDecay
+	}
+	return &TemplateStore{config: config, hosts: make(map[string]*hostTemplate)}
+}
+
+// LoadTemplateStore reads a TemplateStore saved with Save. A missing file
+// yields an empty store. Close saves the store back to path.
+func LoadTemplateStore(path string, config TemplateConfig) (*TemplateStore, error) {
+	store := NewTemplateStore(config)
+	store.path = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template store: %w", err)
+	}
+
+	var file templateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse template store: %w", err)
+	}
+	for host, tmpl := range file.Hosts {
+		if tmpl.Blocks == nil {
+			tmpl.Blocks = make(map[string]float64)
+		}
+		if tmpl.Versions == nil {
+			tmpl.Versions = make(map[string]string)
+		}
+		store.hosts[host] = tmpl
+	}
+
+	return store, nil
+}
+
+// Save writes the store to path as JSON. The file is replaced atomically.
+func (s *TemplateStore) Save(path string) error {
+	s.mu.Lock()
+	data, err := json.Marshal(templateFile{Hosts: s.hosts})
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode template store: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create template store directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write template store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write template store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write template store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write template store: %w", err)
+	}
+	return nil
+}
+
+// Close saves the store to the file it was loaded from. It does nothing for
+// a store created with NewTemplateStore.
+func (s *TemplateStore) Close() error {
+	if s.path == "" {
+		return nil
+	}
+	return s.Save(s.path)
+}
+
+// Pages returns the number of pages observed for host
+func (s *TemplateStore) Pages(host string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if tmpl, ok := s.hosts[templateHost(host)]; ok {
+		return tmpl.Observed
+	}
+	return 0
+}
+
+// Observe adds the blocks of a page to the template of its host. Observing
+// the same version of a page again has no effect, so every extraction
+// strategy can observe the page it works on.
+func (s *TemplateStore) Observe(pageURL, host string, doc *html.Node) {
+	fingerprints := make(map[string]bool)
+	for _, block := range templateBlocks(doc) {
+		fingerprints[block.fingerprint] = true
+	}
+	version := hashString(htmlutil.Render(doc))
+	urlKey := hashString(pageURL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	host = templateHost(host)
+	tmpl, ok := s.hosts[host]
+	if !ok {
+		tmpl = &hostTemplate{Blocks: make(map[string]float64), Versions: make(map[string]string)}
+		s.hosts[host] = tmpl
+	}
+	if tmpl.Versions[urlKey] == version {
+		return
+	}
+	if len(tmpl.Versions) >= maxTrackedPages {
+		// Forgetting versions only risks counting a page twice
+		tmpl.Versions = make(map[string]string)
+	}
+	tmpl.Versions[urlKey] = version
+
+	decay := s.config.Decay
+	tmpl.Observed++
+	tmpl.Weight = tmpl.Weight*decay + 1
+	for fp, weight := range tmpl.Blocks {
+		weight *= decay
+		if weight < minBlockWeight {
+			delete(tmpl.Blocks, fp)
+			continue
+		}
+		tmpl.Blocks[fp] = weight
+	}
+	for fp := range fingerprints {
+		tmpl.Blocks[fp]++
+	}
+}
+
+// Strip removes the template blocks of host from doc and returns a segment
+// for each removed block. Nothing is removed until the host has enough
+// pages, and a block holding most of the page text is always kept.
+func (s *TemplateStore) Strip(host string, doc *html.Node) []models.Segment {
+	s.mu.Lock()
+	template := make(map[string]bool)
+	if tmpl, ok := s.hosts[templateHost(host)]; ok && tmpl.Observed >= s.config.MinPages {
+		for fp, weight := range tmpl.Blocks {
+			if weight/tmpl.Weight >= s.config.Threshold {
+				template[fp] = true
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if len(template) == 0 {
+		return nil
+	}
+
+	pageLen := len(htmlutil.TextContent(doc))
+	var segments []models.Segment
+	var removed []*html.Node
+	for _, block := range templateBlocks(doc) {
+		if !template[block.fingerprint] || isInside(block.node, removed) {
+			continue
+		}
+		if float64(len(block.text)) > maxBoilerplateShare*float64(pageLen) {
+			continue
+		}
+		segments = append(segments, models.Segment{
+			Label:   models.SegmentTemplate,
+			Path:    htmlutil.NodePath(block.node),
+			Excerpt: excerpt(block.text, segmentExcerptLength),
+			Length:  len(block.text),
+		})
+		removed = append(removed, block.node)
+	}
+
+	for _, n := range removed {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+	return segments
+}
+
+// templateBlock is a fingerprinted element of a page
+type templateBlock struct {
+	node        *html.Node
+	text        string
+	fingerprint string
+}
+
+// templateBlocks returns the fingerprintable blocks of doc in document
+// order. Article and main elements are not fingerprinted since they hold the
+// content that differs from page to page, but their descendants are.
+func templateBlocks(doc *html.Node) []templateBlock {
+	var blocks []templateBlock
+	htmlutil.Walk(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || !templateBlockTags[n.Data] {
+			return true
+		}
+		text := htmlutil.TextContent(n)
+		if len(text) < minTemplateBlockText {
+			return true
+		}
+		blocks = append(blocks, templateBlock{
+			node:        n,
+			text:        text,
+			fingerprint: hashString(n.Data + "\x00" + strings.ToLower(text)),
+		})
+		return true
+	})
+	return blocks
+}
+
+// isInside reports whether n is one of nodes or a descendant of one
+func isInside(n *html.Node, nodes []*html.Node) bool {
+	for node := n; node != nil; node = node.Parent {
+		for _, candidate := range nodes {
+			if node == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// templateHost normalizes a host name so www and bare hosts share a template
+func templateHost(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+func hashString(s string) string {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
@@ -0,0 +1,193 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// sitePage renders a page of a site whose banner and sidebar repeat on
+// every page while the article differs
+func sitePage(n int, banner string) string {
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head><title>Post %[1]d</title></head>
+<body>
+    <div class="masthead"><p>%[2]s</p></div>
+    <div class="wrapper">
+        <article>
+            <h1>Post %[1]d</h1>
+            <p>This is post number %[1]d. It discusses topic %[1]d at length, explaining why topic %[1]d
+            matters and how readers can apply what they learn about topic %[1]d in their own projects.</p>
+            <p>The second paragraph of post %[1]d continues the discussion with examples that only make
+            sense for topic %[1]d, so no two posts on this site share any of their body text.</p>
+        </article>
+        <div class="widget"><p>All content copyright Example Media, reproduced with permission.</p></div>
+    </div>
+</body>
+</html>`, n, banner)
+}
+
+func parseTestDoc(t *testing.T, page string) *html.Node {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("Failed to parse page: %v", err)
+	}
+	return doc
+}
+
+func TestTemplateStoreStripsSiteBoilerplate(t *testing.T) {
+	store := NewTemplateStore(TemplateConfig{MinPages: 4})
+	e := NewReadabilityExtractor(Config{Templates: store})
+
+	extractPage := func(n int) *models.ExtractedContent {
+		content, err := e.Extract(context.Background(), &models.RawContent{
+			URL:  fmt.Sprintf("https://www.example.com/posts/%d", n),
+			HTML: sitePage(n, "Free shipping on all orders over fifty dollars this week"),
+		})
+		if err != nil {
+			t.Fatalf("Failed to extract page %d: %v", n, err)
+		}
+		return content
+	}
+
+	// Extracting a page twice must not count it twice
+	extractPage(1)
+	extractPage(1)
+	if pages := store.Pages("example.com"); pages != 1 {
+		t.Errorf("Expected one observed page, got %d", pages)
+	}
+
+	for n := 2; n <= 5; n++ {
+		extractPage(n)
+	}
+
+	content := extractPage(6)
+	if strings.Contains(content.Text, "copyright Example Media") || strings.Contains(content.Text, "Free shipping") {
+		t.Errorf("Expected the site template to be stripped, got:\n%s", content.Text)
+	}
+	if !strings.Contains(content.Text, "This is post number 6.") {
+		t.Errorf("Expected the article to be kept, got:\n%s", content.Text)
+	}
+
+	var templates int
+	for _, segment := range content.Segments {
+		if segment.Label == models.SegmentTemplate {
+			templates++
+		}
+	}
+	if templates == 0 {
+		t.Errorf("Expected template segments in %+v", content.Segments)
+	}
+}
+
+func TestTemplateStoreMinPages(t *testing.T) {
+	store := NewTemplateStore(TemplateConfig{MinPages: 3})
+	banner := "Free shipping on all orders over fifty dollars this week"
+
+	for n := 1; n <= 2; n++ {
+		store.Observe(fmt.Sprintf("https://example.com/%d", n), "example.com", parseTestDoc(t, sitePage(n, banner)))
+	}
+	if segments := store.Strip("example.com", parseTestDoc(t, sitePage(3, banner))); len(segments) != 0 {
+		t.Errorf("Nothing should be stripped before MinPages pages are seen, got %+v", segments)
+	}
+}
+
+func TestTemplateStoreAdaptsToSiteChanges(t *testing.T) {
+	store := NewTemplateStore(TemplateConfig{MinPages: 2, Decay: 0.5})
+	oldBanner := "Spring sale: everything twenty percent off"
+	newBanner := "Summer sale: everything thirty percent off"
+
+	for n := 1; n <= 5; n++ {
+		store.Observe(fmt.Sprintf("https://example.com/%d", n), "example.com", parseTestDoc(t, sitePage(n, oldBanner)))
+	}
+	for n := 6; n <= 10; n++ {
+		store.Observe(fmt.Sprintf("https://example.com/%d", n), "example.com", parseTestDoc(t, sitePage(n, newBanner)))
+	}
+
+	doc := parseTestDoc(t, sitePage(11, oldBanner+" "+newBanner))
+	store.Strip("example.com", doc)
+	text := renderText(doc, nil).Text
+	if strings.Contains(text, "copyright Example Media") {
+		t.Errorf("Expected the stable footer to be stripped, got:\n%s", text)
+	}
+
+	doc = parseTestDoc(t, sitePage(12, oldBanner))
+	store.Strip("example.com", doc)
+	if text := renderText(doc, nil).Text; !strings.Contains(text, "Spring sale") {
+		t.Errorf("A block that left the template should no longer be stripped, got:\n%s", text)
+	}
+}
+
+func TestTemplateStoreSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "templates.json")
+	banner := "Free shipping on all orders over fifty dollars this week"
+
+	store := NewTemplateStore(TemplateConfig{MinPages: 2})
+	for n := 1; n <= 3; n++ {
+		store.Observe(fmt.Sprintf("https://example.com/%d", n), "example.com", parseTestDoc(t, sitePage(n, banner)))
+	}
+	if err := store.Save(path); err != nil {
+		t.Fatalf("Failed to save template store: %v", err)
+	}
+
+	loaded, err := LoadTemplateStore(path, TemplateConfig{MinPages: 2})
+	if err != nil {
+		t.Fatalf("Failed to load template store: %v", err)
+	}
+	if loaded.Pages("www.example.com") != store.Pages("example.com") {
+		t.Errorf("Expected %d pages after loading, got %d", store.Pages("example.com"), loaded.Pages("example.com"))
+	}
+	if segments := loaded.Strip("example.com", parseTestDoc(t, sitePage(4, banner))); len(segments) == 0 {
+		t.Errorf("Expected the loaded template to strip the banner")
+	}
+
+	empty, err := LoadTemplateStore(filepath.Join(t.TempDir(), "missing.json"), TemplateConfig{})
+	if err != nil || empty.Pages("example.com") != 0 {
+		t.Errorf("Expected an empty store for a missing file, got %v", err)
+	}
+}
+
+func TestExtractorCloseSavesTemplates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "templates", "store.json")
+	store, err := LoadTemplateStore(path, TemplateConfig{})
+	if err != nil {
+		t.Fatalf("Failed to load template store: %v", err)
+	}
+	e, err := NewChainExtractor(Config{Templates: store})
+	if err != nil {
+		t.Fatalf("Failed to create extractor: %v", err)
+	}
+	for n := 1; n <= 3; n++ {
+		raw := &models.RawContent{URL: fmt.Sprintf("https://example.com/%d", n), HTML: sitePage(n, "Free shipping on all orders over fifty dollars this week")}
+		if _, err := e.Extract(context.Background(), raw); err != nil {
+			t.Fatalf("Failed to extract page %d: %v", n, err)
+		}
+	}
+	// The strategies share the store but only the chain saves it
+	for _, strategy := range e.strategies {
+		strategy.Extractor.(io.Closer).Close()
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected closing a strategy to leave the store unsaved, got %v", err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Failed to close extractor: %v", err)
+	}
+
+	// The next run starts from the pages observed by this one
+	loaded, err := LoadTemplateStore(path, TemplateConfig{})
+	if err != nil || loaded.Pages("example.com") != 3 {
+		t.Errorf("Expected 3 saved pages, got %d, %v", loaded.Pages("example.com"), err)
+	}
+}
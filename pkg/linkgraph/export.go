@@ -0,0 +1,125 @@
+package linkgraph
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// WriteCSV writes one row per edge with a header of source, target, text
+// and internal
+func (g *Graph) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"source", "target", "text", "internal"}); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	for _, edge := range g.Edges() {
+		if err := cw.Write([]string{edge.Source, edge.Target, edge.Text, strconv.FormatBool(edge.Internal)}); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// graphML is the subset of the GraphML schema the export uses
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the graph as a directed GraphML document. Nodes are
+// identified by URL and carry their title and whether the page was added;
+// edges carry the anchor text and whether the link is internal.
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	g.mu.RLock()
+	edges := g.edges()
+	nodes := make(map[string]bool)
+	for page := range g.pages {
+		nodes[page] = true
+	}
+	for _, edge := range edges {
+		nodes[edge.Target] = true
+	}
+	ids := make([]string, 0, len(nodes))
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "title", For: "node", Name: "title", Type: "string"},
+			{ID: "crawled", For: "node", Name: "crawled", Type: "boolean"},
+			{ID: "text", For: "edge", Name: "text", Type: "string"},
+			{ID: "internal", For: "edge", Name: "internal", Type: "boolean"},
+		},
+		Graph: graphMLGraph{EdgeDefault: "directed"},
+	}
+	for _, id := range ids {
+		title, crawled := g.pages[id]
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: id, Data: []graphMLData{
+			{Key: "title", Value: title},
+			{Key: "crawled", Value: strconv.FormatBool(crawled)},
+		}})
+	}
+	g.mu.RUnlock()
+
+	for _, edge := range edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{Source: edge.Source, Target: edge.Target, Data: []graphMLData{
+			{Key: "text", Value: edge.Text},
+			{Key: "internal", Value: strconv.FormatBool(edge.Internal)},
+		}})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write GraphML: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write GraphML: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write GraphML: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+// Package linkgraph records the links between extracted pages across the
+// corpus. The graph ranks pages, finds orphans, suggests related posts, and
+// exports to CSV and GraphML for analysis in other tools.
+package linkgraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/internal/urlutil"
+)
+
+const (
+	// DefaultDamping is the PageRank damping factor
+	DefaultDamping = 0.85
+	// DefaultIterations is the number of PageRank iterations
+	DefaultIterations = 50
+)
+
+// Edge is a link from one page to another. URLs are normalized.
+type Edge struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Text     string `json:"text,omitempty"`
+	Internal bool   `json:"internal"`
+}
+
+// Graph is a directed link graph of the pages extracted so far. Pages are
+// the documents that were added; link targets that were never added are
+// still nodes of the graph but have no outlinks. Graph is safe for
+// concurrent use.
+type Graph struct {
+	mu    sync.RWMutex
+	pages map[string]string
+	links map[string][]Edge
+}
+
+// graphFile is the on-disk format of a Graph
+type graphFile struct {
+	Pages map[string]string `json:"pages"`
+	Links map[string][]Edge `json:"links"`
+}
+
+// New creates an empty Graph
+func New() *Graph {
+	return &Graph{pages: make(map[string]string), links: make(map[string][]Edge)}
+}
+
+// Load reads a Graph saved with Save. A missing file yields an empty graph.
+func Load(path string) (*Graph, error) {
+	g := New()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return g, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read link graph: %w", err)
+	}
+
+	var file graphFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse link graph: %w", err)
+	}
+	for page, title := range file.Pages {
+		g.pages[page] = title
+	}
+	for page, edges := range file.Links {
+		g.links[page] = edges
+	}
+
+	return g, nil
+}
+
+// Save writes the graph to path as JSON. The file is replaced atomically.
+func (g *Graph) Save(path string) error {
+	g.mu.RLock()
+	data, err := json.Marshal(graphFile{Pages: g.pages, Links: g.links})
+	g.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode link graph: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write link graph: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write link graph: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write link graph: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write link graph: %w", err)
+	}
+	return nil
+}
+
+// AddPage records an extracted page and its outlinks. Adding a page again
+// replaces the links recorded for it, so the graph follows edits.
+func (g *Graph) AddPage(content *models.ExtractedContent) error {
+	source, err := urlutil.Normalize(content.URL)
+	if err != nil {
+		return fmt.Errorf("invalid page URL %q: %w", content.URL, err)
+	}
+
+	edges := make([]Edge, 0, len(content.Links))
+	for _, link := range content.Links {
+		if link.URL == source {
+			continue
+		}
+		edges = append(edges, Edge{Source: source, Target: link.URL, Text: link.Text, Internal: link.Internal})
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.pages[source] = content.Title
+	g.links[source] = edges
+	return nil
+}
+
+// Pages returns the URLs of the pages added to the graph, sorted
+func (g *Graph) Pages() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	pages := make([]string, 0, len(g.pages))
+	for page := range g.pages {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	return pages
+}
+
+// Edges returns every edge of the graph ordered by source and target
+func (g *Graph) Edges() []Edge {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.edges()
+}
+
+func (g *Graph) edges() []Edge {
+	var edges []Edge
+	for _, out := range g.links {
+		edges = append(edges, out...)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Source != edges[j].Source {
+			return edges[i].Source < edges[j].Source
+		}
+		return edges[i].Target < edges[j].Target
+	})
+	return edges
+}
+
+// Inlinks returns the edges pointing to page from added pages
+func (g *Graph) Inlinks(page string) []Edge {
+	if normalized, err := urlutil.Normalize(page); err == nil {
+		page = normalized
+	}
+
+	var inlinks []Edge
+	for _, edge := range g.Edges() {
+		if edge.Target == page {
+			inlinks = append(inlinks, edge)
+		}
+	}
+	return inlinks
+}
+
+// Orphans returns the added pages that no other added page links to, sorted
+func (g *Graph) Orphans() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	linked := make(map[string]bool)
+	for source, edges := range g.links {
+		for _, edge := range edges {
+			if edge.Target != source {
+				linked[edge.Target] = true
+			}
+		}
+	}
+
+	var orphans []string
+	for page := range g.pages {
+		if !linked[page] {
+			orphans = append(orphans, page)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
+}
+
+// PageRank ranks the added pages by the links between them. Links to pages
+// outside the graph are ignored, and pages without outlinks spread their
+// rank evenly. Zero arguments use DefaultDamping and DefaultIterations.
+func (g *Graph) PageRank(damping float64, iterations int) map[string]float64 {
+	if damping <= 0 || damping >= 1 {
+		damping = DefaultDamping
+	}
+	if iterations <= 0 {
+		iterations = DefaultIterations
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	n := float64(len(g.pages))
+	if n == 0 {
+		return map[string]float64{}
+	}
+
+	out := make(map[string][]string, len(g.pages))
+	for page := range g.pages {
+		seen := make(map[string]bool)
+		for _, edge := range g.links[page] {
+			if _, ok := g.pages[edge.Target]; ok && !seen[edge.Target] {
+				seen[edge.Target] = true
+				out[page] = append(out[page], edge.Target)
+			}
+		}
+	}
+
+	rank := make(map[string]float64, len(g.pages))
+	for page := range g.pages {
+		rank[page] = 1 / n
+	}
+
+	for i := 0; i < iterations; i++ {
+		dangling := 0.0
+		for page := range g.pages {
+			if len(out[page]) == 0 {
+				dangling += rank[page]
+			}
+		}
+
+		next := make(map[string]float64, len(g.pages))
+		for page := range g.pages {
+			next[page] = (1-damping)/n + damping*dangling/n
+		}
+		for page, targets := range out {
+			share := damping * rank[page] / float64(len(targets))
+			for _, target := range targets {
+				next[target] += share
+			}
+		}
+		rank = next
+	}
+
+	return rank
+}
+
+// Related returns up to limit added pages most closely linked to page.
+// Pages score for linking to or from page, for being cited by the same
+// pages, and for citing the same targets. Ties are broken by URL.
+func (g *Graph) Related(page string, limit int) []string {
+	if normalized, err := urlutil.Normalize(page); err == nil {
+		page = normalized
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	scores := make(map[string]float64)
+	targets := make(map[string]bool)
+	for _, edge := range g.links[page] {
+		targets[edge.Target] = true
+		scores[edge.Target]++
+	}
+
+	for source, edges := range g.links {
+		if source == page {
+			continue
+		}
+		citesPage := false
+		for _, edge := range edges {
+			if edge.Target == page {
+				citesPage = true
+			}
+		}
+		if citesPage {
+			scores[source]++
+		}
+
+		for _, edge := range edges {
+			// Co-citation: source links to page and to edge.Target
+			if citesPage && edge.Target != page {
+				scores[edge.Target] += 0.5
+			}
+			// Coupling: source and page link to the same target
+			if targets[edge.Target] {
+				scores[source] += 0.5
+			}
+		}
+	}
+
+	var related []string
+	for candidate := range scores {
+		if _, ok := g.pages[candidate]; ok && candidate != page {
+			related = append(related, candidate)
+		}
+	}
+	sort.Slice(related, func(i, j int) bool {
+		if scores[related[i]] != scores[related[j]] {
+			return scores[related[i]] > scores[related[j]]
+		}
+		return related[i] < related[j]
+	})
+
+	if limit > 0 && len(related) > limit {
+		related = related[:limit]
+	}
+	return related
+}
@@ -0,0 +1,143 @@
+package linkgraph
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+)
+
+// testGraph builds a small blog: the index links to both posts, post a
+// links to post b and an external site, and post c is linked from nowhere
+func testGraph(t *testing.T) *Graph {
+	t.Helper()
+
+	g := New()
+	pages := []*models.ExtractedContent{
+		{URL: "https://example.com/", Title: "Home", Links: []models.Link{
+			{URL: "https://example.com/a", Text: "Post A", Internal: true},
+			{URL: "https://example.com/b", Text: "Post B", Internal: true},
+		}},
+		{URL: "https://example.com/a", Title: "Post A", Links: []models.Link{
+			{URL: "https://example.com/b", Text: "see B", Internal: true},
+			{URL: "https://go.dev/doc/gc-guide", Text: "GC guide, \"official\"", Internal: false},
+		}},
+		{URL: "https://example.com/b", Title: "Post B", Links: []models.Link{
+			{URL: "https://go.dev/doc/gc-guide", Text: "GC guide", Internal: false},
+		}},
+		{URL: "https://EXAMPLE.com/c?utm_source=feed", Title: "Post C"},
+	}
+	for _, page := range pages {
+		if err := g.AddPage(page); err != nil {
+			t.Fatalf("Failed to add page: %v", err)
+		}
+	}
+	return g
+}
+
+func TestOrphans(t *testing.T) {
+	g := testGraph(t)
+
+	orphans := g.Orphans()
+	if strings.Join(orphans, " ") != "https://example.com/ https://example.com/c" {
+		t.Errorf("Unexpected orphans %v", orphans)
+	}
+	if inlinks := g.Inlinks("https://example.com/b"); len(inlinks) != 2 {
+		t.Errorf("Expected 2 inlinks to post B, got %+v", inlinks)
+	}
+}
+
+func TestAddPageReplacesLinks(t *testing.T) {
+	g := testGraph(t)
+
+	if err := g.AddPage(&models.ExtractedContent{URL: "https://example.com/a", Title: "Post A"}); err != nil {
+		t.Fatalf("Failed to add page: %v", err)
+	}
+	if inlinks := g.Inlinks("https://example.com/b"); len(inlinks) != 1 {
+		t.Errorf("Expected the old links of post A to be replaced, got %+v", inlinks)
+	}
+	if err := g.AddPage(&models.ExtractedContent{URL: "not a url"}); err == nil {
+		t.Errorf("Expected an error for an invalid page URL")
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	rank := testGraph(t).PageRank(0, 0)
+
+	if len(rank) != 4 {
+		t.Fatalf("Expected a rank for every added page, got %v", rank)
+	}
+	total := 0.0
+	for _, r := range rank {
+		total += r
+	}
+	if total < 0.999 || total > 1.001 {
+		t.Errorf("Ranks should sum to 1, got %f", total)
+	}
+	if rank["https://example.com/b"] <= rank["https://example.com/a"] ||
+		rank["https://example.com/a"] <= rank["https://example.com/c"] {
+		t.Errorf("Expected b > a > c, got %v", rank)
+	}
+}
+
+func TestRelated(t *testing.T) {
+	related := testGraph(t).Related("https://example.com/a", 2)
+
+	// B is linked from A, co-cited with A by the index and shares a target
+	if len(related) != 2 || related[0] != "https://example.com/b" || related[1] != "https://example.com/" {
+		t.Errorf("Unexpected related pages %v", related)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.json")
+	g := testGraph(t)
+
+	if err := g.Save(path); err != nil {
+		t.Fatalf("Failed to save graph: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load graph: %v", err)
+	}
+	if len(loaded.Pages()) != 4 || len(loaded.Edges()) != len(g.Edges()) {
+		t.Errorf("Loaded graph differs: %v / %d edges", loaded.Pages(), len(loaded.Edges()))
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testGraph(t).WriteCSV(&buf); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV back: %v", err)
+	}
+	if len(records) != 6 || strings.Join(records[0], ",") != "source,target,text,internal" {
+		t.Fatalf("Unexpected CSV %v", records)
+	}
+	if records[4][2] != `GC guide, "official"` || records[4][3] != "false" {
+		t.Errorf("Unexpected row %v", records[4])
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testGraph(t).WriteGraphML(&buf); err != nil {
+		t.Fatalf("Failed to write GraphML: %v", err)
+	}
+
+	var doc graphML
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to parse GraphML: %v\n%s", err, buf.String())
+	}
+	if doc.Graph.EdgeDefault != "directed" || len(doc.Graph.Nodes) != 5 || len(doc.Graph.Edges) != 5 {
+		t.Errorf("Unexpected graph with %d nodes and %d edges", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+}
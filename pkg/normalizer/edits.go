@@ -0,0 +1,117 @@
+package normalizer
+
+import (
+	"sort"
+	"strings"
+)
+
+// Edit replaces the bytes Start to End of a text with Replacement. An edit
+// with Start == End inserts text.
+type Edit struct {
+	Replacement string
+	Start       int
+	End         int
+}
+
+// OffsetMap maps every byte offset of an input text, including its length,
+// to the corresponding offset of the text it was rewritten to. Offsets
+// inside a replaced range map to the start of its replacement.
+type OffsetMap []int
+
+// Map returns the output offset of input offset i. Offsets outside the
+// input are clamped to it.
+func (m OffsetMap) Map(i int) int {
+	if len(m) == 0 {
+		return 0
+	}
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(m) {
+		i = len(m) - 1
+	}
+	return m[i]
+}
+
+// Then returns the map of applying m and then next
+func (m OffsetMap) Then(next OffsetMap) OffsetMap {
+	composed := make(OffsetMap, len(m))
+	for i, offset := range m {
+		composed[i] = next.Map(offset)
+	}
+	return composed
+}
+
+// Invert returns the map from offsets of the output text, of length n, back
+// to the first input offset mapped to each. Offsets inside a replacement
+// map to the start of the replaced range.
+func (m OffsetMap) Invert(n int) OffsetMap {
+	inverse := make(OffsetMap, n+1)
+	for i := range inverse {
+		inverse[i] = -1
+	}
+	for i := len(m) - 1; i >= 0; i-- {
+		if m[i] >= 0 && m[i] <= n {
+			inverse[m[i]] = i
+		}
+	}
+	for j := range inverse {
+		if inverse[j] < 0 {
+			inverse[j] = 0
+			if j > 0 {
+				inverse[j] = inverse[j-1]
+			}
+		}
+	}
+	return inverse
+}
+
+// identityMap returns the map of a text of length n left unchanged
+func identityMap(n int) OffsetMap {
+	m := make(OffsetMap, n+1)
+	for i := range m {
+		m[i] = i
+	}
+	return m
+}
+
+// ApplyEdits applies edits to text and returns the result with the map of
+// text offsets to result offsets. Edits are applied in order of their start
+// offset; an edit overlapping an earlier one is skipped.
+func ApplyEdits(text string, edits []Edit) (string, OffsetMap) {
+	if len(edits) == 0 {
+		return text, identityMap(len(text))
+	}
+
+	sorted := make([]Edit, len(edits))
+	copy(sorted, edits)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	var out strings.Builder
+	out.Grow(len(text))
+	m := make(OffsetMap, len(text)+1)
+
+	pos := 0
+	copyTo := func(end int) {
+		for ; pos < end; pos++ {
+			m[pos] = out.Len()
+			out.WriteByte(text[pos])
+		}
+	}
+
+	for _, edit := range sorted {
+		if edit.Start < pos || edit.End < edit.Start || edit.End > len(text) {
+			continue
+		}
+		copyTo(edit.Start)
+		start := out.Len()
+		for ; pos < edit.End; pos++ {
+			m[pos] = start
+		}
+		out.WriteString(edit.Replacement)
+	}
+	copyTo(len(text))
+	m[len(text)] = out.Len()
+
+	return out.String(), m
+}
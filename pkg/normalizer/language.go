@@ -0,0 +1,145 @@
+package normalizer
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// RuleSet holds the normalization rules specific to a language. Rules
+// rewrite the normalized text. Match rules only apply to MatchKey, for
+// folding that would lose information in the text itself, such as German
+// "ß" to "ss".
+type RuleSet struct {
+	Rules []Rule
+	Match []Rule
+}
+
+var languages = struct {
+	sync.RWMutex
+	sets map[string]RuleSet
+}{sets: make(map[string]RuleSet)}
+
+// RegisterLanguage sets the rules used for content in language, a primary
+// language subtag such as "de". It replaces any rules registered before.
+func RegisterLanguage(lang string, set RuleSet) {
+	languages.Lock()
+	defer languages.Unlock()
+	languages.sets[primaryLanguage(lang)] = set
+}
+
+// languageRules returns the rules registered for a language tag such as
+// "de-AT", matched by its primary subtag
+func languageRules(lang string) RuleSet {
+	languages.RLock()
+	defer languages.RUnlock()
+	return languages.sets[primaryLanguage(lang)]
+}
+
+func primaryLanguage(lang string) string {
+	primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(lang)), "-")
+	primary, _, _ = strings.Cut(primary, "_")
+	return primary
+}
+
+func init() {
+	cjk := RuleSet{Rules: []Rule{foldWidth}}
+	RegisterLanguage("zh", cjk)
+	RegisterLanguage("ja", cjk)
+	RegisterLanguage("ko", cjk)
+
+	RegisterLanguage("de", RuleSet{Match: []Rule{foldGerman}})
+
+	turkic := RuleSet{Rules: []Rule{fixDottedI}}
+	RegisterLanguage("tr", turkic)
+	RegisterLanguage("az", turkic)
+
+	RegisterLanguage("ar", RuleSet{Rules: []Rule{stripArabicMarks}, Match: []Rule{foldArabicLetters}})
+}
+
+// MatchKey returns the form of text used to match it against other text in
+// the same language: NFKC-normalized, lowercased with the casing rules of
+// lang, folded by its Match rules and with whitespace collapsed. The key is
+// for comparison only and is not meant to be displayed.
+func MatchKey(text, lang string) string {
+	key := norm.NFKC.String(text)
+	key = cases.Lower(language.Make(primaryLanguage(lang))).String(key)
+	for _, r := range languageRules(lang).Match {
+		key, _ = ApplyEdits(key, r(key))
+	}
+	return strings.Join(strings.Fields(key), " ")
+}
+
+// foldWidth replaces full-width forms of ASCII characters with ASCII and
+// half-width katakana and Hangul with their usual full-width forms
+func foldWidth(text string) []Edit {
+	return mapRunes(text, func(_ int, r rune) (string, bool) {
+		if folded := width.LookupRune(r).Folded(); folded != 0 {
+			return string(folded), true
+		}
+		return "", false
+	})
+}
+
+// germanFolds spells umlauts and sharp s the way they are written when the
+// letters are unavailable, so "Straße" matches "Strasse"
+var germanFolds = map[rune]string{
+	'ß': "ss", 'ä': "ae", 'ö': "oe", 'ü': "ue",
+}
+
+func foldGerman(text string) []Edit {
+	return mapRunes(text, func(_ int, r rune) (string, bool) {
+		replacement, ok := germanFolds[r]
+		return replacement, ok
+	})
+}
+
+// fixDottedI removes the combining dot above that locale-unaware
+// lowercasing of "İ" leaves after "i", as in "i\u0307stanbul"
+func fixDottedI(text string) []Edit {
+	return mapRunes(text, func(i int, r rune) (string, bool) {
+		return "", r == '\u0307' && i > 0 && text[i-1] == 'i'
+	})
+}
+
+// isArabicMark reports whether r is an Arabic diacritic, Quranic
+// annotation mark or the tatweel used to stretch words
+func isArabicMark(r rune) bool {
+	switch {
+	case r == '\u0640': // tatweel
+		return true
+	case r >= '\u064B' && r <= '\u065F': // harakat
+		return true
+	case r == '\u0670': // superscript alef
+		return true
+	case r >= '\u06D6' && r <= '\u06ED' && unicode.Is(unicode.Mn, r): // Quranic marks
+		return true
+	}
+	return false
+}
+
+func stripArabicMarks(text string) []Edit {
+	return mapRunes(text, func(_ int, r rune) (string, bool) {
+		return "", isArabicMark(r)
+	})
+}
+
+// arabicFolds unifies letter variants that are often written
+// interchangeably: hamzated alefs, alef maksura and ta marbuta
+var arabicFolds = map[rune]string{
+	'\u0622': "\u0627", '\u0623': "\u0627", '\u0625': "\u0627", '\u0671': "\u0627", // alef variants
+	'\u0649': "\u064A", // alef maksura to ya
+	'\u0629': "\u0647", // ta marbuta to ha
+}
+
+func foldArabicLetters(text string) []Edit {
+	return mapRunes(text, func(_ int, r rune) (string, bool) {
+		replacement, ok := arabicFolds[r]
+		return replacement, ok
+	})
+}
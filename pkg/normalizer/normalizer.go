@@ -0,0 +1,296 @@
+// Package normalizer cleans extracted text into the consistent form the
+// chunker and embedder expect. Normalization is a sequence of rules, each
+// producing edits; applying the edits keeps a map from extracted to
+// normalized offsets so the outline and special blocks stay aligned with
+// the text. Code blocks and tables are copied through unchanged. Rules
+// specific to a language are registered with RegisterLanguage and chosen by
+// the content's language.
+package normalizer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/pii"
+)
+
+// Config holds configuration for the normalizer
+type Config struct {
+	// UnicodeForm is the normalization form applied when NormalizeUnicode is
+	// set: "NFC" (the default), "NFKC", "NFD" or "NFKD"
+	UnicodeForm      string
+	NormalizeUnicode bool
+	// TrimWhitespace collapses runs of spaces, limits blank lines to one and
+	// trims the text
+	TrimWhitespace bool
+	// UnifyPunctuation replaces smart quotes and dash variants
+	UnifyPunctuation bool
+	// DecodeEntities decodes HTML character references left in the text
+	DecodeEntities bool
+	// StripInvisible removes control and zero-width characters
+	StripInvisible bool
+	// PreserveLists keeps list markers and the indentation of nested lists.
+	// Otherwise lists are flattened into plain lines.
+	PreserveLists bool
+	// PreserveHeadings keeps the heading outline of the content
+	PreserveHeadings bool
+	// LanguageRules applies the rules registered for the content's language
+	// with RegisterLanguage
+	LanguageRules bool
+	// PII, when set, redacts personal data according to its policy
+	PII *pii.Config
+}
+
+// DefaultConfig returns a configuration with every normalization enabled
+func DefaultConfig() Config {
+	return Config{
+		UnicodeForm:      "NFC",
+		NormalizeUnicode: true,
+		TrimWhitespace:   true,
+		UnifyPunctuation: true,
+		DecodeEntities:   true,
+		StripInvisible:   true,
+		PreserveLists:    true,
+		PreserveHeadings: true,
+		LanguageRules:    true,
+	}
+}
+
+// Normalizer implements the models.Normalizer interface
+type Normalizer struct {
+	// rules run before language rules, which run before final
+	rules            []Rule
+	final            []Rule
+	trim             bool
+	preserveHeadings bool
+	languageRules    bool
+	pii              *pii.Detector
+}
+
+// New creates a Normalizer with the given configuration
+func New(config Config) (*Normalizer, error) {
+	n := &Normalizer{
+		trim:             config.TrimWhitespace,
+		preserveHeadings: config.PreserveHeadings,
+		languageRules:    config.LanguageRules,
+	}
+
+	// Entities are decoded first so the characters they produce are
+	// normalized like any other
+	if config.DecodeEntities {
+		n.rules = append(n.rules, decodeEntities)
+	}
+	// Language rules come next so characters they decompose, such as
+	// half-width voiced katakana, are recomposed by the Unicode form
+	if config.NormalizeUnicode {
+		form, err := parseForm(config.UnicodeForm)
+		if err != nil {
+			return nil, err
+		}
+		n.final = append(n.final, unicodeForm(form))
+	}
+	if config.StripInvisible {
+		n.final = append(n.final, stripInvisible)
+	}
+	if config.UnifyPunctuation {
+		n.final = append(n.final, unifyPunctuation)
+	}
+	if config.TrimWhitespace {
+		n.final = append(n.final, collapseWhitespace(config.PreserveLists))
+	}
+	if config.PII != nil {
+		detector, err := pii.New(*config.PII)
+		if err != nil {
+			return nil, err
+		}
+		n.pii = detector
+	}
+
+	return n, nil
+}
+
+func parseForm(name string) (norm.Form, error) {
+	switch strings.ToUpper(name) {
+	case "", "NFC":
+		return norm.NFC, nil
+	case "NFKC":
+		return norm.NFKC, nil
+	case "NFD":
+		return norm.NFD, nil
+	case "NFKD":
+		return norm.NFKD, nil
+	}
+	return 0, fmt.Errorf("unknown Unicode normalization form %q", name)
+}
+
+// Normalize normalizes the text of extracted content
+func (n *Normalizer) Normalize(ctx context.Context, content *models.ExtractedContent) (*models.NormalizedContent, error) {
+	// Check if context is canceled
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	text, offsets := n.normalizeText(content.Text, content.Blocks, n.rulesFor(content.Language))
+
+	// Personal data is redacted last so no later rule can reassemble it
+	var redactions []models.Redaction
+	if n.pii != nil {
+		var redactedOffsets OffsetMap
+		text, redactedOffsets, redactions = redact(n.pii, text)
+		offsets = offsets.Then(redactedOffsets)
+	}
+
+	normalized := &models.NormalizedContent{
+		ID:         models.DocumentID(content.URL),
+		Original:   content,
+		Text:       text,
+		Blocks:     remapBlocks(content.Blocks, offsets),
+		Offsets:    offsets.Invert(len(text)),
+		Redactions: redactions,
+	}
+	if n.preserveHeadings {
+		normalized.Outline = remapOutline(content.Outline, offsets)
+	}
+	if n.pii != nil {
+		redactBlocks(n.pii, text, normalized.Blocks)
+		redactOutline(n.pii, normalized.Outline)
+	}
+
+	return normalized, nil
+}
+
+// rulesFor returns the rules applied to content in language
+func (n *Normalizer) rulesFor(language string) []Rule {
+	rules := n.rules[:len(n.rules):len(n.rules)]
+	if n.languageRules {
+		rules = append(rules, languageRules(language).Rules...)
+	}
+	return append(rules, n.final...)
+}
+
+// normalizeText applies rules to the text between blocks, copies the
+// blocks unchanged, and returns the result with its offset map
+func (n *Normalizer) normalizeText(text string, blocks []models.Block, rules []Rule) (string, OffsetMap) {
+	protected := protectedRanges(text, blocks)
+
+	var out strings.Builder
+	offsets := make(OffsetMap, len(text)+1)
+
+	pos := 0
+	emit := func(end int, normalize bool) {
+		piece := text[pos:end]
+		pieceMap := identityMap(len(piece))
+		if normalize {
+			piece, pieceMap = n.apply(piece, rules, pos == 0, end == len(text))
+		}
+		base := out.Len()
+		for i := 0; i < end-pos; i++ {
+			offsets[pos+i] = base + pieceMap[i]
+		}
+		out.WriteString(piece)
+		pos = end
+	}
+
+	for _, r := range protected {
+		emit(r[0], true)
+		emit(r[1], false)
+	}
+	emit(len(text), true)
+	offsets[len(text)] = out.Len()
+
+	return out.String(), offsets
+}
+
+// apply runs rules over text. first and last report whether text starts
+// or ends the document, where surrounding whitespace is trimmed.
+func (n *Normalizer) apply(text string, rules []Rule, first, last bool) (string, OffsetMap) {
+	offsets := identityMap(len(text))
+	if n.trim {
+		rules = append(rules[:len(rules):len(rules)], trimEnds(first, last))
+	}
+
+	for _, r := range rules {
+		edits := r(text)
+		if len(edits) == 0 {
+			continue
+		}
+		var m OffsetMap
+		text, m = ApplyEdits(text, edits)
+		offsets = offsets.Then(m)
+	}
+	return text, offsets
+}
+
+// trimEnds returns a rule trimming whitespace at the ends of the document
+func trimEnds(start, end bool) Rule {
+	return func(text string) []Edit {
+		var edits []Edit
+		for _, edit := range trimText(text) {
+			if (edit.Start == 0 && start) || (edit.End == len(text) && end) {
+				edits = append(edits, edit)
+			}
+		}
+		return edits
+	}
+}
+
+// protectedRanges returns the sorted, non-overlapping spans of blocks
+func protectedRanges(text string, blocks []models.Block) [][2]int {
+	var ranges [][2]int
+	for _, block := range blocks {
+		if block.Start < 0 || block.End > len(text) || block.Start >= block.End {
+			continue
+		}
+		ranges = append(ranges, [2]int{block.Start, block.End})
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+
+	merged := ranges[:0]
+	for _, r := range ranges {
+		if len(merged) > 0 && r[0] < merged[len(merged)-1][1] {
+			if r[1] > merged[len(merged)-1][1] {
+				merged[len(merged)-1][1] = r[1]
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// remapBlocks returns copies of blocks with offsets into the normalized text
+func remapBlocks(blocks []models.Block, offsets OffsetMap) []models.Block {
+	if blocks == nil {
+		return nil
+	}
+	remapped := make([]models.Block, len(blocks))
+	for i, block := range blocks {
+		block.Start = offsets.Map(block.Start)
+		block.End = offsets.Map(block.End)
+		remapped[i] = block
+	}
+	return remapped
+}
+
+// remapOutline returns a copy of outline with offsets into the normalized text
+func remapOutline(outline []*models.Section, offsets OffsetMap) []*models.Section {
+	if outline == nil {
+		return nil
+	}
+	remapped := make([]*models.Section, len(outline))
+	for i, section := range outline {
+		s := *section
+		s.Start = offsets.Map(section.Start)
+		s.End = offsets.Map(section.End)
+		s.Children = remapOutline(section.Children, offsets)
+		remapped[i] = &s
+	}
+	return remapped
+}
@@ -0,0 +1,315 @@
+package normalizer
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/pii"
+)
+
+func normalize(t *testing.T, config Config, content *models.ExtractedContent) *models.NormalizedContent {
+	t.Helper()
+
+	n, err := New(config)
+	if err != nil {
+		t.Fatalf("Failed to create normalizer: %v", err)
+	}
+	normalized, err := n.Normalize(context.Background(), content)
+	if err != nil {
+		t.Fatalf("Failed to normalize content: %v", err)
+	}
+	return normalized
+}
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"whitespace", "  Hello   world\t\tagain  \n\n\n\nNext  ", "Hello world again\n\nNext"},
+		{"quotes and dashes", "\u201CSmart\u201D quotes \u2018here\u2019 \u2013 and 1\u201210", `"Smart" quotes 'here' - and 1-10`},
+		{"entities", "Fish &amp; chips &#8211; &copy; &bogus;", "Fish & chips - © &bogus;"},
+		{"nfc", "cafe\u0301", "caf\u00E9"},
+		{"invisible", "zero\u200Bwidth\u00ADsoft\x07bell\uFEFF", "zerowidthsoftbell"},
+		{"line endings", "one\r\ntwo\rthree four", "one\ntwo\nthree\nfour"},
+		{"unicode spaces", "non\u00A0breaking\u3000space", "non breaking space"},
+		{"nested lists", "- one\n  - nested   item\n    continued\n1. first", "- one\n  - nested item\n    continued\n1. first"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalize(t, DefaultConfig(), &models.ExtractedContent{Text: tt.in}).Text
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeNFKC(t *testing.T) {
+	config := DefaultConfig()
+	config.UnicodeForm = "NFKC"
+
+	got := normalize(t, config, &models.ExtractedContent{Text: "ﬁle ① Ａ"}).Text
+	if got != "file 1 A" {
+		t.Errorf("Expected compatibility forms to be folded, got %q", got)
+	}
+
+	config.UnicodeForm = "NFX"
+	if _, err := New(config); err == nil {
+		t.Errorf("Expected an error for an unknown normalization form")
+	}
+}
+
+func TestNormalizeFlattensLists(t *testing.T) {
+	config := DefaultConfig()
+	config.PreserveLists = false
+
+	got := normalize(t, config, &models.ExtractedContent{Text: "Steps:\n- one\n  2. two"}).Text
+	if got != "Steps:\none\ntwo" {
+		t.Errorf("Expected flattened list, got %q", got)
+	}
+}
+
+func TestNormalizeKeepsBlocksAndOutline(t *testing.T) {
+	code := "func main() {\n\tfmt.Println(\"a  “b”\")\n}"
+	text := "Intro  “quoted”\n\n\n" + code + "\n\nSetup   steps\n\nMore   text  "
+	codeStart := strings.Index(text, code)
+	setupStart := strings.Index(text, "Setup")
+
+	content := &models.ExtractedContent{
+		Text: text,
+		Blocks: []models.Block{{
+			Kind:  models.BlockCode,
+			Text:  code,
+			Start: codeStart,
+			End:   codeStart + len(code),
+		}},
+		Outline: []*models.Section{{
+			Heading: "Setup steps",
+			Level:   2,
+			Start:   setupStart,
+			End:     len(text),
+		}},
+	}
+
+	normalized := normalize(t, DefaultConfig(), content)
+
+	want := "Intro \"quoted\"\n\n" + code + "\n\nSetup steps\n\nMore text"
+	if normalized.Text != want {
+		t.Fatalf("Unexpected text %q", normalized.Text)
+	}
+
+	block := normalized.Blocks[0]
+	if normalized.Text[block.Start:block.End] != code {
+		t.Errorf("Code block should be unchanged and aligned, got %q", normalized.Text[block.Start:block.End])
+	}
+	if content.Blocks[0].Start != codeStart {
+		t.Errorf("The extracted blocks must not be modified")
+	}
+
+	section := normalized.Outline[0]
+	if got := normalized.Text[section.Start:section.End]; got != "Setup steps\n\nMore text" {
+		t.Errorf("Outline should be remapped, got %q", got)
+	}
+
+	config := DefaultConfig()
+	config.PreserveHeadings = false
+	if normalize(t, config, content).Outline != nil {
+		t.Errorf("Outline should be dropped when headings are not preserved")
+	}
+}
+
+func TestNormalizeID(t *testing.T) {
+	normalized := normalize(t, DefaultConfig(), &models.ExtractedContent{URL: "HTTPS://Example.com/a?utm_source=x"})
+	if want := models.DocumentID("https://example.com/a"); normalized.ID != want {
+		t.Errorf("Expected the ID of the canonical URL %q, got %q", want, normalized.ID)
+	}
+}
+
+func TestNormalizeCanceled(t *testing.T) {
+	n, _ := New(DefaultConfig())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := n.Normalize(ctx, &models.ExtractedContent{}); err == nil {
+		t.Errorf("Expected an error for a canceled context")
+	}
+}
+
+func TestApplyEdits(t *testing.T) {
+	text := "abcdef"
+	got, m := ApplyEdits(text, []Edit{
+		{Start: 4, End: 6, Replacement: "XYZ"},
+		{Start: 1, End: 2},
+		{Start: 1, End: 3, Replacement: "overlap"},
+		{Start: 3, End: 3, Replacement: "+"},
+	})
+
+	if got != "ac+dXYZ" {
+		t.Fatalf("ApplyEdits() = %q", got)
+	}
+	for in, want := range map[int]int{0: 0, 1: 1, 2: 1, 3: 3, 4: 4, 5: 4, 6: 7} {
+		if m.Map(in) != want {
+			t.Errorf("Map(%d) = %d, want %d", in, m.Map(in), want)
+		}
+	}
+	inverse := m.Invert(len(got))
+	for out, want := range map[int]int{0: 0, 1: 1, 3: 3, 4: 4, 6: 4, 7: 6} {
+		if inverse.Map(out) != want {
+			t.Errorf("Invert().Map(%d) = %d, want %d", out, inverse.Map(out), want)
+		}
+	}
+}
+
+func TestNormalizeCite(t *testing.T) {
+	content := &models.ExtractedContent{
+		URL:     "https://example.com/quotes#top",
+		Content: "<p>He said  <b>\u201Chello\u201D</b> twice</p>",
+		Text:    "He said  \u201Chello\u201D twice",
+		SourceMap: models.SourceMap{
+			{Path: "/p[1]", Start: 0, End: 2, HTMLStart: 3, HTMLEnd: 5},
+			{Path: "/p[1]", Start: 3, End: 7, HTMLStart: 6, HTMLEnd: 10},
+			{Path: "/p[1]/b[1]", Start: 9, End: 20, HTMLStart: 15, HTMLEnd: 26},
+			{Path: "/p[1]", Start: 21, End: 26, HTMLStart: 31, HTMLEnd: 36},
+		},
+	}
+	normalized := normalize(t, DefaultConfig(), content)
+	if normalized.Text != `He said "hello" twice` {
+		t.Fatalf("Normalize() = %q", normalized.Text)
+	}
+
+	start := strings.Index(normalized.Text, `"hello"`)
+	citation := normalized.Cite(start, len(normalized.Text))
+
+	if citation.Quote != "\u201Chello\u201D twice" {
+		t.Errorf("Quote = %q", citation.Quote)
+	}
+	if got := content.Content[citation.HTMLStart:citation.HTMLEnd]; got != "\u201Chello\u201D</b> twice" {
+		t.Errorf("HTML span = %q", got)
+	}
+	if citation.Path != "/p[1]/b[1]" {
+		t.Errorf("Path = %q", citation.Path)
+	}
+	if want := "https://example.com/quotes#:~:text=%E2%80%9Chello%E2%80%9D%20twice"; citation.URL != want {
+		t.Errorf("URL = %q, want %q", citation.URL, want)
+	}
+}
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// TestLanguageGolden normalizes testdata/language/<lang>.txt as content in
+// that language and compares the text and match key with the golden files
+func TestLanguageGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "language", "*.txt"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("No golden inputs found: %v", err)
+	}
+
+	for _, input := range inputs {
+		lang := strings.TrimSuffix(filepath.Base(input), ".txt")
+		t.Run(lang, func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatalf("Failed to read input: %v", err)
+			}
+			normalized := normalize(t, DefaultConfig(), &models.ExtractedContent{Text: string(data), Language: lang})
+
+			base := strings.TrimSuffix(input, ".txt")
+			checkGolden(t, base+".golden", normalized.Text+"\n")
+			checkGolden(t, base+".match.golden", MatchKey(normalized.Text, lang)+"\n")
+		})
+	}
+}
+
+func checkGolden(t *testing.T, path, got string) {
+	t.Helper()
+
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file: %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s mismatch:\ngot:  %q\nwant: %q", path, got, want)
+	}
+}
+
+func TestLanguageRulesDisabled(t *testing.T) {
+	config := DefaultConfig()
+	config.LanguageRules = false
+
+	// "jamila" stretched with tatweel
+	text := "\u062C\u0645\u0640\u0640\u064A\u0644\u0629"
+	got := normalize(t, config, &models.ExtractedContent{Text: text, Language: "ar"}).Text
+	if got != text {
+		t.Errorf("Normalize() = %q, want the text unchanged", got)
+	}
+	got = normalize(t, DefaultConfig(), &models.ExtractedContent{Text: text, Language: "ar-EG"}).Text
+	if want := "\u062C\u0645\u064A\u0644\u0629"; got != want {
+		t.Errorf("Normalize() = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeRedactsPII(t *testing.T) {
+	config := DefaultConfig()
+	config.PII = &pii.Config{Actions: map[pii.Kind]pii.Action{pii.Phone: pii.Mask}}
+
+	text := "Contact  jane@example.com or +1 415-555-0132.\n\nconfig: admin@example.org\n\nThanks"
+	start := strings.Index(text, "config")
+	content := &models.ExtractedContent{
+		URL:  "https://example.com/about",
+		Text: text,
+		Blocks: []models.Block{{
+			Kind:     models.BlockCode,
+			Text:     "config: admin@example.org",
+			Markdown: "```\nconfig: admin@example.org\n```",
+			Start:    start,
+			End:      start + len("config: admin@example.org"),
+		}},
+	}
+
+	normalized := normalize(t, config, content)
+	want := "Contact [EMAIL] or +* ***-***-0132.\n\nconfig: [EMAIL]\n\nThanks"
+	if normalized.Text != want {
+		t.Fatalf("Normalize() = %q, want %q", normalized.Text, want)
+	}
+
+	kinds := []string{"email", "phone", "email"}
+	if len(normalized.Redactions) != len(kinds) {
+		t.Fatalf("Redactions = %+v", normalized.Redactions)
+	}
+	for i, r := range normalized.Redactions {
+		if r.Kind != kinds[i] {
+			t.Errorf("Redaction %d kind = %q, want %q", i, r.Kind, kinds[i])
+		}
+		if replaced := normalized.Text[r.Start:r.End]; strings.Contains(replaced, "@") || strings.Contains(replaced, "415") {
+			t.Errorf("Redaction %d covers %q", i, replaced)
+		}
+	}
+	if got := normalized.RedactionsIn(0, 10); len(got) != 1 || got[0].Kind != "email" {
+		t.Errorf("RedactionsIn(0, 10) = %+v", got)
+	}
+
+	block := normalized.Blocks[0]
+	if block.Text != "config: [EMAIL]" || block.Markdown != "```\nconfig: [EMAIL]\n```" {
+		t.Errorf("Block not redacted: %+v", block)
+	}
+	if content.Blocks[0].Text != "config: admin@example.org" {
+		t.Errorf("Original block was modified: %+v", content.Blocks[0])
+	}
+	if citation := normalized.Cite(block.Start, block.End); citation.Start != start {
+		t.Errorf("Cite() start = %d, want %d", citation.Start, start)
+	}
+}
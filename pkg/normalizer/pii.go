@@ -0,0 +1,66 @@
+package normalizer
+
+import (
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/pii"
+)
+
+// redact replaces the personal data detector finds in text and returns the
+// result with its offset map and the redactions made. Unlike the other
+// rules it also applies inside code blocks and tables.
+func redact(detector *pii.Detector, text string) (string, OffsetMap, []models.Redaction) {
+	matches := detector.Find(text)
+	if len(matches) == 0 {
+		return text, identityMap(len(text)), nil
+	}
+
+	edits := make([]Edit, len(matches))
+	for i, m := range matches {
+		edits[i] = Edit{Start: m.Start, End: m.End, Replacement: detector.Replacement(m)}
+	}
+	redacted, offsets := ApplyEdits(text, edits)
+
+	redactions := make([]models.Redaction, len(matches))
+	for i, m := range matches {
+		start := offsets.Map(m.Start)
+		redactions[i] = models.Redaction{
+			Kind:   string(m.Kind),
+			Action: string(detector.Action(m.Kind)),
+			Start:  start,
+			End:    start + len(edits[i].Replacement),
+		}
+	}
+	return redacted, offsets, redactions
+}
+
+// redactBlocks updates blocks, already remapped to the redacted text, so
+// their text, Markdown and cells no longer hold the redacted data
+func redactBlocks(detector *pii.Detector, text string, blocks []models.Block) {
+	for i := range blocks {
+		block := &blocks[i]
+		if block.Start >= 0 && block.End <= len(text) && block.Start <= block.End {
+			block.Text = text[block.Start:block.End]
+		}
+		block.Markdown = detector.Redact(block.Markdown)
+
+		// The rows are shared with the original block
+		rows := make([][]string, len(block.Rows))
+		for j, row := range block.Rows {
+			rows[j] = make([]string, len(row))
+			for k, cell := range row {
+				rows[j][k] = detector.Redact(cell)
+			}
+		}
+		if block.Rows != nil {
+			block.Rows = rows
+		}
+	}
+}
+
+// redactOutline redacts the headings of a remapped outline
+func redactOutline(detector *pii.Detector, outline []*models.Section) {
+	for _, section := range outline {
+		section.Heading = detector.Redact(section.Heading)
+		redactOutline(detector, section.Children)
+	}
+}
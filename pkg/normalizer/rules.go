@@ -0,0 +1,218 @@
+package normalizer
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Rule computes the edits of one normalization step. Rules receive the text
+// between protected blocks and must not depend on text outside it.
+type Rule func(text string) []Edit
+
+// entityPattern matches named and numeric character references
+var entityPattern = regexp.MustCompile(`&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+
+// decodeEntities replaces HTML character references left in the text, such
+// as "&amp;" from double-escaped pages
+func decodeEntities(text string) []Edit {
+	var edits []Edit
+	for _, loc := range entityPattern.FindAllStringIndex(text, -1) {
+		entity := text[loc[0]:loc[1]]
+		if decoded := html.UnescapeString(entity); decoded != entity {
+			edits = append(edits, Edit{Start: loc[0], End: loc[1], Replacement: decoded})
+		}
+	}
+	return edits
+}
+
+// unicodeForm returns a rule normalizing text to form
+func unicodeForm(form norm.Form) Rule {
+	return func(text string) []Edit {
+		if form.IsNormalString(text) {
+			return nil
+		}
+
+		var edits []Edit
+		var it norm.Iter
+		it.InitString(form, text)
+		for !it.Done() {
+			start := it.Pos()
+			segment := it.Next()
+			if string(segment) != text[start:it.Pos()] {
+				edits = append(edits, Edit{Start: start, End: it.Pos(), Replacement: string(segment)})
+			}
+		}
+		return edits
+	}
+}
+
+// punctuation unifies typographic quotes and dashes
+var punctuation = map[rune]string{
+	'\u2018': "'", '\u2019': "'", '\u201A': "'", '\u201B': "'", '\u2032': "'", // single quotes, prime
+	'\u201C': `"`, '\u201D': `"`, '\u201E': `"`, '\u201F': `"`, '\u2033': `"`, // double quotes, double prime
+	'\u00AB': `"`, '\u00BB': `"`, // guillemets
+	'\u2010': "-", '\u2011': "-", '\u2012': "-", '\u2013': "-", '\u2212': "-", // hyphens, en dash, minus
+	'\u2015': "\u2014", // horizontal bar to em dash
+}
+
+// unifyPunctuation replaces curly quotes with straight ones, hyphen and en
+// dash variants with "-" and the horizontal bar with an em dash
+func unifyPunctuation(text string) []Edit {
+	return mapRunes(text, func(_ int, r rune) (string, bool) {
+		replacement, ok := punctuation[r]
+		return replacement, ok
+	})
+}
+
+// zeroWidth are invisible characters that break tokenization and matching
+var zeroWidth = map[rune]bool{
+	'\u200B': true, // zero width space
+	'\u200C': true, // zero width non-joiner
+	'\u200D': true, // zero width joiner
+	'\u2060': true, // word joiner
+	'\uFEFF': true, // byte order mark
+	'\u00AD': true, // soft hyphen
+	'\u180E': true, // Mongolian vowel separator
+}
+
+// stripInvisible removes control characters other than newlines and tabs,
+// zero-width characters and soft hyphens, turns carriage returns and
+// line separators into newlines, and Unicode space variants into spaces
+func stripInvisible(text string) []Edit {
+	return mapRunes(text, func(i int, r rune) (string, bool) {
+		switch {
+		case r == '\n' || r == '\t' || r == ' ':
+			return "", false
+		case r == '\r' && strings.HasPrefix(text[i+1:], "\n"):
+			return "", true
+		case r == '\r':
+			return "\n", true
+		case r == '\u2028' || r == '\u2029':
+			return "\n", true
+		case zeroWidth[r] || r == utf8.RuneError:
+			return "", true
+		case unicode.IsControl(r) || unicode.Is(unicode.Cf, r):
+			return "", true
+		case unicode.IsSpace(r):
+			return " ", true
+		}
+		return "", false
+	})
+}
+
+// mapRunes returns an edit for every rune replace reports a replacement for.
+// replace receives the byte offset of the rune.
+func mapRunes(text string, replace func(i int, r rune) (string, bool)) []Edit {
+	var edits []Edit
+	for i, r := range text {
+		if replacement, ok := replace(i, r); ok {
+			size := utf8.RuneLen(r)
+			if r == utf8.RuneError {
+				_, size = utf8.DecodeRuneInString(text[i:])
+			}
+			edits = append(edits, Edit{Start: i, End: i + size, Replacement: replacement})
+		}
+	}
+	return edits
+}
+
+// listMarker matches the marker of a list item line after its indentation
+var listMarker = regexp.MustCompile(`^(?:[-*+]|\d{1,9}[.)]) `)
+
+// collapseWhitespace collapses runs of spaces and tabs within lines, trims
+// trailing spaces and limits blank lines to one. Leading
+// indentation of lines is kept when keepIndent is set so nested lists keep
+// their structure; otherwise it is removed along with list markers.
+func collapseWhitespace(keepIndent bool) Rule {
+	return func(text string) []Edit {
+		var edits []Edit
+		blank := 0
+
+		lineStart := 0
+		for lineStart <= len(text) {
+			lineEnd := strings.IndexByte(text[lineStart:], '\n')
+			if lineEnd < 0 {
+				lineEnd = len(text)
+			} else {
+				lineEnd += lineStart
+			}
+			line := text[lineStart:lineEnd]
+			content := strings.TrimLeft(line, " \t")
+			indent := len(line) - len(content)
+
+			// An empty first line continues whatever precedes the text,
+			// such as a code block, rather than being a blank line
+			if lineStart == 0 && line == "" {
+				blank = 0
+			} else if strings.TrimSpace(line) == "" {
+				blank++
+				// Drop the spaces of blank lines and every blank line after the first
+				end := lineEnd
+				if blank > 1 && lineEnd < len(text) {
+					end = lineEnd + 1
+				}
+				if end > lineStart {
+					edits = append(edits, Edit{Start: lineStart, End: end})
+				}
+			} else {
+				blank = 0
+				if !keepIndent && indent > 0 {
+					edits = append(edits, Edit{Start: lineStart, End: lineStart + indent})
+				}
+				if !keepIndent {
+					if marker := listMarker.FindString(content); marker != "" {
+						edits = append(edits, Edit{Start: lineStart + indent, End: lineStart + indent + len(marker)})
+					}
+				}
+				edits = append(edits, collapseRuns(text, lineStart+indent, lineEnd)...)
+			}
+
+			if lineEnd == len(text) {
+				break
+			}
+			lineStart = lineEnd + 1
+		}
+
+		return edits
+	}
+}
+
+// collapseRuns replaces runs of spaces and tabs between start and end with
+// a single space and removes them at the end of the line
+func collapseRuns(text string, start, end int) []Edit {
+	var edits []Edit
+	for i := start; i < end; i++ {
+		if text[i] != ' ' && text[i] != '\t' {
+			continue
+		}
+		j := i
+		for j < end && (text[j] == ' ' || text[j] == '\t') {
+			j++
+		}
+		switch {
+		case j == end:
+			edits = append(edits, Edit{Start: i, End: j})
+		case j-i > 1 || text[i] == '\t':
+			edits = append(edits, Edit{Start: i, End: j, Replacement: " "})
+		}
+		i = j
+	}
+	return edits
+}
+
+// trimText removes leading and trailing whitespace of the whole text
+func trimText(text string) []Edit {
+	var edits []Edit
+	if lead := len(text) - len(strings.TrimLeft(text, " \t\n")); lead > 0 {
+		edits = append(edits, Edit{Start: 0, End: lead})
+	}
+	if trailing := len(text) - len(strings.TrimRight(text, " \t\n")); trailing > 0 && trailing < len(text) {
+		edits = append(edits, Edit{Start: len(text) - trailing, End: len(text)})
+	}
+	return edits
+}
@@ -0,0 +1,279 @@
+// Package pii finds personal data such as email addresses, phone numbers
+// and payment card numbers in text and computes the replacement a
+// redaction policy calls for. Candidates are found with patterns and
+// confirmed with checksum or format validators to keep false positives,
+// such as order numbers that look like card numbers, out.
+package pii
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind identifies a type of personal data
+type Kind string
+
+const (
+	// Email is an email address
+	Email Kind = "email"
+	// Phone is a phone number
+	Phone Kind = "phone"
+	// CreditCard is a payment card number that passes the Luhn check
+	CreditCard Kind = "credit_card"
+	// IP is an IPv4 or IPv6 address
+	IP Kind = "ip"
+	// IBAN is an international bank account number with a valid checksum
+	IBAN Kind = "iban"
+)
+
+// Kinds lists every kind in the order matches are resolved: when two
+// matches overlap, the one whose kind comes first wins
+var Kinds = []Kind{Email, IBAN, CreditCard, IP, Phone}
+
+// Action is what a policy does with a match
+type Action string
+
+const (
+	// Redact replaces the match with a placeholder such as "[EMAIL]"
+	Redact Action = "redact"
+	// Mask hides all but the last four characters of the match
+	Mask Action = "mask"
+	// Hash replaces the match with an HMAC of it under a secret key, so
+	// equal values can still be linked without being revealed. Without the
+	// key, short values such as IPv4 addresses cannot be recovered by trying
+	// every possible value.
+	Hash Action = "hash"
+)
+
+// Config holds configuration for a Detector
+type Config struct {
+	// Kinds are the kinds to detect. All kinds are detected when empty.
+	Kinds []Kind
+	// Action applies to every kind without an entry in Actions and defaults
+	// to Redact
+	Action  Action
+	Actions map[Kind]Action
+	// Secret is the HMAC key of the Hash action. It is required when any
+	// detected kind is hashed.
+	Secret []byte
+}
+
+// Match is an occurrence of personal data in a text. Start and End are byte
+// offsets into the text.
+type Match struct {
+	Kind  Kind
+	Value string
+	Start int
+	End   int
+}
+
+// Detector finds personal data in text
+type Detector struct {
+	kinds   []Kind
+	action  Action
+	actions map[Kind]Action
+	secret  []byte
+}
+
+// New creates a Detector with the given configuration
+func New(config Config) (*Detector, error) {
+	d := &Detector{action: config.Action, actions: make(map[Kind]Action), secret: config.Secret}
+	if d.action == "" {
+		d.action = Redact
+	}
+	if err := validateAction(d.action); err != nil {
+		return nil, err
+	}
+	for kind, action := range config.Actions {
+		if _, ok := detectors[kind]; !ok {
+			return nil, fmt.Errorf("unknown PII kind %q", kind)
+		}
+		if err := validateAction(action); err != nil {
+			return nil, err
+		}
+		d.actions[kind] = action
+	}
+
+	wanted := make(map[Kind]bool)
+	for _, kind := range config.Kinds {
+		if _, ok := detectors[kind]; !ok {
+			return nil, fmt.Errorf("unknown PII kind %q", kind)
+		}
+		wanted[kind] = true
+	}
+	for _, kind := range Kinds {
+		if len(wanted) == 0 || wanted[kind] {
+			d.kinds = append(d.kinds, kind)
+		}
+	}
+	for _, kind := range d.kinds {
+		if d.Action(kind) == Hash && len(d.secret) == 0 {
+			return nil, fmt.Errorf("PII kind %q is hashed but no secret is set", kind)
+		}
+	}
+
+	return d, nil
+}
+
+func validateAction(action Action) error {
+	switch action {
+	case Redact, Mask, Hash:
+		return nil
+	}
+	return fmt.Errorf("unknown PII action %q", action)
+}
+
+// Find returns the non-overlapping matches in text, sorted by offset
+func (d *Detector) Find(text string) []Match {
+	var matches []Match
+	taken := func(start, end int) bool {
+		for _, m := range matches {
+			if start < m.End && m.Start < end {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, kind := range d.kinds {
+		detector := detectors[kind]
+		for _, loc := range detector.pattern.FindAllStringIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			value := text[start:end]
+			if detector.prefix != nil {
+				value = detector.prefix(value)
+				end = start + len(value)
+			}
+			if value == "" {
+				continue
+			}
+			if !atBoundary(text, start, end) || !detector.valid(value) || taken(start, end) {
+				continue
+			}
+			matches = append(matches, Match{Kind: kind, Value: value, Start: start, End: end})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+	return matches
+}
+
+// Action returns the action the policy applies to kind
+func (d *Detector) Action(kind Kind) Action {
+	if action, ok := d.actions[kind]; ok {
+		return action
+	}
+	return d.action
+}
+
+// Replacement returns the text that replaces a match under the policy
+func (d *Detector) Replacement(m Match) string {
+	label := strings.ToUpper(string(m.Kind))
+	switch d.Action(m.Kind) {
+	case Mask:
+		return mask(m)
+	case Hash:
+		mac := hmac.New(sha256.New, d.secret)
+		mac.Write([]byte(m.Value))
+		return "[" + label + ":" + hex.EncodeToString(mac.Sum(nil)[:8]) + "]"
+	default:
+		return "[" + label + "]"
+	}
+}
+
+// Redact returns text with every match replaced
+func (d *Detector) Redact(text string) string {
+	matches := d.Find(text)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var sb strings.Builder
+	pos := 0
+	for _, m := range matches {
+		sb.WriteString(text[pos:m.Start])
+		sb.WriteString(d.Replacement(m))
+		pos = m.End
+	}
+	sb.WriteString(text[pos:])
+	return sb.String()
+}
+
+// mask replaces letters and digits with "*", keeping the last four of
+// them. Email addresses keep the first character and the domain instead.
+func mask(m Match) string {
+	if m.Kind == Email {
+		local, domain, _ := strings.Cut(m.Value, "@")
+		_, size := utf8.DecodeRuneInString(local)
+		return local[:size] + strings.Repeat("*", utf8.RuneCountInString(local)-1) + "@" + domain
+	}
+
+	keep := 0
+	runes := []rune(m.Value)
+	for i := len(runes) - 1; i >= 0; i-- {
+		if !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) {
+			continue
+		}
+		if keep < 4 {
+			keep++
+			continue
+		}
+		runes[i] = '*'
+	}
+	return string(runes)
+}
+
+// atBoundary reports whether the match is not part of a longer word or
+// number
+func atBoundary(text string, start, end int) bool {
+	if r, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWordRune(r) {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(r) {
+		return false
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '@'
+}
+
+type detector struct {
+	pattern *regexp.Regexp
+	valid   func(value string) bool
+	// prefix, when set, returns the longest valid prefix of a match, or ""
+	// when there is none, for patterns that can run on into the next word
+	prefix func(value string) string
+}
+
+var detectors = map[Kind]detector{
+	Email: {
+		pattern: regexp.MustCompile(`(?i)[a-z0-9._%+\-]+@[a-z0-9\-]+(?:\.[a-z0-9\-]+)*\.[a-z]{2,}`),
+		valid:   func(string) bool { return true },
+	},
+	IBAN: {
+		pattern: regexp.MustCompile(`[A-Z]{2}[0-9]{2}(?: ?[A-Z0-9]){11,30}`),
+		valid:   validIBAN,
+		prefix:  ibanPrefix,
+	},
+	CreditCard: {
+		pattern: regexp.MustCompile(`[0-9](?:[ \-]?[0-9]){12,18}`),
+		valid:   validCard,
+	},
+	IP: {
+		pattern: regexp.MustCompile(`(?i)(?:[0-9]{1,3}\.){3}[0-9]{1,3}|(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4}`),
+		valid:   validIP,
+	},
+	Phone: {
+		pattern: regexp.MustCompile(`(?:\+[0-9]{1,3}[ .\-]?)?(?:\([0-9]{1,4}\)[ .\-]?)?[0-9]{2,4}(?:[ .\-][0-9]{2,4}){1,4}`),
+		valid:   validPhone,
+	},
+}
@@ -0,0 +1,123 @@
+package pii
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		kind Kind
+		want string
+	}{
+		{"email", "Write to jane.doe+blog@example.co.uk today", Email, "jane.doe+blog@example.co.uk"},
+		{"international phone", "Call +1 415-555-0132 now", Phone, "+1 415-555-0132"},
+		{"area code", "Office: (030) 1234 5678.", Phone, "(030) 1234 5678"},
+		{"card", "Card 4111 1111 1111 1111 expired", CreditCard, "4111 1111 1111 1111"},
+		{"ipv4", "from 192.168.10.1 yesterday", IP, "192.168.10.1"},
+		{"ipv6", "via 2001:db8::8a2e:370:7334.", IP, "2001:db8::8a2e:370:7334"},
+		{"iban", "IBAN DE89 3704 0044 0532 0130 00 please", IBAN, "DE89 3704 0044 0532 0130 00"},
+		{"iban before bic", "IBAN DE89 3704 0044 0532 0130 00 BIC COBADEFFXXX", IBAN, "DE89 3704 0044 0532 0130 00"},
+		{"compact iban before code", "GB82WEST12345698765432 REF AB12", IBAN, "GB82WEST12345698765432"},
+	}
+
+	d, err := New(Config{})
+	if err != nil {
+		t.Fatalf("Failed to create detector: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := d.Find(tt.text)
+			if len(matches) != 1 {
+				t.Fatalf("Find(%q) = %+v, want one match", tt.text, matches)
+			}
+			m := matches[0]
+			if m.Kind != tt.kind || m.Value != tt.want || tt.text[m.Start:m.End] != tt.want {
+				t.Errorf("Find(%q) = %+v, want %s %q", tt.text, m, tt.kind, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindRejectsLookalikes(t *testing.T) {
+	d, err := New(Config{})
+	if err != nil {
+		t.Fatalf("Failed to create detector: %v", err)
+	}
+	for _, text := range []string{
+		"Order 4111 1111 1111 1112 shipped",  // fails the Luhn check
+		"Released on 2024-01-15 at 10:30:00", // date and time
+		"Version 999.1.2.3 and 300.1.1.1",    // octets over 255
+		"Years 2019 2020 2021",               // space-separated numbers
+		"IBAN DE89 3704 0044 0532 0130 01",   // bad checksum
+		"user@localhost",                     // no top-level domain
+	} {
+		if matches := d.Find(text); len(matches) != 0 {
+			t.Errorf("Find(%q) = %+v, want no matches", text, matches)
+		}
+	}
+}
+
+func TestReplacement(t *testing.T) {
+	d, err := New(Config{
+		Action:  Mask,
+		Actions: map[Kind]Action{IP: Redact, Email: Hash},
+		Secret:  []byte("pepper"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create detector: %v", err)
+	}
+
+	got := d.Redact("Card 4111-1111-1111-1111, host 10.0.0.1, mail bob@example.com")
+	if !strings.HasPrefix(got, "Card ****-****-****-1111, host [IP], mail [EMAIL:") {
+		t.Errorf("Redact() = %q", got)
+	}
+	if again := d.Redact("mail bob@example.com"); !strings.HasSuffix(got, strings.TrimPrefix(again, "mail ")) {
+		t.Errorf("Hashes differ: %q and %q", got, again)
+	}
+
+	masked := mask(Match{Kind: Email, Value: "jane@example.com"})
+	if masked != "j***@example.com" {
+		t.Errorf("mask() = %q", masked)
+	}
+}
+
+func TestHashRequiresSecret(t *testing.T) {
+	if _, err := New(Config{Actions: map[Kind]Action{IP: Hash}}); err == nil {
+		t.Error("Expected an error for hashing without a secret")
+	}
+	// A hashed kind that is not detected needs no secret
+	if _, err := New(Config{Kinds: []Kind{Email}, Actions: map[Kind]Action{IP: Hash}}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// Different secrets give unrelated hashes of the same value
+	a, _ := New(Config{Action: Hash, Secret: []byte("one")})
+	b, _ := New(Config{Action: Hash, Secret: []byte("two")})
+	m := Match{Kind: IP, Value: "10.0.0.1"}
+	if a.Replacement(m) == b.Replacement(m) {
+		t.Errorf("Expected the secret to change the hash, got %q twice", a.Replacement(m))
+	}
+}
+
+func TestNewRejectsUnknownNames(t *testing.T) {
+	if _, err := New(Config{Kinds: []Kind{"ssn"}}); err == nil {
+		t.Error("Expected an error for an unknown kind")
+	}
+	if _, err := New(Config{Action: "shred"}); err == nil {
+		t.Error("Expected an error for an unknown action")
+	}
+}
+
+func TestKindsFilter(t *testing.T) {
+	d, err := New(Config{Kinds: []Kind{Email}})
+	if err != nil {
+		t.Fatalf("Failed to create detector: %v", err)
+	}
+	matches := d.Find("bob@example.com or +1 415-555-0132")
+	if len(matches) != 1 || matches[0].Kind != Email {
+		t.Errorf("Find() = %+v, want only the email", matches)
+	}
+}
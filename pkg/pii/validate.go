@@ -0,0 +1,116 @@
+package pii
+
+import (
+	"net"
+	"regexp"
+	"strings"
+)
+
+// digits returns the decimal digits of s
+func digits(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// luhn reports whether number, a string of digits, passes the Luhn check
+func luhn(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// validCard accepts 13 to 19 digit numbers with a card network prefix that
+// pass the Luhn check
+func validCard(value string) bool {
+	number := digits(value)
+	if len(number) < 13 || len(number) > 19 || !luhn(number) {
+		return false
+	}
+	// Visa, Mastercard, American Express, Discover, Diners, JCB and UnionPay
+	// numbers start with 2 to 6
+	return number[0] >= '2' && number[0] <= '6'
+}
+
+// ibanLengths are the IBAN lengths of common countries. Other countries are
+// accepted at any length between 15 and 34.
+var ibanLengths = map[string]int{
+	"AT": 20, "BE": 16, "CH": 21, "CZ": 24, "DE": 22, "DK": 18, "ES": 24,
+	"FI": 18, "FR": 27, "GB": 22, "IE": 22, "IT": 27, "LU": 20, "NL": 18,
+	"NO": 15, "PL": 28, "PT": 25, "SE": 24,
+}
+
+// validIBAN checks the length and the ISO 7064 mod 97 checksum
+func validIBAN(value string) bool {
+	iban := strings.ReplaceAll(value, " ", "")
+	if length, ok := ibanLengths[iban[:2]]; ok && len(iban) != length {
+		return false
+	}
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	// Move the country code and check digits to the end and read letters as
+	// two-digit numbers, A = 10
+	remainder := 0
+	for _, r := range iban[4:] + iban[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			remainder = (remainder*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			remainder = (remainder*100 + int(r-'A') + 10) % 97
+		default:
+			return false
+		}
+	}
+	return remainder == 1
+}
+
+// ibanPrefix returns the longest prefix of value that is a valid IBAN, or
+// "" when there is none. The IBAN pattern also takes in uppercase words
+// that follow the number, such as "BIC", which fail the checksum.
+func ibanPrefix(value string) string {
+	for end := len(value); end >= 15; end-- {
+		if value[end-1] != ' ' && validIBAN(value[:end]) {
+			return value[:end]
+		}
+	}
+	return ""
+}
+
+// validIP accepts what net.ParseIP accepts, which rules out octets over
+// 255 and times such as "10:30:00"
+func validIP(value string) bool {
+	return net.ParseIP(value) != nil
+}
+
+// datePattern matches numbers that are more likely dates than phone numbers
+var datePattern = regexp.MustCompile(`^(?:[0-9]{4}[./\-][0-9]{1,2}[./\-][0-9]{1,2}|[0-9]{1,2}[./\-][0-9]{1,2}[./\-][0-9]{2,4})$`)
+
+// validPhone accepts numbers of 7 to 15 digits that are written like
+// phone numbers: with an international prefix, an area code in
+// parentheses, a leading trunk zero, or hyphens or dots between groups.
+// Space-separated groups alone are too often lists of numbers or years.
+func validPhone(value string) bool {
+	count := len(digits(value))
+	if count < 7 || count > 15 || datePattern.MatchString(value) {
+		return false
+	}
+	return strings.HasPrefix(value, "+") || strings.HasPrefix(value, "(") ||
+		strings.HasPrefix(value, "0") || strings.ContainsAny(value, "-.")
+}
@@ -0,0 +1,73 @@
+package quality
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Rules a chunk can fail. Check returns them wrapped in a *RejectionError,
+// so callers can test for a reason with errors.Is.
+var (
+	ErrTooShort        = errors.New("content too short")
+	ErrWordLength      = errors.New("mean word length out of range")
+	ErrLowAlphaRatio   = errors.New("too few words with letters")
+	ErrSymbolRatio     = errors.New("too many symbols")
+	ErrURLRatio        = errors.New("too much URL text")
+	ErrRepeatedLines   = errors.New("too many repeated lines")
+	ErrEllipsisRatio   = errors.New("too many sentences ending in an ellipsis")
+	ErrTerminalRatio   = errors.New("too few sentences ending in punctuation")
+	ErrFewStopwords    = errors.New("too few stopwords")
+	ErrLowQualityScore = errors.New("quality score below minimum")
+	ErrDuplicate       = errors.New("duplicate of earlier content")
+)
+
+// Violation is a rule a text failed, with the measured value and the limit
+// it crossed
+type Violation struct {
+	Err   error
+	Value float64
+	Limit float64
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("%v: %.3g (limit %.3g)", v.Err, v.Value, v.Limit)
+}
+
+func (v Violation) Unwrap() error {
+	return v.Err
+}
+
+// RejectionError reports why Check rejected a chunk. A duplicate has the ID
+// of the chunk it duplicates in DuplicateOf and the similarity to it.
+type RejectionError struct {
+	ChunkID     string
+	Score       float64
+	Violations  []Violation
+	DuplicateOf string
+	Similarity  float64
+}
+
+func (e *RejectionError) Error() string {
+	var reasons []string
+	for _, v := range e.Violations {
+		reasons = append(reasons, v.Error())
+	}
+	if e.DuplicateOf != "" {
+		reasons = append(reasons, fmt.Sprintf("%v %s: similarity %.3g", ErrDuplicate, e.DuplicateOf, e.Similarity))
+	}
+	return fmt.Sprintf("chunk %s rejected with quality score %.2f: %s", e.ChunkID, e.Score, strings.Join(reasons, "; "))
+}
+
+// Unwrap returns the rules the chunk failed, and ErrDuplicate for a
+// duplicate
+func (e *RejectionError) Unwrap() []error {
+	errs := make([]error, len(e.Violations), len(e.Violations)+1)
+	for i, v := range e.Violations {
+		errs[i] = v.Err
+	}
+	if e.DuplicateOf != "" {
+		errs = append(errs, ErrDuplicate)
+	}
+	return errs
+}
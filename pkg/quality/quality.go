@@ -0,0 +1,238 @@
+// Package quality filters low-quality chunks before they are embedded.
+// Every chunk is scored on heuristic signals of natural prose, such as its
+// share of alphabetic words, its symbol and URL density, its repeated
+// lines, how its sentences end and its stopwords, in the manner of the rules used to filter the
+// Gopher training corpus. Chunks scoring below a minimum, or shorter than a
+// minimum length, are rejected with the reasons as typed errors.
+package quality
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/chunker"
+	"github.com/ncolesummers/scrape-pipeline/pkg/dedup"
+)
+
+// Metadata keys set by Check on every chunk
+const (
+	// MetaScore holds the quality score, between 0 and 1
+	MetaScore = "quality_score"
+	// MetaFlags holds the rules a chunk failed as a []string, when any
+	MetaFlags = "quality_flags"
+)
+
+func init() {
+	models.RegisterMetadataType[[]string](MetaFlags)
+}
+
+// Thresholds are the limits of the quality rules
+type Thresholds struct {
+	MinMeanWordLength    float64
+	MaxMeanWordLength    float64
+	MinAlphaRatio        float64
+	MaxSymbolRatio       float64
+	MaxURLRatio          float64
+	MaxRepeatedLineRatio float64
+	MaxEllipsisRatio     float64
+	MinTerminalRatio     float64
+	MinStopwords         int
+}
+
+// DefaultThresholds returns the limits of the Gopher rules, with a URL
+// limit added for link lists and a terminal punctuation limit after the C4
+// filter
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		MinMeanWordLength:    3,
+		MaxMeanWordLength:    10,
+		MinAlphaRatio:        0.8,
+		MaxSymbolRatio:       0.1,
+		MaxURLRatio:          0.3,
+		MaxRepeatedLineRatio: 0.3,
+		MaxEllipsisRatio:     0.3,
+		MinTerminalRatio:     0.5,
+		MinStopwords:         2,
+	}
+}
+
+// DuplicateDetector decides whether a chunk repeats content seen before
+type DuplicateDetector interface {
+	IsDuplicate(ctx context.Context, chunk *models.ContentChunk) (bool, float64, error)
+}
+
+// Config holds configuration for a Checker
+type Config struct {
+	// MinContentLength is the number of characters below which a chunk is
+	// rejected whatever its score
+	MinContentLength int
+	// MinScore is the share of rules, between 0 and 1, a chunk must pass
+	MinScore float64
+	// Language selects the stopwords for chunks that do not record their
+	// language. It defaults to "en".
+	Language string
+	// Thresholds are the limits of the rules. The zero value takes
+	// DefaultThresholds.
+	Thresholds Thresholds
+	// Duplicates answers IsDuplicate. Without it no chunk is a duplicate.
+	// It is closed by Close when it implements io.Closer.
+	Duplicates DuplicateDetector
+}
+
+// DefaultConfig returns the configuration used when none is given
+func DefaultConfig() Config {
+	return Config{
+		MinContentLength: 100,
+		MinScore:         0.5,
+		Language:         "en",
+		Thresholds:       DefaultThresholds(),
+	}
+}
+
+// Checker implements the models.QualityControl interface with heuristic
+// rules
+type Checker struct {
+	config Config
+}
+
+// New creates a Checker with the given configuration
+func New(config Config) (*Checker, error) {
+	if config.MinScore < 0 || config.MinScore > 1 {
+		return nil, fmt.Errorf("minimum quality score must be between 0 and 1, got %g", config.MinScore)
+	}
+	if config.MinContentLength < 0 {
+		return nil, fmt.Errorf("minimum content length must not be negative, got %d", config.MinContentLength)
+	}
+	if config.Language == "" {
+		config.Language = "en"
+	}
+	if config.Thresholds == (Thresholds{}) {
+		config.Thresholds = DefaultThresholds()
+	}
+	return &Checker{config: config}, nil
+}
+
+// Score returns the share of rules text passes and the rules it fails. The
+// stopword rule only applies to languages with a stopword list, the
+// sentence rules only to text with sentences, and the length rule does not
+// count towards the score.
+func (c *Checker) Score(text, language string) (float64, []Violation) {
+	t := c.config.Thresholds
+	s := Measure(text, language)
+
+	var violations []Violation
+	rules, passed := 0, 0
+	rule := func(ok bool, err error, value, limit float64) {
+		rules++
+		if ok {
+			passed++
+			return
+		}
+		violations = append(violations, Violation{Err: err, Value: value, Limit: limit})
+	}
+
+	switch {
+	case s.MeanWordLength < t.MinMeanWordLength:
+		rule(false, ErrWordLength, s.MeanWordLength, t.MinMeanWordLength)
+	case s.MeanWordLength > t.MaxMeanWordLength:
+		rule(false, ErrWordLength, s.MeanWordLength, t.MaxMeanWordLength)
+	default:
+		rule(true, nil, 0, 0)
+	}
+	rule(s.AlphaRatio >= t.MinAlphaRatio, ErrLowAlphaRatio, s.AlphaRatio, t.MinAlphaRatio)
+	rule(s.SymbolRatio <= t.MaxSymbolRatio, ErrSymbolRatio, s.SymbolRatio, t.MaxSymbolRatio)
+	rule(s.URLRatio <= t.MaxURLRatio, ErrURLRatio, s.URLRatio, t.MaxURLRatio)
+	rule(s.RepeatedLineRatio <= t.MaxRepeatedLineRatio, ErrRepeatedLines, s.RepeatedLineRatio, t.MaxRepeatedLineRatio)
+	if s.Sentences > 0 {
+		rule(s.EllipsisRatio <= t.MaxEllipsisRatio, ErrEllipsisRatio, s.EllipsisRatio, t.MaxEllipsisRatio)
+		rule(s.TerminalRatio >= t.MinTerminalRatio, ErrTerminalRatio, s.TerminalRatio, t.MinTerminalRatio)
+	}
+	if s.Stopwords >= 0 {
+		rule(s.Stopwords >= t.MinStopwords, ErrFewStopwords, float64(s.Stopwords), float64(t.MinStopwords))
+	}
+
+	score := float64(passed) / float64(rules)
+	if s.Length < c.config.MinContentLength {
+		violations = append([]Violation{{Err: ErrTooShort, Value: float64(s.Length), Limit: float64(c.config.MinContentLength)}}, violations...)
+	}
+	return score, violations
+}
+
+// Check scores every chunk, records the score and failed rules in its
+// metadata, and returns the chunks that are long enough, score at least
+// MinScore and are not duplicates. Only chunks passing the rules are
+// checked for duplicates, so rejected chunks never become originals. Every
+// rejected chunk has a *RejectionError in the errors.
+func (c *Checker) Check(ctx context.Context, chunks []*models.ContentChunk) ([]*models.ContentChunk, []error) {
+	var accepted []*models.ContentChunk
+	var errs []error
+	for _, chunk := range chunks {
+		// Check if context is canceled
+		select {
+		case <-ctx.Done():
+			return accepted, append(errs, ctx.Err())
+		default:
+		}
+
+		language := c.config.Language
+		if lang, ok := chunk.Metadata[chunker.MetaLanguage].(string); ok && lang != "" {
+			language = lang
+		}
+		score, violations := c.Score(chunk.Text, language)
+
+		if chunk.Metadata == nil {
+			chunk.Metadata = make(map[string]interface{})
+		}
+		chunk.Metadata[MetaScore] = score
+		if len(violations) > 0 {
+			flags := make([]string, len(violations))
+			for i, v := range violations {
+				flags[i] = v.Err.Error()
+			}
+			chunk.Metadata[MetaFlags] = flags
+		}
+
+		if score < c.config.MinScore {
+			violations = append(violations, Violation{Err: ErrLowQualityScore, Value: score, Limit: c.config.MinScore})
+		}
+		tooShort := utf8.RuneCountInString(chunk.Text) < c.config.MinContentLength
+		if score < c.config.MinScore || tooShort {
+			errs = append(errs, &RejectionError{ChunkID: chunk.ID, Score: score, Violations: violations})
+			continue
+		}
+
+		duplicate, similarity, err := c.IsDuplicate(ctx, chunk)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to check chunk %s for duplicates: %w", chunk.ID, err))
+			continue
+		}
+		if duplicate {
+			original, _ := chunk.Metadata[dedup.MetaDuplicateOf].(string)
+			errs = append(errs, &RejectionError{ChunkID: chunk.ID, Score: score, DuplicateOf: original, Similarity: similarity})
+			continue
+		}
+		accepted = append(accepted, chunk)
+	}
+	return accepted, errs
+}
+
+// IsDuplicate reports whether chunk repeats content seen before, with the
+// similarity to it, using the configured DuplicateDetector
+func (c *Checker) IsDuplicate(ctx context.Context, chunk *models.ContentChunk) (bool, float64, error) {
+	if c.config.Duplicates == nil {
+		return false, 0, nil
+	}
+	return c.config.Duplicates.IsDuplicate(ctx, chunk)
+}
+
+// Close closes the DuplicateDetector, saving its index, when it implements
+// io.Closer
+func (c *Checker) Close() error {
+	if closer, ok := c.config.Duplicates.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package quality
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/chunker"
+	"github.com/ncolesummers/scrape-pipeline/pkg/dedup"
+)
+
+var (
+	_ models.QualityControl = (*Checker)(nil)
+	_ DuplicateDetector     = (*dedup.Index)(nil)
+)
+
+const prose = "The pipeline fetches pages from the configured sites and extracts the main article. " +
+	"It then normalizes the text, splits it into chunks and checks that each chunk reads like prose " +
+	"with enough context to be useful."
+
+func TestMeasure(t *testing.T) {
+	s := Measure("the cat ### sat\nhttps://example.com/x\nthe cat ### sat", "en")
+	if s.Words != 9 {
+		t.Errorf("Words = %d, want 9", s.Words)
+	}
+	if s.Stopwords != 2 {
+		t.Errorf("Stopwords = %d, want 2", s.Stopwords)
+	}
+	if s.RepeatedLineRatio != 1.0/3 {
+		t.Errorf("RepeatedLineRatio = %v, want 1/3", s.RepeatedLineRatio)
+	}
+	if s.SymbolRatio != 6.0/9 {
+		t.Errorf("SymbolRatio = %v, want 6/9", s.SymbolRatio)
+	}
+	if s.AlphaRatio != 7.0/9 {
+		t.Errorf("AlphaRatio = %v, want 7/9", s.AlphaRatio)
+	}
+	if s.URLRatio <= 0.3 || s.URLRatio >= 0.5 {
+		t.Errorf("URLRatio = %v", s.URLRatio)
+	}
+	if s := Measure("text", "xx"); s.Stopwords != -1 {
+		t.Errorf("Expected no stopword count for an unknown language, got %d", s.Stopwords)
+	}
+}
+
+func TestMeasureSentences(t *testing.T) {
+	// Abbreviations, versions and a mid-sentence ellipsis do not end
+	// sentences
+	s := Measure("Dr. Smith upgraded to v1.2 last week, e.g. on the build servers. "+
+		"It waited... and then it failed again!\nRead more…\nTags: go, ci", "en")
+	if s.Sentences != 4 {
+		t.Errorf("Sentences = %d, want 4", s.Sentences)
+	}
+	if s.EllipsisRatio != 1.0/4 {
+		t.Errorf("EllipsisRatio = %v, want 1/4", s.EllipsisRatio)
+	}
+	if s.TerminalRatio != 2.0/4 {
+		t.Errorf("TerminalRatio = %v, want 2/4", s.TerminalRatio)
+	}
+	if s.SymbolRatio != 0 {
+		t.Errorf("SymbolRatio = %v, want 0", s.SymbolRatio)
+	}
+
+	checker, _ := New(DefaultConfig())
+	_, violations := checker.Score("Read more…\nSee also…\nSubscribe…\nThe pipeline keeps the article and drops the rest.", "en")
+	found := map[error]bool{}
+	for _, v := range violations {
+		found[v.Err] = true
+	}
+	if !found[ErrEllipsisRatio] || !found[ErrTerminalRatio] {
+		t.Errorf("Expected the ellipsis and terminal punctuation rules to fail, got %v", violations)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	checker, err := New(Config{MinContentLength: 50, MinScore: 0.7})
+	if err != nil {
+		t.Fatalf("Failed to create checker: %v", err)
+	}
+
+	chunks := []*models.ContentChunk{
+		{ID: "prose", Text: prose, Metadata: map[string]interface{}{}},
+		{ID: "short", Text: "The end of the post.", Metadata: map[string]interface{}{}},
+		{ID: "links", Text: strings.Repeat("https://example.com/a-very-long-link-target #tag #tag\n", 4), Metadata: map[string]interface{}{}},
+		{ID: "german", Text: "Der Zug kommt heute nicht, und die Fahrgäste warten mit dem Gepäck auf dem Bahnsteig in der Kälte.",
+			Metadata: map[string]interface{}{chunker.MetaLanguage: "de"}},
+	}
+	accepted, errs := checker.Check(context.Background(), chunks)
+
+	if len(accepted) != 2 || accepted[0].ID != "prose" || accepted[1].ID != "german" {
+		t.Fatalf("Expected the prose chunks to pass, got %v", accepted)
+	}
+	if score := accepted[0].Metadata[MetaScore]; score != 1.0 {
+		t.Errorf("Expected a perfect score for prose, got %v", score)
+	}
+
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 rejections, got %v", errs)
+	}
+	var rejection *RejectionError
+	if !errors.As(errs[0], &rejection) || rejection.ChunkID != "short" || !errors.Is(errs[0], ErrTooShort) {
+		t.Errorf("Expected the short chunk to be rejected as too short, got %v", errs[0])
+	}
+	for _, want := range []error{ErrURLRatio, ErrSymbolRatio, ErrRepeatedLines, ErrFewStopwords, ErrLowQualityScore} {
+		if !errors.Is(errs[1], want) {
+			t.Errorf("Expected the link list to fail %q, got %v", want, errs[1])
+		}
+	}
+	if errors.Is(errs[1], ErrTooShort) {
+		t.Errorf("The link list is long enough: %v", errs[1])
+	}
+	if flags, _ := chunks[2].Metadata[MetaFlags].([]string); len(flags) == 0 {
+		t.Errorf("Expected the failed rules in the metadata, got %v", chunks[2].Metadata)
+	}
+}
+
+func TestScoreWordLength(t *testing.T) {
+	checker, err := New(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create checker: %v", err)
+	}
+	_, violations := checker.Score("the and of to be with that have "+strings.Repeat("supercalifragilistic ", 20), "en")
+	found := false
+	for _, v := range violations {
+		if errors.Is(v, ErrWordLength) {
+			found = v.Limit == 10
+		}
+	}
+	if !found {
+		t.Errorf("Expected a mean word length violation against the maximum, got %v", violations)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, config := range []Config{{MinScore: 1.5}, {MinScore: -0.1}, {MinContentLength: -1}} {
+		if _, err := New(config); err == nil {
+			t.Errorf("New(%+v) should fail", config)
+		}
+	}
+}
+
+// stubDetector reports every chunk as a duplicate
+type stubDetector struct{}
+
+func (stubDetector) IsDuplicate(ctx context.Context, chunk *models.ContentChunk) (bool, float64, error) {
+	return true, 0.9, nil
+}
+
+func TestIsDuplicate(t *testing.T) {
+	checker, _ := New(Config{})
+	if dup, _, err := checker.IsDuplicate(context.Background(), &models.ContentChunk{}); dup || err != nil {
+		t.Errorf("Expected no duplicates without a detector, got %v, %v", dup, err)
+	}
+	checker, _ = New(Config{Duplicates: stubDetector{}})
+	if dup, similarity, _ := checker.IsDuplicate(context.Background(), &models.ContentChunk{}); !dup || similarity != 0.9 {
+		t.Errorf("Expected the detector's answer, got %v, %v", dup, similarity)
+	}
+}
+
+func TestCheckRejectsDuplicates(t *testing.T) {
+	index, err := dedup.NewIndex(dedup.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	checker, _ := New(Config{MinContentLength: 20, MinScore: 0.9, Duplicates: index})
+
+	chunks := []*models.ContentChunk{
+		{ID: "noise", Text: "### ### ### ### ### ### ###"},
+		{ID: "original", Text: prose},
+		{ID: "copy", Text: prose + " Really."},
+	}
+	accepted, errs := checker.Check(context.Background(), chunks)
+	if len(accepted) != 1 || accepted[0].ID != "original" || len(errs) != 2 {
+		t.Fatalf("Expected only the original to pass, got %v, %v", accepted, errs)
+	}
+	var rejection *RejectionError
+	if !errors.As(errs[1], &rejection) || !errors.Is(errs[1], ErrDuplicate) {
+		t.Fatalf("Expected a duplicate rejection, got %v", errs[1])
+	}
+	if rejection.ChunkID != "copy" || rejection.DuplicateOf != "original" || rejection.Similarity < 0.5 {
+		t.Errorf("Rejection = %+v", rejection)
+	}
+	// Rejected chunks are not indexed as originals
+	if _, found := index.Find(chunks[0].Text, ""); found {
+		t.Error("Expected the rejected noise to stay out of the index")
+	}
+}
@@ -0,0 +1,127 @@
+package quality
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ncolesummers/scrape-pipeline/pkg/sentence"
+)
+
+// Signals are the measurements of a text the quality rules are based on.
+// They follow the heuristics used to filter the Gopher training corpus.
+type Signals struct {
+	// Length is the number of characters
+	Length int
+	// Words is the number of whitespace-separated words
+	Words int
+	// MeanWordLength is the mean number of characters of the words
+	MeanWordLength float64
+	// AlphaRatio is the share of words that contain a letter
+	AlphaRatio float64
+	// SymbolRatio is the number of "#" characters per word
+	SymbolRatio float64
+	// Sentences is the number of sentences, as split by package sentence
+	Sentences int
+	// EllipsisRatio is the share of sentences that end with an ellipsis
+	EllipsisRatio float64
+	// TerminalRatio is the share of sentences that end with a period, an
+	// exclamation mark or a question mark
+	TerminalRatio float64
+	// URLRatio is the share of characters that belong to URLs
+	URLRatio float64
+	// RepeatedLineRatio is the share of non-empty lines that repeat an
+	// earlier line
+	RepeatedLineRatio float64
+	// Stopwords is the number of words that are stopwords of the language,
+	// or -1 when there is no stopword list for it
+	Stopwords int
+}
+
+// Measure computes the signals of text written in language, a tag such as
+// "en" or "de-AT"
+func Measure(text, language string) Signals {
+	s := Signals{Length: utf8.RuneCountInString(text), Stopwords: -1}
+
+	words := strings.Fields(text)
+	s.Words = len(words)
+	stopwords := stopwordsFor(language)
+	if stopwords != nil {
+		s.Stopwords = 0
+	}
+
+	var letters, alpha, symbols, urlChars int
+	for _, word := range words {
+		letters += utf8.RuneCountInString(word)
+		if strings.IndexFunc(word, unicode.IsLetter) >= 0 {
+			alpha++
+		}
+		symbols += strings.Count(word, "#")
+		if isURL(word) {
+			urlChars += utf8.RuneCountInString(word)
+		}
+		if stopwords != nil && stopwords[strings.ToLower(strings.TrimFunc(word, isPunct))] {
+			s.Stopwords++
+		}
+	}
+	if s.Words > 0 {
+		s.MeanWordLength = float64(letters) / float64(s.Words)
+		s.AlphaRatio = float64(alpha) / float64(s.Words)
+		s.SymbolRatio = float64(symbols) / float64(s.Words)
+	}
+	if s.Length > 0 {
+		s.URLRatio = float64(urlChars) / float64(s.Length)
+	}
+
+	// Abbreviations, numbers and URLs do not end sentences, so "e.g." or
+	// "v1.2" does not count as a sentence ending in a period
+	var ellipses, terminal int
+	for _, sent := range sentence.For(language).Split(text) {
+		s.Sentences++
+		sent = strings.TrimRight(sent, closers)
+		switch {
+		case strings.HasSuffix(sent, "...") || strings.HasSuffix(sent, "…"):
+			ellipses++
+		case strings.HasSuffix(sent, "."), strings.HasSuffix(sent, "!"), strings.HasSuffix(sent, "?"),
+			strings.HasSuffix(sent, "。"), strings.HasSuffix(sent, "！"), strings.HasSuffix(sent, "？"):
+			terminal++
+		}
+	}
+	if s.Sentences > 0 {
+		s.EllipsisRatio = float64(ellipses) / float64(s.Sentences)
+		s.TerminalRatio = float64(terminal) / float64(s.Sentences)
+	}
+
+	var lines, repeated int
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		lines++
+		if seen[line] {
+			repeated++
+		}
+		seen[line] = true
+	}
+	if lines > 0 {
+		s.RepeatedLineRatio = float64(repeated) / float64(lines)
+	}
+	return s
+}
+
+// isURL reports whether word is, or is wrapped around, a web address
+func isURL(word string) bool {
+	word = strings.TrimFunc(word, isPunct)
+	lower := strings.ToLower(word)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "www.")
+}
+
+// closers are the quotes and brackets that may follow the punctuation
+// ending a sentence
+const closers = "\"')]}”’»」』"
+
+func isPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
@@ -0,0 +1,36 @@
+package quality
+
+import "strings"
+
+// stopwords are, for each language, common function words that appear in
+// nearly any passage of natural prose. English uses the Gopher list.
+var stopwords = map[string][]string{
+	"en": {"the", "be", "to", "of", "and", "that", "have", "with"},
+	"de": {"der", "die", "das", "und", "ist", "zu", "den", "mit", "von", "nicht"},
+	"fr": {"le", "la", "les", "de", "et", "est", "un", "une", "que", "avec"},
+	"es": {"el", "la", "los", "de", "y", "que", "en", "es", "un", "con"},
+	"it": {"il", "la", "di", "e", "che", "è", "un", "una", "per", "con"},
+	"pt": {"o", "a", "os", "de", "e", "que", "um", "uma", "com", "não"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "met", "niet", "te"},
+}
+
+var stopwordSets = func() map[string]map[string]bool {
+	sets := make(map[string]map[string]bool, len(stopwords))
+	for lang, words := range stopwords {
+		set := make(map[string]bool, len(words))
+		for _, word := range words {
+			set[word] = true
+		}
+		sets[lang] = set
+	}
+	return sets
+}()
+
+// stopwordsFor returns the stopwords of a language tag matched by its
+// primary subtag, or nil when there is no list for it
+func stopwordsFor(language string) map[string]bool {
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	return stopwordSets[strings.ToLower(language)]
+}
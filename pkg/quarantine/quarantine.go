@@ -0,0 +1,307 @@
+// Package quarantine keeps content that a pipeline stage rejected, such as
+// chunks failing quality control, pages that could not be extracted and
+// soft 404s, so it can be reviewed and released back into the pipeline.
+// Every item is a JSON file in the store directory. Released items move to
+// its "released" subdirectory until the pipeline has processed them, and
+// then to its "accepted" subdirectory. Extractor and
+// QualityControl wrap pipeline stages to quarantine what they reject.
+package quarantine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/quality"
+)
+
+// Stages that quarantine content
+const (
+	StageFetch      = "fetch"
+	StageExtraction = "extraction"
+	StageQuality    = "quality"
+)
+
+// Subdirectories of released items waiting to be processed and of
+// released items the pipeline has processed
+const (
+	releasedDir = "released"
+	acceptedDir = "accepted"
+)
+
+// idLength is the number of hexadecimal digits in an item ID
+const idLength = 32
+
+var (
+	// ErrNotFound is returned for an ID that matches no item
+	ErrNotFound = errors.New("quarantined item not found")
+	// ErrAmbiguous is returned for an ID prefix that matches several items
+	ErrAmbiguous = errors.New("ambiguous quarantined item ID")
+	// ErrInvalidID is returned for an ID that is not lowercase hexadecimal
+	// of at most idLength digits
+	ErrInvalidID = errors.New("invalid quarantined item ID")
+)
+
+// Item is a piece of rejected content. Raw holds the fetched page for
+// items from the fetch and extraction stages and Chunk the chunk for items
+// from quality control, so a released item can re-enter the pipeline at the
+// stage that rejected it.
+type Item struct {
+	ID            string               `json:"id"`
+	Stage         string               `json:"stage"`
+	Reason        string               `json:"reason"`
+	Score         float64              `json:"score"`
+	URL           string               `json:"url"`
+	QuarantinedAt time.Time            `json:"quarantined_at"`
+	Raw           *models.RawContent   `json:"raw,omitempty"`
+	Chunk         *models.ContentChunk `json:"chunk,omitempty"`
+}
+
+// FromChunk returns the item for a chunk rejected by quality control. The
+// score is taken from err when it is a *quality.RejectionError.
+func FromChunk(chunk *models.ContentChunk, err error) *Item {
+	item := &Item{Stage: StageQuality, Reason: err.Error(), URL: chunk.Source, Chunk: chunk}
+	var rejection *quality.RejectionError
+	if errors.As(err, &rejection) {
+		item.Score = rejection.Score
+	}
+	return item
+}
+
+// FromRaw returns the item for a page rejected at stage, such as a soft 404
+// or a page that could not be extracted
+func FromRaw(stage string, raw *models.RawContent, err error) *Item {
+	return &Item{Stage: stage, Reason: err.Error(), URL: raw.URL, Raw: raw}
+}
+
+// Store keeps quarantined items in a directory
+type Store struct {
+	dir string
+}
+
+// Open returns the Store in dir. The directory is created on first Add.
+func Open(dir string) (*Store, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("quarantine requires a directory")
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Add quarantines an item. An item without an ID gets one from its stage,
+// URL and chunk, so quarantining the same content again replaces it; an
+// item without a time gets the current time.
+func (s *Store) Add(item *Item) error {
+	if item.Stage == "" {
+		return fmt.Errorf("quarantined item without a stage")
+	}
+	if item.ID == "" {
+		chunkID := ""
+		if item.Chunk != nil {
+			chunkID = item.Chunk.ID
+		}
+		item.ID = itemID(item.Stage, item.URL, chunkID)
+	}
+	if err := checkID(item.ID); err != nil {
+		return err
+	}
+	if item.QuarantinedAt.IsZero() {
+		item.QuarantinedAt = time.Now().UTC()
+	}
+	return writeItem(filepath.Join(s.dir, item.ID+".json"), item)
+}
+
+// List returns the quarantined items of stage, or of every stage when stage
+// is empty, oldest first
+func (s *Store) List(stage string) ([]*Item, error) {
+	items, err := readItems(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	if stage == "" {
+		return items, nil
+	}
+	var filtered []*Item
+	for _, item := range items {
+		if item.Stage == stage {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered, nil
+}
+
+// Get returns the quarantined item whose ID is or starts with id
+func (s *Store) Get(id string) (*Item, error) {
+	path, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	return readItem(path)
+}
+
+// Release moves the quarantined item whose ID is or starts with id to the
+// released items, and returns it
+func (s *Store) Release(id string) (*Item, error) {
+	path, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	item, err := readItem(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(s.dir, releasedDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create released directory: %w", err)
+	}
+	if err := os.Rename(path, filepath.Join(s.dir, releasedDir, filepath.Base(path))); err != nil {
+		return nil, fmt.Errorf("failed to release item %s: %w", item.ID, err)
+	}
+	return item, nil
+}
+
+// Released returns the released items the pipeline has yet to process,
+// oldest first
+func (s *Store) Released() ([]*Item, error) {
+	return readItems(filepath.Join(s.dir, releasedDir))
+}
+
+// Done records that the released item with the given ID was processed by
+// moving it to the accepted items. Call it once the item's content is
+// stored, so an item whose processing failed is processed again by the
+// next run.
+func (s *Store) Done(id string) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(s.dir, acceptedDir), 0755); err != nil {
+		return fmt.Errorf("failed to create accepted directory: %w", err)
+	}
+	name := id + ".json"
+	if err := os.Rename(filepath.Join(s.dir, releasedDir, name), filepath.Join(s.dir, acceptedDir, name)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrNotFound, id)
+		}
+		return fmt.Errorf("failed to accept item %s: %w", id, err)
+	}
+	return nil
+}
+
+// isReleased reports whether the content rejected at stage was released,
+// so the stage that rejected it lets it through
+func (s *Store) isReleased(stage, url, chunkID string) bool {
+	name := itemID(stage, url, chunkID) + ".json"
+	for _, dir := range []string{releasedDir, acceptedDir} {
+		if _, err := os.Stat(filepath.Join(s.dir, dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// itemID returns the ID of the item for content rejected at stage, so the
+// same content always has the same ID
+func itemID(stage, url, chunkID string) string {
+	return models.ContentHash(stage + "\x00" + url + "\x00" + chunkID)[:idLength]
+}
+
+// find returns the path of the only quarantined item whose ID is or starts
+// with id
+func (s *Store) find(id string) (string, error) {
+	if err := checkID(id); err != nil {
+		return "", err
+	}
+	matches, err := filepath.Glob(filepath.Join(s.dir, id+"*.json"))
+	if err != nil {
+		return "", err
+	}
+	for _, match := range matches {
+		if filepath.Base(match) == id+".json" {
+			return match, nil
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", ErrNotFound, id)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%w: %s matches %d items", ErrAmbiguous, id, len(matches))
+	}
+}
+
+// checkID returns ErrInvalidID unless id is a non-empty string of at most
+// idLength lowercase hexadecimal digits, which keeps IDs from naming files
+// outside the store or acting as glob patterns
+func checkID(id string) error {
+	if id == "" || len(id) > idLength {
+		return fmt.Errorf("%w: %q", ErrInvalidID, id)
+	}
+	for _, r := range id {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return fmt.Errorf("%w: %q", ErrInvalidID, id)
+		}
+	}
+	return nil
+}
+
+// readItems reads every item in dir, oldest first. A missing directory has
+// no items.
+func readItems(dir string) ([]*Item, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantine: %w", err)
+	}
+	var items []*Item
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		item, err := readItem(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].QuarantinedAt.Before(items[j].QuarantinedAt)
+	})
+	return items, nil
+}
+
+func readItem(path string) (*Item, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantined item: %w", err)
+	}
+	var item Item
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, fmt.Errorf("failed to parse quarantined item %s: %w", filepath.Base(path), err)
+	}
+	return &item, nil
+}
+
+func writeItem(path string, item *Item) error {
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode quarantined item: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+	// Write to a temporary file first so a crash cannot truncate the item
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write quarantined item: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write quarantined item: %w", err)
+	}
+	return nil
+}
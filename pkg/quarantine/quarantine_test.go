@@ -0,0 +1,166 @@
+package quarantine
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/chunker"
+	"github.com/ncolesummers/scrape-pipeline/pkg/quality"
+)
+
+func TestStore(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open quarantine: %v", err)
+	}
+
+	chunk := &models.ContentChunk{ID: "c1", Text: "tiny", Source: "https://example.com/a"}
+	rejection := &quality.RejectionError{ChunkID: "c1", Score: 0.25, Violations: []quality.Violation{{Err: quality.ErrTooShort, Value: 4, Limit: 100}}}
+	rejected := FromChunk(chunk, rejection)
+	rejected.QuarantinedAt = time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if err := s.Add(rejected); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if rejected.Stage != StageQuality || rejected.Score != 0.25 || len(rejected.ID) != idLength {
+		t.Errorf("Item = %+v", rejected)
+	}
+
+	raw := &models.RawContent{URL: "https://example.com/gone", HTML: "<p>Page not found</p>", StatusCode: 200}
+	notFound := FromRaw(StageFetch, raw, errors.New("soft 404"))
+	notFound.QuarantinedAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.Add(notFound); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+
+	items, err := s.List("")
+	if err != nil || len(items) != 2 || items[0].ID != notFound.ID {
+		t.Fatalf("Expected both items, oldest first, got %v, %v", items, err)
+	}
+	if items, _ := s.List(StageQuality); len(items) != 1 || items[0].Chunk.Text != "tiny" {
+		t.Errorf("Expected the quality item, got %v", items)
+	}
+
+	got, err := s.Get(rejected.ID[:8])
+	if err != nil || got.Reason != rejection.Error() || got.URL != chunk.Source {
+		t.Errorf("Get by prefix = %+v, %v", got, err)
+	}
+	if _, err := s.Get("fff"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	released, err := s.Release(notFound.ID)
+	if err != nil || released.Raw.HTML != raw.HTML {
+		t.Fatalf("Release = %+v, %v", released, err)
+	}
+	if items, _ := s.List(""); len(items) != 1 {
+		t.Errorf("Expected one item left in quarantine, got %d", len(items))
+	}
+	if _, err := s.Release(notFound.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a released item to leave quarantine, got %v", err)
+	}
+
+	// Released items stay until they are done, even across reopening
+	s, _ = Open(s.dir)
+	pending, err := s.Released()
+	if err != nil || len(pending) != 1 || pending[0].ID != notFound.ID {
+		t.Fatalf("Released = %v, %v", pending, err)
+	}
+	if !s.isReleased(StageFetch, raw.URL, "") {
+		t.Error("Expected the released page to be let through")
+	}
+	if err := s.Done(notFound.ID); err != nil {
+		t.Fatalf("Done = %v", err)
+	}
+	if pending, _ := s.Released(); len(pending) != 0 {
+		t.Errorf("Expected no released items left, got %v", pending)
+	}
+	if !s.isReleased(StageFetch, raw.URL, "") {
+		t.Error("Expected a processed item to be let through on later runs")
+	}
+	if err := s.Done(notFound.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an item already done, got %v", err)
+	}
+}
+
+func TestStoreReplacesSameContent(t *testing.T) {
+	s, _ := Open(t.TempDir())
+	raw := &models.RawContent{URL: "https://example.com/a"}
+	s.Add(FromRaw(StageExtraction, raw, errors.New("no content")))
+	s.Add(FromRaw(StageExtraction, raw, errors.New("still no content")))
+
+	items, err := s.List("")
+	if err != nil || len(items) != 1 || items[0].Reason != "still no content" {
+		t.Errorf("Expected the second rejection to replace the first, got %v, %v", items, err)
+	}
+}
+
+func TestStoreAmbiguousPrefix(t *testing.T) {
+	s, _ := Open(t.TempDir())
+	s.Add(&Item{ID: "ab1", Stage: StageFetch})
+	s.Add(&Item{ID: "ab2", Stage: StageFetch})
+
+	if _, err := s.Get("ab"); !errors.Is(err, ErrAmbiguous) {
+		t.Errorf("Expected ErrAmbiguous, got %v", err)
+	}
+	if _, err := Open(""); err == nil {
+		t.Error("Expected an error without a directory")
+	}
+	if err := s.Add(&Item{}); err == nil {
+		t.Error("Expected an error for an item without a stage")
+	}
+}
+
+func TestStoreRejectsInvalidIDs(t *testing.T) {
+	s, _ := Open(t.TempDir())
+	s.Add(&Item{ID: "ab1", Stage: StageFetch})
+
+	for _, id := range []string{"", "../../x", "*", "ab?", "AB1", "ab1/..", strings.Repeat("a", idLength+1)} {
+		if _, err := s.Get(id); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("Get(%q) = %v, want ErrInvalidID", id, err)
+		}
+		if _, err := s.Release(id); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("Release(%q) = %v, want ErrInvalidID", id, err)
+		}
+		if err := s.Done(id); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("Done(%q) = %v, want ErrInvalidID", id, err)
+		}
+	}
+	if err := s.Add(&Item{ID: "../evil", Stage: StageFetch}); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("Add with a path as ID = %v, want ErrInvalidID", err)
+	}
+}
+
+func TestStoreKeepsMetadataTypes(t *testing.T) {
+	s, _ := Open(t.TempDir())
+	metadata := map[string]interface{}{
+		chunker.MetaTitle:       "Post",
+		chunker.MetaIndex:       2,
+		chunker.MetaTokens:      40,
+		chunker.MetaTags:        []string{"go"},
+		chunker.MetaSectionPath: []string{"Intro", "Setup"},
+		chunker.MetaRedactions:  []models.Redaction{{Kind: "email", Action: "redact", Start: 3, End: 10}},
+		quality.MetaScore:       0.25,
+		quality.MetaFlags:       []string{"too short"},
+	}
+	chunk := &models.ContentChunk{ID: "c1", Text: "tiny", Metadata: metadata}
+	item := FromChunk(chunk, errors.New("rejected"))
+	if err := s.Add(item); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+
+	got, err := s.Get(item.ID)
+	if err != nil {
+		t.Fatalf("Failed to get item: %v", err)
+	}
+	if !reflect.DeepEqual(got.Chunk.Metadata, metadata) {
+		t.Errorf("Metadata = %#v, want %#v", got.Chunk.Metadata, metadata)
+	}
+	// The context header reads the section path as a []string
+	if path, ok := got.Chunk.Metadata[chunker.MetaSectionPath].([]string); !ok || len(path) != 2 {
+		t.Errorf("Expected the section path as a []string, got %T", got.Chunk.Metadata[chunker.MetaSectionPath])
+	}
+}
@@ -0,0 +1,192 @@
+package quarantine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/htmlutil"
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/quality"
+)
+
+var (
+	// ErrSoft404 is returned for a page served with a success status that
+	// says it was not found
+	ErrSoft404 = errors.New("soft 404")
+	// ErrQuarantined wraps the errors of pages the Extractor quarantined
+	ErrQuarantined = errors.New("quarantined")
+)
+
+// soft404Pattern matches the phrases of error pages
+var soft404Pattern = regexp.MustCompile(`(?i)\b404\b|\bnot found\b|\b(?:does not|doesn't|no longer) exists?\b|\bno longer available\b`)
+
+// soft404MaxText is the length in characters up to which a page whose
+// title or text matches soft404Pattern is a soft 404. Longer pages are
+// articles that merely mention such a phrase.
+const soft404MaxText = 1000
+
+// Soft404 returns an error wrapping ErrSoft404 when raw is a soft 404: a
+// page with a success status and little text whose title or text says it
+// was not found
+func Soft404(raw *models.RawContent) error {
+	if raw.StatusCode != 0 && (raw.StatusCode < 200 || raw.StatusCode > 299) {
+		return nil
+	}
+	doc, err := html.Parse(strings.NewReader(raw.HTML))
+	if err != nil {
+		return nil
+	}
+
+	text := ""
+	for _, body := range htmlutil.FindAll(doc, "body") {
+		text = htmlutil.TextContent(body)
+		break
+	}
+	if utf8.RuneCountInString(text) > soft404MaxText {
+		return nil
+	}
+	title := ""
+	for _, t := range htmlutil.FindAll(doc, "title") {
+		title = htmlutil.TextContent(t)
+		break
+	}
+	for _, s := range []string{title, text} {
+		if phrase := soft404Pattern.FindString(s); phrase != "" {
+			return fmt.Errorf("%w: page says %q", ErrSoft404, phrase)
+		}
+	}
+	return nil
+}
+
+// Extractor wraps a models.Extractor, quarantining soft 404s at StageFetch
+// and pages that fail extraction at StageExtraction. A released soft 404 is
+// extracted like any other page.
+type Extractor struct {
+	extractor models.Extractor
+	store     *Store
+}
+
+// NewExtractor creates an Extractor that quarantines the pages rejected by
+// extractor in store
+func NewExtractor(extractor models.Extractor, store *Store) (*Extractor, error) {
+	if extractor == nil || store == nil {
+		return nil, fmt.Errorf("quarantining extractor requires an extractor and a store")
+	}
+	return &Extractor{extractor: extractor, store: store}, nil
+}
+
+// Extract extracts the content of a page, quarantining the page when it is
+// a soft 404 or extraction fails
+func (e *Extractor) Extract(ctx context.Context, rawContent *models.RawContent) (*models.ExtractedContent, error) {
+	if err := Soft404(rawContent); err != nil && !e.store.isReleased(StageFetch, rawContent.URL, "") {
+		return nil, e.quarantine(StageFetch, rawContent, err)
+	}
+
+	content, err := e.extractor.Extract(ctx, rawContent)
+	if err != nil {
+		// Cancellation says nothing about the page
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, e.quarantine(StageExtraction, rawContent, err)
+	}
+	return content, nil
+}
+
+// Close closes the wrapped extractor, if it is an io.Closer
+func (e *Extractor) Close() error {
+	if closer, ok := e.extractor.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// quarantine adds the page rejected at stage with err to the store and
+// returns err wrapped in ErrQuarantined
+func (e *Extractor) quarantine(stage string, raw *models.RawContent, err error) error {
+	if addErr := e.store.Add(FromRaw(stage, raw, err)); addErr != nil {
+		return errors.Join(err, addErr)
+	}
+	return fmt.Errorf("%w: %w", ErrQuarantined, err)
+}
+
+// QualityControl wraps a models.QualityControl, quarantining the chunks it
+// rejects with a *quality.RejectionError at StageQuality. Released chunks
+// pass the check.
+type QualityControl struct {
+	quality models.QualityControl
+	store   *Store
+}
+
+// NewQualityControl creates a QualityControl that quarantines the chunks
+// rejected by qc in store
+func NewQualityControl(qc models.QualityControl, store *Store) (*QualityControl, error) {
+	if qc == nil || store == nil {
+		return nil, fmt.Errorf("quarantining quality control requires a quality control and a store")
+	}
+	return &QualityControl{quality: qc, store: store}, nil
+}
+
+// Check checks chunks with the wrapped quality control. It returns the
+// accepted and released chunks, in order, and the errors of the other
+// chunks, quarantining the rejected ones.
+func (q *QualityControl) Check(ctx context.Context, chunks []*models.ContentChunk) ([]*models.ContentChunk, []error) {
+	accepted, errs := q.quality.Check(ctx, chunks)
+
+	byID := make(map[string]*models.ContentChunk, len(chunks))
+	for _, chunk := range chunks {
+		byID[chunk.ID] = chunk
+	}
+	pass := make(map[*models.ContentChunk]bool, len(chunks))
+	for _, chunk := range accepted {
+		pass[chunk] = true
+	}
+
+	var remaining []error
+	for _, err := range errs {
+		var rejection *quality.RejectionError
+		var chunk *models.ContentChunk
+		if errors.As(err, &rejection) {
+			chunk = byID[rejection.ChunkID]
+		}
+		switch {
+		case chunk == nil:
+			remaining = append(remaining, err)
+		case q.store.isReleased(StageQuality, chunk.Source, chunk.ID):
+			pass[chunk] = true
+		default:
+			if addErr := q.store.Add(FromChunk(chunk, err)); addErr != nil {
+				err = errors.Join(err, addErr)
+			}
+			remaining = append(remaining, err)
+		}
+	}
+
+	var passed []*models.ContentChunk
+	for _, chunk := range chunks {
+		if pass[chunk] {
+			passed = append(passed, chunk)
+		}
+	}
+	return passed, remaining
+}
+
+// IsDuplicate checks duplicates with the wrapped quality control
+func (q *QualityControl) IsDuplicate(ctx context.Context, chunk *models.ContentChunk) (bool, float64, error) {
+	return q.quality.IsDuplicate(ctx, chunk)
+}
+
+// Close closes the wrapped quality control, if it is an io.Closer
+func (q *QualityControl) Close() error {
+	if closer, ok := q.quality.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
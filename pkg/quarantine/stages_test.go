@@ -0,0 +1,122 @@
+package quarantine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/quality"
+)
+
+func TestSoft404(t *testing.T) {
+	article := "<p>" + strings.Repeat("Handlers should return a 404 status when a post is not found. ", 30) + "</p>"
+	tests := []struct {
+		name string
+		raw  models.RawContent
+		want bool
+	}{
+		{"not found page", models.RawContent{StatusCode: 200, HTML: "<title>Oops</title><h1>Page not found</h1><p>Try the search.</p>"}, true},
+		{"404 title", models.RawContent{StatusCode: 200, HTML: "<title>404 | Example Blog</title><p>Sorry.</p>"}, true},
+		{"unknown status", models.RawContent{HTML: "<p>This post no longer exists.</p>"}, true},
+		{"article about 404s", models.RawContent{StatusCode: 200, HTML: "<title>Handling 404 in Go</title>" + article}, false},
+		{"short page", models.RawContent{StatusCode: 200, HTML: "<title>About</title><p>We write about Go.</p>"}, false},
+		{"hard 404", models.RawContent{StatusCode: 404, HTML: "<h1>Not found</h1>"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Soft404(&tt.raw)
+			if got := errors.Is(err, ErrSoft404); got != tt.want {
+				t.Errorf("Soft404() = %v, want soft 404 %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// stubExtractor returns its content, or its error
+type stubExtractor struct {
+	err error
+}
+
+func (e stubExtractor) Extract(ctx context.Context, raw *models.RawContent) (*models.ExtractedContent, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &models.ExtractedContent{URL: raw.URL, Text: "text"}, nil
+}
+
+func TestExtractor(t *testing.T) {
+	store, _ := Open(t.TempDir())
+	ctx := context.Background()
+	notFound := &models.RawContent{URL: "https://example.com/gone", StatusCode: 200, HTML: "<h1>Page not found</h1>"}
+
+	e, err := NewExtractor(stubExtractor{}, store)
+	if err != nil {
+		t.Fatalf("Failed to create extractor: %v", err)
+	}
+	if _, err := e.Extract(ctx, notFound); !errors.Is(err, ErrSoft404) || !errors.Is(err, ErrQuarantined) {
+		t.Fatalf("Expected a soft 404, got %v", err)
+	}
+	failing, _ := NewExtractor(stubExtractor{err: errors.New("no content")}, store)
+	if _, err := failing.Extract(ctx, &models.RawContent{URL: "https://example.com/empty"}); err == nil {
+		t.Fatal("Expected the extraction error")
+	}
+
+	items, _ := store.List("")
+	if len(items) != 2 || items[0].Stage != StageFetch || items[1].Stage != StageExtraction || items[1].Reason != "no content" {
+		t.Fatalf("Expected the soft 404 and the failed page in quarantine, got %+v", items)
+	}
+
+	// A released soft 404 is extracted on the next run
+	if _, err := store.Release(items[0].ID); err != nil {
+		t.Fatalf("Failed to release: %v", err)
+	}
+	if content, err := e.Extract(ctx, notFound); err != nil || content.URL != notFound.URL {
+		t.Errorf("Expected the released page to be extracted, got %v, %v", content, err)
+	}
+	if items, _ := store.List(StageFetch); len(items) != 0 {
+		t.Errorf("Expected the released page to stay out of quarantine, got %+v", items)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	failing.Extract(canceled, &models.RawContent{URL: "https://example.com/slow"})
+	if items, _ := store.List(StageExtraction); len(items) != 1 {
+		t.Errorf("Expected cancellation not to quarantine the page, got %d items", len(items))
+	}
+}
+
+func TestQualityControl(t *testing.T) {
+	store, _ := Open(t.TempDir())
+	ctx := context.Background()
+	checker, err := quality.New(quality.Config{MinContentLength: 20})
+	if err != nil {
+		t.Fatalf("Failed to create checker: %v", err)
+	}
+	q, err := NewQualityControl(checker, store)
+	if err != nil {
+		t.Fatalf("Failed to create quality control: %v", err)
+	}
+
+	good := &models.ContentChunk{ID: "good", Source: "https://example.com/a", Text: "A paragraph about concurrency in Go, long enough to pass every check."}
+	tiny := &models.ContentChunk{ID: "tiny", Source: "https://example.com/a", Text: "Too short."}
+	passed, errs := q.Check(ctx, []*models.ContentChunk{tiny, good})
+	if len(passed) != 1 || passed[0] != good || len(errs) != 1 {
+		t.Fatalf("Check = %v, %v", passed, errs)
+	}
+	items, _ := store.List(StageQuality)
+	if len(items) != 1 || items[0].Chunk.ID != "tiny" {
+		t.Fatalf("Expected the short chunk in quarantine, got %+v", items)
+	}
+
+	// A released chunk passes on the next run, in its original position
+	store.Release(items[0].ID)
+	passed, errs = q.Check(ctx, []*models.ContentChunk{tiny, good})
+	if len(passed) != 2 || passed[0] != tiny || len(errs) != 0 {
+		t.Errorf("Expected the released chunk to pass, got %v, %v", passed, errs)
+	}
+	if items, _ := store.List(StageQuality); len(items) != 0 {
+		t.Errorf("Expected the released chunk to stay out of quarantine, got %+v", items)
+	}
+}
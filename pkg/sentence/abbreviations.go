@@ -0,0 +1,46 @@
+package sentence
+
+// commonAbbreviations are abbreviations used across languages
+var commonAbbreviations = []string{"ca", "cf", "e.g", "i.e", "vs"}
+
+// numberAbbreviations only count as abbreviations before a number, since
+// they are also ordinary words, as in "No. 5" and "I said no."
+var numberAbbreviations = []string{"art", "ch", "fig", "figs", "no", "nos", "nr", "p", "pp", "sec", "vol", "vols"}
+
+// builtinAbbreviations are the abbreviations of each language that are
+// written with a period. English is used for languages without a list.
+var builtinAbbreviations = map[string][]string{
+	"en": {
+		"mr", "mrs", "ms", "dr", "prof", "sr", "jr", "st", "mt", "rev", "gen", "gov", "sen", "rep",
+		"capt", "col", "lt", "sgt", "inc", "ltd", "co", "corp", "dept", "univ", "assn", "bros",
+		"approx", "est", "misc", "ave", "blvd", "rd",
+		"jan", "feb", "mar", "apr", "jun", "jul", "aug", "sep", "sept", "oct", "nov", "dec",
+		"a.m", "p.m", "u.s", "u.k", "ph.d",
+	},
+	"de": {
+		"bzw", "d.h", "dr", "evtl", "ggf", "hr", "fr", "inkl", "jh", "prof", "str",
+		"u.a", "usw", "vgl", "z.b", "z.t", "zzgl", "abs", "bsp", "geb", "gegr",
+	},
+	"fr": {
+		"m", "mm", "mme", "mlle", "dr", "pr", "me", "av", "bd", "env", "ex", "p.ex",
+	},
+	"es": {
+		"sr", "sra", "srta", "dr", "dra", "ud", "uds", "av", "avda", "p.ej", "aprox", "pág", "núm",
+	},
+	"it": {
+		"sig", "sigg", "sig.ra", "dott", "prof", "ing", "avv", "ecc", "pag",
+	},
+	"pt": {
+		"sr", "sra", "srta", "dr", "dra", "av", "pág", "aprox",
+	},
+	"nl": {
+		"dhr", "mevr", "mw", "dr", "prof", "bijv", "enz", "d.w.z", "o.a", "m.b.t",
+	},
+}
+
+// ordinalLanguages write ordinal numbers as a number and a period
+var ordinalLanguages = map[string]bool{
+	"cs": true, "da": true, "de": true, "et": true, "fi": true, "hr": true, "hu": true,
+	"is": true, "lv": true, "no": true, "nb": true, "nn": true, "pl": true, "sk": true,
+	"sl": true, "sr": true, "tr": true,
+}
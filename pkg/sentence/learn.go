@@ -0,0 +1,94 @@
+package sentence
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Thresholds of LearnAbbreviations
+const (
+	// learnMinCount is the number of times a word must appear with a period
+	learnMinCount = 2
+	// learnMinRatio is the share of a word's occurrences that must have a
+	// period
+	learnMinRatio = 0.9
+	// learnMaxLetters is the longest word without internal periods that is
+	// taken for an abbreviation
+	learnMaxLetters = 6
+)
+
+// LearnAbbreviations finds the words of a corpus that behave like
+// abbreviations, in the spirit of the Punkt algorithm: short words that
+// nearly always appear with a trailing period, at least once in the middle
+// of a sentence, where the next word starts in lowercase or is a number.
+// Pass the result to New to segment similar text.
+func LearnAbbreviations(texts ...string) []string {
+	type counts struct {
+		withPeriod, without int
+		midSentence         bool
+	}
+	words := make(map[string]*counts)
+	get := func(word string) *counts {
+		c, ok := words[word]
+		if !ok {
+			c = &counts{}
+			words[word] = c
+		}
+		return c
+	}
+
+	for _, text := range texts {
+		fields := strings.Fields(text)
+		for i, field := range fields {
+			field = strings.TrimLeftFunc(field, isOpener)
+			field = strings.TrimRightFunc(field, func(r rune) bool {
+				return isCloser(r) || r == ',' || r == ';' || r == ':'
+			})
+			word := strings.ToLower(strings.TrimRight(field, "."))
+			if !isWordLike(word) {
+				continue
+			}
+			c := get(word)
+			if !strings.HasSuffix(field, ".") || strings.HasSuffix(field, "..") {
+				c.without++
+				continue
+			}
+			c.withPeriod++
+			if i+1 < len(fields) {
+				next, _ := utf8.DecodeRuneInString(strings.TrimLeftFunc(fields[i+1], isOpener))
+				if unicode.IsLower(next) || unicode.IsDigit(next) {
+					c.midSentence = true
+				}
+			}
+		}
+	}
+
+	var learned []string
+	for word, c := range words {
+		total := c.withPeriod + c.without
+		if c.withPeriod < learnMinCount || float64(c.withPeriod) < learnMinRatio*float64(total) || !c.midSentence {
+			continue
+		}
+		if !strings.Contains(word, ".") && utf8.RuneCountInString(word) > learnMaxLetters {
+			continue
+		}
+		learned = append(learned, word)
+	}
+	sort.Strings(learned)
+	return learned
+}
+
+// isWordLike reports whether word consists of letters and internal periods
+func isWordLike(word string) bool {
+	if word == "" || strings.HasPrefix(word, ".") {
+		return false
+	}
+	for _, r := range word {
+		if r != '.' && !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
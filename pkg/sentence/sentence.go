@@ -0,0 +1,282 @@
+// Package sentence splits text into sentences, reporting the byte offsets
+// of each. Segmentation is rule-based: a period ends a sentence unless it
+// belongs to a known abbreviation, an initial, a number or a URL, or the
+// next word starts in lowercase. Abbreviations come from built-in
+// per-language lists and can be learned from a corpus with
+// LearnAbbreviations.
+package sentence
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Span is the byte range of a sentence in the text it was read from
+type Span struct {
+	Start int
+	End   int
+}
+
+// Segmenter splits text into sentences. It is safe for concurrent use.
+type Segmenter struct {
+	abbreviations map[string]bool
+	// numbered holds the abbreviations that only count before a number
+	numbered map[string]bool
+	// ordinals reports whether the language writes ordinal numbers with a
+	// period, as German does in "am 3. Oktober"
+	ordinals bool
+}
+
+// New creates a Segmenter for a language tag such as "en" or "de-AT",
+// knowing the built-in abbreviations of the language and the given ones.
+// Abbreviations are written without their final period, e.g. "approx" or
+// "e.g", and match regardless of case.
+func New(language string, abbreviations ...string) *Segmenter {
+	lang := primaryLanguage(language)
+	s := &Segmenter{abbreviations: make(map[string]bool), numbered: make(map[string]bool), ordinals: ordinalLanguages[lang]}
+	words := builtinAbbreviations[lang]
+	if words == nil {
+		words = builtinAbbreviations["en"]
+	}
+	for _, list := range [][]string{commonAbbreviations, words, abbreviations} {
+		for _, word := range list {
+			s.abbreviations[strings.ToLower(strings.TrimSuffix(word, "."))] = true
+		}
+	}
+	for _, word := range numberAbbreviations {
+		s.numbered[word] = true
+	}
+	return s
+}
+
+var segmenters sync.Map
+
+// For returns the shared Segmenter with the built-in abbreviations of a
+// language
+func For(language string) *Segmenter {
+	lang := primaryLanguage(language)
+	if s, ok := segmenters.Load(lang); ok {
+		return s.(*Segmenter)
+	}
+	s, _ := segmenters.LoadOrStore(lang, New(lang))
+	return s.(*Segmenter)
+}
+
+// Segment returns the sentences of text in a language, see For
+func Segment(text, language string) []Span {
+	return For(language).Segment(text)
+}
+
+// Split returns the text of the sentences of text
+func (s *Segmenter) Split(text string) []string {
+	spans := s.Segment(text)
+	sentences := make([]string, len(spans))
+	for i, span := range spans {
+		sentences[i] = text[span.Start:span.End]
+	}
+	return sentences
+}
+
+// Segment returns the sentences of text in order. Sentences exclude the
+// whitespace around them and end after their terminating punctuation and
+// any closing quotes or brackets. Line breaks always end a sentence, so
+// headings and list items are sentences of their own.
+func (s *Segmenter) Segment(text string) []Span {
+	var spans []Span
+	start := 0
+	add := func(end int) {
+		if span, ok := trim(text, start, end); ok {
+			spans = append(spans, span)
+		}
+		start = end
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '\n' || r == '\r':
+			add(i)
+			i += size
+			start = i
+			continue
+		case isFullStop(r):
+			// CJK full stops need no space after them
+			end := skip(text, i+size, isCloser)
+			add(end)
+			i = end
+			continue
+		case r == '.' || r == '!' || r == '?' || r == '…':
+			runEnd := skip(text, i, isTerminator)
+			end := skip(text, runEnd, isCloser)
+			if end < len(text) {
+				if next, _ := utf8.DecodeRuneInString(text[end:]); !unicode.IsSpace(next) {
+					// Inside a number, URL or abbreviation such as "e.g."
+					i = runEnd
+					continue
+				}
+			}
+			if strings.ContainsAny(text[i:runEnd], "!?") || s.periodEnds(text, start, i, runEnd, end) {
+				add(end)
+			}
+			i = end
+			continue
+		}
+		i += size
+	}
+	add(len(text))
+	return spans
+}
+
+// periodEnds reports whether the periods from i to runEnd, followed by
+// closing punctuation up to end, end the sentence that starts at start
+func (s *Segmenter) periodEnds(text string, start, i, runEnd, end int) bool {
+	next := nextWord(text, end)
+	if next == "" {
+		return true
+	}
+	first, _ := utf8.DecodeRuneInString(next)
+	if unicode.IsLower(first) {
+		return false
+	}
+	if runEnd-i > 1 || end > runEnd {
+		// An ellipsis or a period before a closing quote
+		return true
+	}
+
+	word := previousWord(text, start, i)
+	switch {
+	case word == "":
+		return true
+	case s.abbreviations[strings.ToLower(word)]:
+		return false
+	case s.numbered[strings.ToLower(word)] && unicode.IsDigit(first):
+		return false
+	case isInitial(word):
+		return false
+	case strings.Contains(word, "."):
+		// "U.S." is an abbreviation, "example.com." or "1.5." is not
+		return !isDottedAbbreviation(word)
+	case s.ordinals && isOrdinal(word):
+		return false
+	}
+	return true
+}
+
+// previousWord returns the word before offset i, without opening
+// punctuation, looking no further back than start
+func previousWord(text string, start, i int) string {
+	from := i
+	for from > start {
+		r, size := utf8.DecodeLastRuneInString(text[start:from])
+		if unicode.IsSpace(r) {
+			break
+		}
+		from -= size
+	}
+	return strings.TrimLeftFunc(text[from:i], isOpener)
+}
+
+// nextWord returns the text from the first word after offset i, skipping
+// whitespace and opening punctuation
+func nextWord(text string, i int) string {
+	rest := strings.TrimLeftFunc(text[i:], unicode.IsSpace)
+	return strings.TrimLeftFunc(rest, isOpener)
+}
+
+// isInitial reports whether word is a single uppercase letter, as in
+// "J. R. R. Tolkien"
+func isInitial(word string) bool {
+	r, size := utf8.DecodeRuneInString(word)
+	return size == len(word) && unicode.IsUpper(r)
+}
+
+// isDottedAbbreviation reports whether word consists of periods and runs
+// of at most two letters, as in "e.g", "U.S" or "z.B"
+func isDottedAbbreviation(word string) bool {
+	for _, part := range strings.Split(word, ".") {
+		n := 0
+		for _, r := range part {
+			if !unicode.IsLetter(r) {
+				return false
+			}
+			n++
+		}
+		if n == 0 || n > 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// isOrdinal reports whether word is a number of at most three digits
+func isOrdinal(word string) bool {
+	if len(word) > 3 {
+		return false
+	}
+	for _, r := range word {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// trim returns the range start to end of text without surrounding
+// whitespace, and whether it is not empty
+func trim(text string, start, end int) (Span, bool) {
+	for start < end {
+		r, size := utf8.DecodeRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += size
+	}
+	for end > start {
+		r, size := utf8.DecodeLastRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		end -= size
+	}
+	return Span{Start: start, End: end}, start < end
+}
+
+// skip returns the offset after the runes from i that satisfy match
+func skip(text string, i int, match func(rune) bool) int {
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !match(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+func isTerminator(r rune) bool {
+	return r == '.' || r == '!' || r == '?' || r == '…'
+}
+
+// isFullStop reports whether r is a sentence terminator of Chinese or
+// Japanese, which are written without spaces between sentences
+func isFullStop(r rune) bool {
+	return r == '。' || r == '！' || r == '？' || r == '｡'
+}
+
+func isCloser(r rune) bool {
+	return strings.ContainsRune("\"')]}”’»」』", r)
+}
+
+func isOpener(r rune) bool {
+	return strings.ContainsRune("\"'([{“‘«¿¡「『", r)
+}
+
+// primaryLanguage returns the primary subtag of a language tag, lowercased
+func primaryLanguage(tag string) string {
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return strings.ToLower(tag)
+}
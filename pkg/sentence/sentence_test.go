@@ -0,0 +1,119 @@
+package sentence
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSegment(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		text     string
+		want     []string
+	}{
+		{
+			name: "basic",
+			text: "One two. Three? Four!  Five",
+			want: []string{"One two.", "Three?", "Four!", "Five"},
+		},
+		{
+			name: "abbreviations",
+			text: "Use a tool, e.g. a hammer. Dr. Smith agrees, i.e. mostly. Prices rose approx. 5% vs. last year.",
+			want: []string{"Use a tool, e.g. a hammer.", "Dr. Smith agrees, i.e. mostly.", "Prices rose approx. 5% vs. last year."},
+		},
+		{
+			name: "numbers and urls",
+			text: "Version 1.5.2 is out. See https://example.com/a.html. It costs $3.50. Done.",
+			want: []string{"Version 1.5.2 is out.", "See https://example.com/a.html.", "It costs $3.50.", "Done."},
+		},
+		{
+			name: "trailing domain",
+			text: "Visit example.com. Then leave.",
+			want: []string{"Visit example.com.", "Then leave."},
+		},
+		{
+			name: "initials and dotted abbreviations",
+			text: "J. R. R. Tolkien lived in the U.K. for years. He wrote books.",
+			want: []string{"J. R. R. Tolkien lived in the U.K. for years.", "He wrote books."},
+		},
+		{
+			name: "words that abbreviate only before numbers",
+			text: "See No. 5 and p. 12. I said no. Then I left.",
+			want: []string{"See No. 5 and p. 12.", "I said no.", "Then I left."},
+		},
+		{
+			name: "lowercase continuation",
+			text: "The value is approx. two. It varies... sometimes. Really...  Yes.",
+			want: []string{"The value is approx. two.", "It varies... sometimes.", "Really...", "Yes."},
+		},
+		{
+			name: "quotes and brackets",
+			text: `He said "Stop." Then he left. (It was late.) We slept.`,
+			want: []string{`He said "Stop."`, "Then he left.", "(It was late.)", "We slept."},
+		},
+		{
+			name: "line breaks",
+			text: "Heading\n\n- item one\n- item two\nLast line.",
+			want: []string{"Heading", "- item one", "- item two", "Last line."},
+		},
+		{
+			name:     "german ordinals and abbreviations",
+			language: "de-AT",
+			text:     "Am 3. Oktober kam er, z.B. mit dem Zug. Vgl. Kapitel zwei. Danach ging er.",
+			want:     []string{"Am 3. Oktober kam er, z.B. mit dem Zug.", "Vgl. Kapitel zwei.", "Danach ging er."},
+		},
+		{
+			name:     "chinese",
+			language: "zh",
+			text:     "今天下雨。我们在家！你呢？",
+			want:     []string{"今天下雨。", "我们在家！", "你呢？"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, span := range Segment(tt.text, tt.language) {
+				got = append(got, tt.text[span.Start:span.End])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Segment() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSegmentEmpty(t *testing.T) {
+	for _, text := range []string{"", "   ", "\n\n"} {
+		if spans := Segment(text, "en"); len(spans) != 0 {
+			t.Errorf("Segment(%q) = %v, want none", text, spans)
+		}
+	}
+}
+
+func TestNewWithAbbreviations(t *testing.T) {
+	text := "Ask the Mgr. Jones about it. Then go."
+	if got := For("en").Split(text); len(got) != 3 {
+		t.Fatalf("Expected the unknown abbreviation to split, got %q", got)
+	}
+	got := New("en", "mgr.").Split(text)
+	if want := []string{"Ask the Mgr. Jones about it.", "Then go."}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Split() = %q, want %q", got, want)
+	}
+}
+
+func TestLearnAbbreviations(t *testing.T) {
+	corpus := []string{
+		"The kit weighs approx. two kilos and the dept. head agrees.",
+		"Ask the dept. about it. The mgr. said so.",
+		"Costs are approx. 10 dollars, e.g. for shipping, e.g. overseas.",
+		"A long sentence ends here. Another sentence ends here.",
+		"This sentence also ends here.",
+	}
+	got := LearnAbbreviations(corpus...)
+	want := []string{"approx", "dept", "e.g"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LearnAbbreviations() = %q, want %q", got, want)
+	}
+}
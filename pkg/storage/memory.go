@@ -0,0 +1,286 @@
+// Package storage stores vector embeddings and searches them by
+// similarity.
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/chunker"
+	"github.com/ncolesummers/scrape-pipeline/pkg/embedding"
+)
+
+// fileName is the file MemoryStorage persists its embeddings to
+const fileName = "embeddings.json"
+
+// MemoryStorage implements the models.VectorStorage interface with an
+// exhaustive cosine similarity search over embeddings held in memory. With
+// a directory it loads its embeddings from there on creation and saves them
+// there by Save, which Close calls. It is safe for concurrent use.
+type MemoryStorage struct {
+	mu         sync.RWMutex
+	dir        string
+	embeddings map[string]*models.VectorEmbedding
+	// dirty is set by changes not yet saved
+	dirty bool
+	// chunks indexes the stored embeddings by chunk ID
+	chunks map[string]*models.VectorEmbedding
+}
+
+// NewMemoryStorage creates an empty MemoryStorage that is not persisted
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		embeddings: make(map[string]*models.VectorEmbedding),
+		chunks:     make(map[string]*models.VectorEmbedding),
+	}
+}
+
+// OpenLocalStorage creates a MemoryStorage persisted in dir, loading the
+// embeddings saved there before. The directory is created on first save.
+func OpenLocalStorage(dir string) (*MemoryStorage, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("local storage requires a directory")
+	}
+	s := NewMemoryStorage()
+	s.dir = dir
+
+	data, err := os.ReadFile(filepath.Join(dir, fileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored embeddings: %w", err)
+	}
+	var embeddings []*models.VectorEmbedding
+	if err := json.Unmarshal(data, &embeddings); err != nil {
+		return nil, fmt.Errorf("failed to parse stored embeddings: %w", err)
+	}
+	for _, e := range embeddings {
+		s.add(e)
+	}
+	return s, nil
+}
+
+// Store saves embeddings, replacing any stored under the same IDs
+func (s *MemoryStorage) Store(ctx context.Context, embeddings []*models.VectorEmbedding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range embeddings {
+		if e.ID == "" {
+			return fmt.Errorf("embedding without an ID")
+		}
+	}
+	for _, e := range embeddings {
+		s.remove(e.ID)
+		s.add(e)
+	}
+	s.dirty = true
+	return nil
+}
+
+// Query returns up to limit embeddings with the same dimensions as
+// queryVector, most similar first
+func (s *MemoryStorage) Query(ctx context.Context, queryVector []float32, limit int) ([]*models.VectorEmbedding, error) {
+	return s.query(ctx, queryVector, limit, func(*models.VectorEmbedding) bool { return true })
+}
+
+// QueryParents matches queryVector against chunks without children, which
+// are the children of hierarchical chunks and chunks of any other strategy,
+// and returns up to limit of their parents, most similar first. A matched
+// chunk without a parent is returned itself; a parent matched through
+// several children is returned once.
+func (s *MemoryStorage) QueryParents(ctx context.Context, queryVector []float32, limit int) ([]*models.VectorEmbedding, error) {
+	leaves, err := s.query(ctx, queryVector, -1, func(e *models.VectorEmbedding) bool {
+		return e.Chunk == nil || e.Chunk.Metadata[chunker.MetaLevel] != chunker.LevelParent
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var results []*models.VectorEmbedding
+	seen := make(map[string]bool)
+	for _, leaf := range leaves {
+		if limit >= 0 && len(results) == limit {
+			break
+		}
+		result := leaf
+		if parent := s.parent(leaf); parent != nil {
+			result = parent
+		}
+		if !seen[result.ID] {
+			seen[result.ID] = true
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// Parent returns the stored embedding of the parent of the chunk with the
+// given ID, or nil when the chunk is not stored or has no stored parent
+func (s *MemoryStorage) Parent(ctx context.Context, chunkID string) (*models.VectorEmbedding, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	child, ok := s.chunks[chunkID]
+	if !ok {
+		return nil, nil
+	}
+	return s.parent(child), nil
+}
+
+// Get returns the stored embeddings with the given IDs, in order, skipping
+// IDs that are not stored
+func (s *MemoryStorage) Get(ctx context.Context, ids []string) ([]*models.VectorEmbedding, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var embeddings []*models.VectorEmbedding
+	for _, id := range ids {
+		if e, ok := s.embeddings[id]; ok {
+			embeddings = append(embeddings, e)
+		}
+	}
+	return embeddings, nil
+}
+
+// Delete removes the embeddings with the given IDs
+func (s *MemoryStorage) Delete(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		s.remove(id)
+	}
+	s.dirty = true
+	return nil
+}
+
+// Save writes the embeddings to the directory. It does nothing when the
+// storage has no directory or nothing changed since the last save.
+func (s *MemoryStorage) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dir == "" || !s.dirty {
+		return nil
+	}
+	if err := s.save(); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// Close saves the embeddings
+func (s *MemoryStorage) Close() error {
+	return s.Save()
+}
+
+// query ranks the embeddings that match keep by similarity to queryVector
+// and returns the first limit, or all when limit is negative
+func (s *MemoryStorage) query(ctx context.Context, queryVector []float32, limit int, keep func(*models.VectorEmbedding) bool) ([]*models.VectorEmbedding, error) {
+	// Check if context is canceled
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type scored struct {
+		embedding *models.VectorEmbedding
+		score     float64
+	}
+	var candidates []scored
+	for _, e := range s.embeddings {
+		if len(e.Vector) == len(queryVector) && keep(e) {
+			candidates = append(candidates, scored{e, embedding.CosineSimilarity(queryVector, e.Vector)})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].embedding.ID < candidates[j].embedding.ID
+	})
+
+	if limit >= 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	results := make([]*models.VectorEmbedding, len(candidates))
+	for i, c := range candidates {
+		results[i] = c.embedding
+	}
+	return results, nil
+}
+
+// parent returns the stored parent of e, if any. The caller must hold the
+// lock.
+func (s *MemoryStorage) parent(e *models.VectorEmbedding) *models.VectorEmbedding {
+	if e.Chunk == nil {
+		return nil
+	}
+	parentID, _ := e.Chunk.Metadata[chunker.MetaParentID].(string)
+	if parentID == "" {
+		return nil
+	}
+	return s.chunks[parentID]
+}
+
+func (s *MemoryStorage) add(e *models.VectorEmbedding) {
+	s.embeddings[e.ID] = e
+	if e.Chunk != nil && e.Chunk.ID != "" {
+		s.chunks[e.Chunk.ID] = e
+	}
+}
+
+func (s *MemoryStorage) remove(id string) {
+	e, ok := s.embeddings[id]
+	if !ok {
+		return
+	}
+	delete(s.embeddings, id)
+	if e.Chunk != nil && s.chunks[e.Chunk.ID] == e {
+		delete(s.chunks, e.Chunk.ID)
+	}
+}
+
+// save writes the embeddings to the directory, sorted by ID. The caller
+// must hold the lock.
+func (s *MemoryStorage) save() error {
+	embeddings := make([]*models.VectorEmbedding, 0, len(s.embeddings))
+	for _, e := range s.embeddings {
+		embeddings = append(embeddings, e)
+	}
+	sort.Slice(embeddings, func(i, j int) bool { return embeddings[i].ID < embeddings[j].ID })
+
+	data, err := json.Marshal(embeddings)
+	if err != nil {
+		return fmt.Errorf("failed to encode embeddings: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	// Write to a temporary file first so a crash cannot truncate the store
+	tmp := filepath.Join(s.dir, fileName+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write embeddings: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, fileName)); err != nil {
+		return fmt.Errorf("failed to write embeddings: %w", err)
+	}
+	return nil
+}
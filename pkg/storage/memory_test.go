@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ncolesummers/scrape-pipeline/internal/models"
+	"github.com/ncolesummers/scrape-pipeline/pkg/chunker"
+)
+
+var (
+	_ models.HierarchicalStorage = (*MemoryStorage)(nil)
+	_ models.LookupStorage       = (*MemoryStorage)(nil)
+)
+
+func newEmbedding(id string, vector []float32, metadata map[string]interface{}) *models.VectorEmbedding {
+	return &models.VectorEmbedding{
+		ID:     "e-" + id,
+		Chunk:  &models.ContentChunk{ID: id, Text: id, Metadata: metadata},
+		Vector: vector,
+	}
+}
+
+// family returns a parent with two children and an unrelated chunk
+func family() []*models.VectorEmbedding {
+	return []*models.VectorEmbedding{
+		newEmbedding("parent", []float32{1, 1, 0}, map[string]interface{}{chunker.MetaLevel: chunker.LevelParent}),
+		newEmbedding("child-a", []float32{1, 0, 0}, map[string]interface{}{chunker.MetaLevel: chunker.LevelChild, chunker.MetaParentID: "parent"}),
+		newEmbedding("child-b", []float32{0.9, 0.1, 0}, map[string]interface{}{chunker.MetaLevel: chunker.LevelChild, chunker.MetaParentID: "parent"}),
+		newEmbedding("other", []float32{0, 0, 1}, nil),
+	}
+}
+
+func ids(embeddings []*models.VectorEmbedding) []string {
+	var ids []string
+	for _, e := range embeddings {
+		ids = append(ids, e.Chunk.ID)
+	}
+	return ids
+}
+
+func TestMemoryStorageQuery(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStorage()
+	if err := s.Store(ctx, family()); err != nil {
+		t.Fatalf("Failed to store: %v", err)
+	}
+
+	results, err := s.Query(ctx, []float32{1, 0, 0}, 2)
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	if got := ids(results); len(got) != 2 || got[0] != "child-a" || got[1] != "child-b" {
+		t.Errorf("Query = %v, want [child-a child-b]", got)
+	}
+
+	// Vectors of other dimensions are skipped
+	if results, _ := s.Query(ctx, []float32{1, 0}, 10); len(results) != 0 {
+		t.Errorf("Expected no results for a vector of other dimensions, got %v", ids(results))
+	}
+
+	if err := s.Delete(ctx, []string{"e-child-a"}); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if results, _ := s.Query(ctx, []float32{1, 0, 0}, 1); ids(results)[0] != "child-b" {
+		t.Errorf("Expected the deleted embedding to be gone, got %v", ids(results))
+	}
+
+	got, err := s.Get(ctx, []string{"e-other", "e-child-a", "e-parent"})
+	if err != nil {
+		t.Fatalf("Failed to get: %v", err)
+	}
+	if g := ids(got); len(g) != 2 || g[0] != "other" || g[1] != "parent" {
+		t.Errorf("Get = %v, want [other parent]", g)
+	}
+}
+
+func TestMemoryStorageParents(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStorage()
+	if err := s.Store(ctx, family()); err != nil {
+		t.Fatalf("Failed to store: %v", err)
+	}
+
+	// Both children match, but their parent is returned once, before the
+	// unrelated chunk
+	results, err := s.QueryParents(ctx, []float32{1, 0, 0.1}, 10)
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	if got := ids(results); len(got) != 2 || got[0] != "parent" || got[1] != "other" {
+		t.Errorf("QueryParents = %v, want [parent other]", got)
+	}
+
+	parent, err := s.Parent(ctx, "child-b")
+	if err != nil || parent == nil || parent.Chunk.ID != "parent" {
+		t.Errorf("Parent(child-b) = %v, %v", parent, err)
+	}
+	if parent, _ := s.Parent(ctx, "other"); parent != nil {
+		t.Errorf("Expected no parent for a chunk without one, got %v", parent.Chunk.ID)
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s, err := OpenLocalStorage(dir)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	if err := s.Store(ctx, family()); err != nil {
+		t.Fatalf("Failed to store: %v", err)
+	}
+	// Storing only changes memory; closing writes the file once
+	if _, err := os.Stat(filepath.Join(dir, fileName)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected nothing written before closing, got %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+
+	reopened, err := OpenLocalStorage(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen storage: %v", err)
+	}
+	parent, err := reopened.Parent(ctx, "child-a")
+	if err != nil || parent == nil || parent.Chunk.ID != "parent" {
+		t.Errorf("Expected the parent to survive reopening, got %v, %v", parent, err)
+	}
+	if results, _ := reopened.Query(ctx, []float32{0, 0, 1}, 1); len(results) != 1 || results[0].Chunk.ID != "other" {
+		t.Errorf("Query after reopening = %v", ids(results))
+	}
+}
+
+func TestStoreRequiresID(t *testing.T) {
+	if err := NewMemoryStorage().Store(context.Background(), []*models.VectorEmbedding{{Vector: []float32{1}}}); err == nil {
+		t.Error("Expected an error for an embedding without an ID")
+	}
+}
+
+func TestLocalStorageSavesOnlyChanges(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, _ := OpenLocalStorage(dir)
+	s.Store(ctx, family())
+	if err := s.Save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	// A save without changes leaves the file alone
+	path := filepath.Join(dir, fileName)
+	os.Remove(path)
+	if err := s.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no write without changes, got %v", err)
+	}
+
+	s.Delete(ctx, []string{"e-other"})
+	s.Close()
+	reopened, _ := OpenLocalStorage(dir)
+	if results, _ := reopened.Query(ctx, []float32{0, 0, 1}, -1); len(results) != len(family())-1 {
+		t.Errorf("Expected the deletion saved, got %v", ids(results))
+	}
+}